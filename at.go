@@ -0,0 +1,38 @@
+package limiter
+
+import (
+	"time"
+)
+
+// like Try() but decision is made at t
+// instead of limiter clock, e.g. for replay
+// of historical events in batch jobs
+func (l *Limiter[T]) TryAt(id T, t time.Time) bool {
+	return l.TryNAt(id, 1, t)
+}
+
+// like TryN() but decision is made at t
+// instead of limiter clock
+//
+// t before earlier decisions of key counts towards
+// current window of key, so keep events in order
+// clean up still uses limiter clock, so set it with
+// SetClock() or SetAutoClean(false) for old events
+func (l *Limiter[T]) TryNAt(id T, n int, t time.Time) bool {
+	if n < 1 {
+		n = 1
+	}
+	ok := l.run(id, n, noPriority, t)
+	if !ok {
+		l.sampleDenial(id, nil, t)
+	}
+	if s := l.shadow.Load(); s != nil {
+		l.compareShadow(ok, s.TryNAt(id, n, t))
+	}
+	return ok
+}
+
+// like KeyStats() but state is at t
+func (l *Limiter[T]) KeyStatsAt(id T, t time.Time) (KeyState, bool) {
+	return l.keyStats(id, t.Unix())
+}
@@ -0,0 +1,183 @@
+package limiter
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ssleert/mu"
+)
+
+// record of denied action
+type AuditRecord[T any] struct {
+	Key    T         `json:"key"`
+	Time   time.Time `json:"time"`
+	Policy Policy    `json:"policy"`
+	Count  int       `json:"count"`
+	Denies int       `json:"denies"`
+	Banned bool      `json:"banned,omitempty"`
+	// see Namespace()
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// destination of audit records
+// like file, kafka or webhook
+type AuditSink[T any] interface {
+	Write(ctx context.Context, records []AuditRecord[T]) error
+}
+
+// func that implements AuditSink
+type AuditFunc[T any] func(ctx context.Context, records []AuditRecord[T]) error
+
+func (f AuditFunc[T]) Write(ctx context.Context, records []AuditRecord[T]) error {
+	return f(ctx, records)
+}
+
+// audit counters
+type AuditStats struct {
+	// records given to sink
+	Written uint64
+	// records dropped because buffer was full
+	Dropped uint64
+	// failed sink writes
+	Errors uint64
+}
+
+type auditCounters struct {
+	written atomic.Uint64
+	dropped atomic.Uint64
+	errors  atomic.Uint64
+}
+
+// interval of Audit() if given one is not positive
+const defaultAuditInterval = time.Second
+
+// stream records of denied actions to sink in batches
+// of up to batch records, at least every interval
+// it blocks so run it in your own goroutine
+//
+// records wait in buffer while sink is busy and
+// when it is full new ones are dropped, so slow
+// sink never blocks Try(), see AuditStats()
+//
+// buffered records are written when ctx is done
+// failed writes go to onErr and are not retried
+// interval <= 0 writes them at least every second
+// returns ctx.Err()
+func (l *Limiter[T]) Audit(
+	ctx context.Context,
+	sink AuditSink[T],
+	buffer,
+	batch int,
+	interval time.Duration,
+	onErr func(err error),
+) error {
+	ctx, unlabel := l.label(ctx, "audit")
+	defer unlabel()
+
+	if batch < 1 {
+		batch = 1
+	}
+	ch := make(chan AuditRecord[T], buffer)
+	mu.ExecMutex(&l.mu, func() {
+		l.audit = ch
+		l.shareStreams()
+	})
+
+	if interval <= 0 {
+		interval = defaultAuditInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	records := make([]AuditRecord[T], 0, batch)
+	flush := func(ctx context.Context) {
+		if len(records) == 0 {
+			return
+		}
+		if err := sink.Write(ctx, records); err != nil {
+			l.auditStats.errors.Add(1)
+			if onErr != nil {
+				onErr(err)
+			}
+		} else {
+			l.auditStats.written.Add(uint64(len(records)))
+		}
+		records = make([]AuditRecord[T], 0, batch)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			mu.ExecMutex(&l.mu, func() {
+				l.audit = nil
+				l.shareStreams()
+			})
+			for len(ch) > 0 {
+				records = append(records, <-ch)
+			}
+			flush(context.Background())
+			return ctx.Err()
+		case r := <-ch:
+			records = append(records, r)
+			if len(records) >= batch {
+				flush(ctx)
+			}
+		case <-ticker.C:
+			flush(ctx)
+		}
+	}
+}
+
+// get audit counters
+func (l *Limiter[T]) AuditStats() AuditStats {
+	return AuditStats{
+		Written: l.auditStats.written.Load(),
+		Dropped: l.auditStats.dropped.Load(),
+		Errors:  l.auditStats.errors.Load(),
+	}
+}
+
+// send record of denied action without blocking
+//
+// l.mu must be held
+func (l *Limiter[T]) auditDenied(id T, a action, timeNow int64) {
+	if l.audit == nil {
+		return
+	}
+	select {
+	case l.audit <- AuditRecord[T]{
+		Key:    id,
+		Time:   time.Unix(timeNow, 0),
+		Policy: l.policyOf(id).external(),
+		Count:  a.count,
+		Denies: a.denies,
+		Banned: a.banned(timeNow),
+
+		Namespace: l.nsName,
+	}:
+	default:
+		l.counters().auditStats.dropped.Add(1)
+	}
+}
+
+// sink that writes records to w as json lines
+// e.g. to audit log file
+func JSONSink[T any](w io.Writer) AuditSink[T] {
+	var mu sync.Mutex
+	enc := json.NewEncoder(w)
+	return AuditFunc[T](func(ctx context.Context, records []AuditRecord[T]) error {
+		mu.Lock()
+		defer mu.Unlock()
+
+		for _, r := range records {
+			if err := enc.Encode(r); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
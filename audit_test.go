@@ -0,0 +1,76 @@
+package limiter_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ssleert/limiter"
+)
+
+// run Audit() of l and deny key until
+// sink gets record of it, returns record
+func audited(
+	t *testing.T,
+	l *limiter.Limiter[string],
+	id string,
+	interval time.Duration,
+) limiter.AuditRecord[string] {
+	t.Helper()
+	got := make(chan limiter.AuditRecord[string], 1)
+	sink := limiter.AuditFunc[string](func(ctx context.Context, rs []limiter.AuditRecord[string]) error {
+		for _, r := range rs {
+			select {
+			case got <- r:
+			default:
+			}
+		}
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- l.Audit(ctx, sink, 16, 1, interval, nil)
+	}()
+	defer func() {
+		cancel()
+		if err := <-done; !errors.Is(err, context.Canceled) {
+			t.Errorf("Audit() err = %v, want %v", err, context.Canceled)
+		}
+	}()
+
+	// audit starts in its goroutine so deny until it is on
+	deadline := time.After(5 * time.Second)
+	for {
+		l.Try(id)
+		select {
+		case r := <-got:
+			return r
+		case <-deadline:
+			t.Fatalf("no audit record of %s", id)
+		case <-time.After(time.Millisecond):
+		}
+	}
+}
+
+func TestAudit(t *testing.T) {
+	tests := []struct {
+		name     string
+		interval time.Duration
+	}{
+		{"interval", time.Hour},
+		{"zero interval", 0},
+		{"negative interval", -time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := limiter.New[string](1, 60, 16, 1024, 16)
+			r := audited(t, l, "a", tt.interval)
+			if r.Key != "a" || r.Policy.MaxCount != 1 {
+				t.Fatalf("record = %+v, want of a with max count 1", r)
+			}
+		})
+	}
+}
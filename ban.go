@@ -0,0 +1,166 @@
+package limiter
+
+import (
+	"time"
+
+	"github.com/ssleert/mu"
+)
+
+type banPolicy struct {
+	// denials that lead to ban
+	// 0 disables banning
+	threshold int
+	period    int64
+	duration  int64
+
+	// ban duration is multiplied by factor
+	// for every previous ban up to maxDuration
+	// factor <= 1 disables backoff
+	factor      float64
+	maxDuration int64
+	// good behavior time that lowers ban level by one
+	decay int64
+
+	// denials in a row that lead to ban
+	// 0 disables it
+	streak int
+}
+
+// ban key for duration after threshold denials within period
+// banned key is denied by Try() without checking its count
+// and its entry is kept until ban ends
+//
+// threshold <= 0 disables banning
+// resolution is one second
+func (l *Limiter[T]) SetBan(threshold int, period, duration time.Duration) {
+	defer l.moveBack()
+	mu.ExecMutex(&l.mu, func() {
+		l.ban = banPolicy{
+			threshold: threshold,
+			period:    int64(period / time.Second),
+			duration:  int64(duration / time.Second),
+		}
+	})
+}
+
+// make every next ban of key factor times longer
+// than previous one but no longer than max
+// each decay period without ban lowers ban level by one
+// and ban level is kept with key until it decays to zero
+// if decay <= 0 level never decays but is lost on clean up
+//
+// factor <= 1 disables backoff
+// resolution is one second
+func (l *Limiter[T]) SetBanBackoff(factor float64, max, decay time.Duration) {
+	defer l.moveBack()
+	mu.ExecMutex(&l.mu, func() {
+		l.ban.factor = factor
+		l.ban.maxDuration = int64(max / time.Second)
+		l.ban.decay = int64(decay / time.Second)
+	})
+}
+
+// also ban key after every n denials in a row
+// whatever time they took, so slow but
+// sustained abuse is banned too
+// ban duration is the one of SetBan()
+//
+// n <= 0 disables it
+func (l *Limiter[T]) SetBanStreak(n int) {
+	defer l.moveBack()
+	mu.ExecMutex(&l.mu, func() {
+		l.ban.streak = n
+	})
+}
+
+// lift ban of key
+// returns false if key is not tracked
+func (l *Limiter[T]) Unban(id T) bool {
+	defer l.moveBack(id)
+	var ok bool
+	mu.ExecMutex(&l.mu, func() {
+		l.holder(id, func(hl *Limiter[T]) {
+			var a action
+			a, ok = hl.get(id)
+			if !ok {
+				return
+			}
+			a.bannedUntil = 0
+			a.violations = 0
+			a.banLevel = 0
+			hl.set(id, a)
+		})
+	})
+	l.denyCache.forget(id)
+	return ok
+}
+
+// true if key is banned at timeNow
+func (a action) banned(timeNow int64) bool {
+	return a.bannedUntil > timeNow
+}
+
+// count denial of key and ban it if needed
+//
+// l.mu must be held
+func (l *Limiter[T]) violate(a *action, timeNow int64) {
+	if l.ban.streak > 0 && a.streak%l.ban.streak == 0 {
+		l.banKey(a, timeNow)
+		return
+	}
+	if l.ban.threshold <= 0 {
+		return
+	}
+
+	if timeNow-a.violationStart >= l.ban.period {
+		a.violationStart = timeNow
+		a.violations = 0
+	}
+	a.violations++
+	if a.violations < l.ban.threshold {
+		return
+	}
+	l.banKey(a, timeNow)
+}
+
+// ban key for duration of its ban level
+//
+// l.mu must be held
+func (l *Limiter[T]) banKey(a *action, timeNow int64) {
+	a.violations = 0
+	a.banLevel = l.banLevel(*a, timeNow)
+	a.bannedUntil = timeNow + l.banDuration(a.banLevel)
+	a.banLevel++
+}
+
+// ban level of key after decay at timeNow
+//
+// l.mu must be held
+func (l *Limiter[T]) banLevel(a action, timeNow int64) int {
+	if a.banLevel == 0 || l.ban.factor <= 1 {
+		return 0
+	}
+	if l.ban.decay <= 0 || timeNow <= a.bannedUntil {
+		return a.banLevel
+	}
+
+	level := a.banLevel - int((timeNow-a.bannedUntil)/l.ban.decay)
+	if level < 0 {
+		level = 0
+	}
+	return level
+}
+
+// duration of ban for key with ban level
+//
+// l.mu must be held
+func (l *Limiter[T]) banDuration(level int) int64 {
+	d := float64(l.ban.duration)
+	for i := 0; i < level && l.ban.factor > 1; i++ {
+		d *= l.ban.factor
+		if l.ban.maxDuration > 0 && d >= float64(l.ban.maxDuration) {
+			return l.ban.maxDuration
+		}
+	}
+	return int64(d)
+}
@@ -0,0 +1,243 @@
+package limiter
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ssleert/mu"
+	"golang.org/x/exp/constraints"
+)
+
+// state of key circuit
+type BreakerState int
+
+const (
+	// calls go through and errors are counted
+	BreakerClosed BreakerState = iota
+	// calls are rejected until open time ends
+	BreakerOpen
+	// few probe calls go through to check
+	// if key has recovered
+	BreakerHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+type circuit struct {
+	state BreakerState
+
+	// closed state window
+	windowStart int64
+	requests    int
+	failures    int
+
+	openedAt int64
+	// half open probes let in and succeeded
+	probes    int
+	successes int
+
+	lastTime int64
+}
+
+// circuit breaker with own circuit for every key
+// it opens key circuit when its error rate
+// in window goes over threshold
+type Breaker[T constraints.Ordered] struct {
+	m  map[T]circuit
+	mu sync.Mutex
+
+	// error rate that opens circuit
+	threshold float64
+	// min calls in window before circuit can open
+	minRequests int
+	window      int64
+	openTime    int64
+	// probe calls in half open state
+	probes int
+
+	// see SetClock()
+	clock atomic.Pointer[func() time.Time]
+}
+
+// make new breaker that opens key circuit for openTime
+// when at least threshold part of at least minRequests
+// calls in window failed
+//
+// after openTime 1 probe call goes through and
+// its success closes circuit, use SetProbes() for more
+// resolution is one second
+func NewBreaker[T constraints.Ordered](
+	threshold float64,
+	minRequests int,
+	window,
+	openTime time.Duration,
+) *Breaker[T] {
+	if minRequests < 1 {
+		minRequests = 1
+	}
+	return &Breaker[T]{
+		m:           make(map[T]circuit, defaultMapLen),
+		threshold:   threshold,
+		minRequests: minRequests,
+		window:      int64(window / time.Second),
+		openTime:    int64(openTime / time.Second),
+		probes:      1,
+	}
+}
+
+// set count of probe calls in half open state
+// all of them must succeed to close circuit
+func (b *Breaker[T]) SetProbes(n int) {
+	if n < 1 {
+		n = 1
+	}
+	mu.ExecMutex(&b.mu, func() {
+		b.probes = n
+	})
+}
+
+// use now instead of time.Now() for circuit
+// windows and open time, e.g. for tests
+//
+// nil now resets clock to time.Now()
+func (b *Breaker[T]) SetClock(now func() time.Time) {
+	if now == nil {
+		b.clock.Store(nil)
+		return
+	}
+	b.clock.Store(&now)
+}
+
+// current time of breaker clock
+func (b *Breaker[T]) now() time.Time {
+	if f := b.clock.Load(); f != nil {
+		return (*f)()
+	}
+	return time.Now()
+}
+
+// true if call for id can go now
+// every allowed call must be followed by Done()
+func (b *Breaker[T]) Allow(id T) bool {
+	timeNow := b.now().Unix()
+
+	var ok bool
+	mu.ExecMutex(&b.mu, func() {
+		c := b.current(b.m[id], timeNow)
+		c.lastTime = timeNow
+		switch c.state {
+		case BreakerClosed:
+			ok = true
+		case BreakerHalfOpen:
+			if c.probes < b.probes {
+				c.probes++
+				ok = true
+			}
+		}
+		b.m[id] = c
+	})
+	return ok
+}
+
+// record result of call for id allowed by Allow()
+// nil err means success
+func (b *Breaker[T]) Done(id T, err error) {
+	timeNow := b.now().Unix()
+
+	mu.ExecMutex(&b.mu, func() {
+		c := b.current(b.m[id], timeNow)
+		c.lastTime = timeNow
+		switch c.state {
+		case BreakerClosed:
+			c.requests++
+			if err != nil {
+				c.failures++
+			}
+			if c.requests >= b.minRequests &&
+				float64(c.failures) >= b.threshold*float64(c.requests) {
+				c = b.open(c, timeNow)
+			}
+		case BreakerHalfOpen:
+			if err != nil {
+				c = b.open(c, timeNow)
+				break
+			}
+			c.successes++
+			if c.successes >= b.probes {
+				c = circuit{
+					windowStart: timeNow,
+					lastTime:    timeNow,
+				}
+			}
+		}
+		b.m[id] = c
+	})
+}
+
+// get state of id circuit
+func (b *Breaker[T]) State(id T) BreakerState {
+	timeNow := b.now().Unix()
+
+	var s BreakerState
+	mu.ExecMutex(&b.mu, func() {
+		s = b.current(b.m[id], timeNow).state
+	})
+	return s
+}
+
+// remove closed circuits without calls in last window
+// returns count of removed circuits
+func (b *Breaker[T]) Clean() int {
+	timeNow := b.now().Unix()
+
+	var removed int
+	mu.ExecMutex(&b.mu, func() {
+		for id, c := range b.m {
+			if c.state == BreakerClosed && timeNow-c.lastTime >= b.window {
+				delete(b.m, id)
+				removed++
+			}
+		}
+	})
+	return removed
+}
+
+// circuit c moved to timeNow
+//
+// b.mu must be held
+func (b *Breaker[T]) current(c circuit, timeNow int64) circuit {
+	switch c.state {
+	case BreakerClosed:
+		if timeNow-c.windowStart >= b.window {
+			c.windowStart = timeNow
+			c.requests = 0
+			c.failures = 0
+		}
+	case BreakerOpen:
+		if timeNow-c.openedAt >= b.openTime {
+			c.state = BreakerHalfOpen
+			c.probes = 0
+			c.successes = 0
+		}
+	}
+	return c
+}
+
+func (b *Breaker[T]) open(c circuit, timeNow int64) circuit {
+	c.state = BreakerOpen
+	c.openedAt = timeNow
+	c.requests = 0
+	c.failures = 0
+	return c
+}
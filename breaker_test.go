@@ -0,0 +1,110 @@
+package limiter_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ssleert/limiter"
+	"github.com/ssleert/limiter/limitertest"
+)
+
+var errCall = errors.New("call failed")
+
+func TestBreaker(t *testing.T) {
+	tests := []struct {
+		name string
+		// results of calls made right after start
+		results []error
+		// clock moved after calls
+		advance time.Duration
+		want    limiter.BreakerState
+	}{
+		{
+			name:    "closed under min requests",
+			results: []error{errCall},
+			want:    limiter.BreakerClosed,
+		},
+		{
+			name:    "closed under threshold",
+			results: []error{nil, nil, errCall},
+			want:    limiter.BreakerClosed,
+		},
+		{
+			name:    "opens",
+			results: []error{errCall, errCall},
+			want:    limiter.BreakerOpen,
+		},
+		{
+			name:    "still open",
+			results: []error{errCall, errCall},
+			advance: 9 * time.Second,
+			want:    limiter.BreakerOpen,
+		},
+		{
+			name:    "half open after open time",
+			results: []error{errCall, errCall},
+			advance: 10 * time.Second,
+			want:    limiter.BreakerHalfOpen,
+		},
+		{
+			name:    "window ends",
+			results: []error{errCall},
+			advance: time.Minute,
+			want:    limiter.BreakerClosed,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := limiter.NewBreaker[string](0.5, 2, time.Minute, 10*time.Second)
+			c := limitertest.NewClock(time.Unix(1000, 0))
+			c.Attach(b)
+
+			for _, err := range tt.results {
+				if !b.Allow("a") {
+					break
+				}
+				b.Done("a", err)
+			}
+			c.Advance(tt.advance)
+			if got := b.State("a"); got != tt.want {
+				t.Fatalf("State(a) = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBreakerProbes(t *testing.T) {
+	tests := []struct {
+		name  string
+		probe error
+		want  limiter.BreakerState
+	}{
+		{"success closes", nil, limiter.BreakerClosed},
+		{"failure opens", errCall, limiter.BreakerOpen},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := limiter.NewBreaker[string](0.5, 1, time.Minute, 10*time.Second)
+			c := limitertest.NewClock(time.Unix(1000, 0))
+			c.Attach(b)
+
+			b.Allow("a")
+			b.Done("a", errCall)
+			if b.Allow("a") {
+				t.Fatal("Allow(a) of open circuit = true")
+			}
+			c.Advance(10 * time.Second)
+			if !b.Allow("a") {
+				t.Fatal("Allow(a) probe = false")
+			}
+			if b.Allow("a") {
+				t.Fatal("Allow(a) second probe = true")
+			}
+			b.Done("a", tt.probe)
+			if got := b.State("a"); got != tt.want {
+				t.Fatalf("State(a) = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package limiter
+
+import (
+	"math"
+	"time"
+)
+
+// entry for new key
+func (p policy) fresh(timeNow int64) action {
+	return action{
+		deltaTime: p.start(timeNow),
+		lastTime:  timeNow,
+		firstTime: timeNow,
+		tokens:    float64(p.burst),
+		refilled:  timeNow,
+	}
+}
+
+// spend n units of key if policy allows it
+// with overdraft tokens can go below zero
+func (p policy) admit(a *action, n int, timeNow int64) bool {
+	if p.burst <= 0 {
+		limit := p.maxCount + a.carry
+		if a.count+n > limit+p.overdraft-p.reserve {
+			return false
+		}
+		if over := a.count + n - limit; over > 0 {
+			if over > n {
+				over = n
+			}
+			a.debt += over
+		}
+		a.count += n
+		return true
+	}
+
+	a.tokens = p.tokens(*a, timeNow)
+	a.refilled = timeNow
+	if a.tokens < float64(n-p.overdraft+p.reserve) {
+		return false
+	}
+	a.tokens -= float64(n)
+	a.count += n
+	return true
+}
+
+// tokens in key bucket at timeNow
+// bucket gets maxCount tokens every maxTime
+// and holds up to burst of them
+func (p policy) tokens(a action, timeNow int64) float64 {
+	t := a.tokens + float64(timeNow-a.refilled)*
+		float64(p.maxCount)/float64(p.maxTime)
+	if t > float64(p.burst) {
+		t = float64(p.burst)
+	}
+	return t
+}
+
+// give n units back to key
+func (p policy) refund(a *action, n int) {
+	if p.burst > 0 {
+		a.tokens += float64(n)
+		if a.tokens > float64(p.burst) {
+			a.tokens = float64(p.burst)
+		}
+	}
+
+	if a.debt > 0 {
+		d := n
+		if d > a.debt {
+			d = a.debt
+		}
+		a.debt -= d
+	}
+	a.count -= n
+	if a.count < 0 {
+		a.count = 0
+	}
+}
+
+// earliest time when key can spend n units
+// timeNow if it can do it now
+// and -1 if it never can
+func (p policy) readyAt(a action, n int, timeNow int64) int64 {
+	if p.burst <= 0 {
+		if a.count+n <= p.maxCount+a.carry+p.overdraft {
+			return timeNow
+		}
+		if n > p.maxCount+p.overdraft {
+			return -1
+		}
+		return p.end(a)
+	}
+
+	need := float64(n-p.overdraft) - p.tokens(a, timeNow)
+	if need <= 0 {
+		return timeNow
+	}
+	if n-p.overdraft > p.burst {
+		return -1
+	}
+	rate := float64(p.maxCount) / float64(p.maxTime)
+	return timeNow + int64(math.Ceil(need/rate))
+}
+
+// move next paced action of key for n units
+func (p policy) pace(a *action, n int, nowNano int64) {
+	interval := p.maxTime * int64(time.Second) / int64(p.maxCount)
+	if a.pacedUntil < nowNano {
+		a.pacedUntil = nowNano
+	}
+	a.pacedUntil += int64(n) * interval
+}
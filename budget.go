@@ -0,0 +1,150 @@
+package limiter
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ssleert/mu"
+	"golang.org/x/exp/constraints"
+)
+
+type retryWindow struct {
+	start int64
+	// successes and retries in current window
+	successes int
+	retries   int
+	// same in previous window
+	prevSuccesses int
+	prevRetries   int
+}
+
+// retry budget with own budget for every key
+// like finagle one, retries of key are allowed
+// only up to ratio of its recent successful
+// requests, so retry storms can't multiply load
+// when upstream is already failing
+type RetryBudget[T constraints.Ordered] struct {
+	m  map[T]retryWindow
+	mu sync.Mutex
+
+	ratio float64
+	// retries allowed in window without successes
+	min    int
+	window int64
+}
+
+// make new budget that allows ratio retries
+// per success in last window, like 0.1 for 10%
+// plus min retries per window, so keys with
+// few requests can retry too
+// resolution is one second
+func NewRetryBudget[T constraints.Ordered](
+	ratio float64,
+	min int,
+	window time.Duration,
+) *RetryBudget[T] {
+	w := int64(window / time.Second)
+	if w < 1 {
+		w = 1
+	}
+	return &RetryBudget[T]{
+		m:      make(map[T]retryWindow, defaultMapLen),
+		ratio:  ratio,
+		min:    min,
+		window: w,
+	}
+}
+
+// record successful request of key
+func (b *RetryBudget[T]) Success(id T) {
+	timeNow := time.Now().Unix()
+	mu.ExecMutex(&b.mu, func() {
+		w := b.current(b.m[id], timeNow)
+		w.successes++
+		b.m[id] = w
+	})
+}
+
+// true if key can retry now
+// allowed retry is spent from budget
+func (b *RetryBudget[T]) TryRetry(id T) bool {
+	timeNow := time.Now().Unix()
+
+	var ok bool
+	mu.ExecMutex(&b.mu, func() {
+		w := b.current(b.m[id], timeNow)
+		successes, retries := b.recent(w, timeNow)
+		if retries+1 > b.ratio*successes+float64(b.min) {
+			b.m[id] = w
+			return
+		}
+		w.retries++
+		b.m[id] = w
+		ok = true
+	})
+	return ok
+}
+
+// retries key can make now
+func (b *RetryBudget[T]) Left(id T) int {
+	timeNow := time.Now().Unix()
+
+	var left float64
+	mu.ExecMutex(&b.mu, func() {
+		w := b.current(b.m[id], timeNow)
+		successes, retries := b.recent(w, timeNow)
+		left = b.ratio*successes + float64(b.min) - retries
+	})
+	if left < 0 {
+		return 0
+	}
+	return int(left)
+}
+
+// remove keys without requests in last two windows
+// returns count of removed keys
+func (b *RetryBudget[T]) Clean() int {
+	timeNow := time.Now().Unix()
+
+	var removed int
+	mu.ExecMutex(&b.mu, func() {
+		for id, w := range b.m {
+			if timeNow-w.start >= 2*b.window {
+				delete(b.m, id)
+				removed++
+			}
+		}
+	})
+	return removed
+}
+
+// move window of key to timeNow
+//
+// b.mu must be held
+func (b *RetryBudget[T]) current(w retryWindow, timeNow int64) retryWindow {
+	start := timeNow - timeNow%b.window
+	switch {
+	case w.start == start:
+	case w.start == start-b.window:
+		w = retryWindow{
+			start:         start,
+			prevSuccesses: w.successes,
+			prevRetries:   w.retries,
+		}
+	default:
+		w = retryWindow{start: start}
+	}
+	return w
+}
+
+// successes and retries in last window length
+// previous window is weighted by its part
+// still in sliding window
+//
+// b.mu must be held
+func (b *RetryBudget[T]) recent(w retryWindow, timeNow int64) (float64, float64) {
+	prev := 1 - float64(timeNow-w.start)/float64(b.window)
+	successes := float64(w.successes) + prev*float64(w.prevSuccesses)
+	retries := float64(w.retries) + prev*float64(w.prevRetries)
+	return successes, retries
+}
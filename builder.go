@@ -0,0 +1,175 @@
+package limiter
+
+import (
+	"time"
+
+	"golang.org/x/exp/constraints"
+)
+
+// fluent alternative to New() and setters
+//
+//	l := limiter.Build[string]().
+//		Count(100).
+//		Window(time.Minute).
+//		MaxKeys(1e6).
+//		Strict().
+//		New()
+type Builder[T constraints.Ordered] struct {
+	maxCount    int
+	window      time.Duration
+	burst       int
+	schedule    Schedule
+	mapLen      int
+	maxMapLen   int
+	cleanAtOnce int
+
+	fullPolicy FullPolicy
+	idleTTL    time.Duration
+	noAuto     bool
+	dryRun     bool
+	keys       map[T]Policy
+	allow      []T
+	deny       []T
+}
+
+// start building limiter with defaults
+func Build[T constraints.Ordered]() *Builder[T] {
+	return &Builder[T]{
+		mapLen:      Default,
+		maxMapLen:   Default,
+		cleanAtOnce: Default,
+		window:      defaultMaxTime * time.Second,
+	}
+}
+
+// max actions in one window
+func (b *Builder[T]) Count(n int) *Builder[T] {
+	b.maxCount = n
+	return b
+}
+
+// window length, resolution is one second
+func (b *Builder[T]) Window(d time.Duration) *Builder[T] {
+	b.window = d
+	return b
+}
+
+// see Policy.Burst
+func (b *Builder[T]) Burst(n int) *Builder[T] {
+	b.burst = n
+	return b
+}
+
+// align windows to unix epoch instead of key first action
+// call it after Window()
+func (b *Builder[T]) Aligned() *Builder[T] {
+	b.schedule = Aligned(b.window)
+	return b
+}
+
+// see Policy.Schedule
+func (b *Builder[T]) Schedule(s Schedule) *Builder[T] {
+	b.schedule = s
+	return b
+}
+
+// hashmap size for first allocation
+func (b *Builder[T]) MapLen(n int) *Builder[T] {
+	b.mapLen = n
+	return b
+}
+
+// max keys before clean up, 0 means unlimited
+func (b *Builder[T]) MaxKeys(n int) *Builder[T] {
+	b.maxMapLen = n
+	return b
+}
+
+// how many entries one Clean() checks
+func (b *Builder[T]) CleanAtOnce(n int) *Builder[T] {
+	b.cleanAtOnce = n
+	return b
+}
+
+// see SetFullPolicy()
+func (b *Builder[T]) Full(p FullPolicy) *Builder[T] {
+	b.fullPolicy = p
+	return b
+}
+
+// fail closed, deny new keys when map is full
+func (b *Builder[T]) Strict() *Builder[T] {
+	return b.Full(FullDeny)
+}
+
+// see SetIdleTTL()
+func (b *Builder[T]) IdleTTL(d time.Duration) *Builder[T] {
+	b.idleTTL = d
+	return b
+}
+
+// never spawn clean up goroutines from Try()
+func (b *Builder[T]) ManualClean() *Builder[T] {
+	b.noAuto = true
+	return b
+}
+
+// see SetDryRun()
+func (b *Builder[T]) DryRun() *Builder[T] {
+	b.dryRun = true
+	return b
+}
+
+// own policy for key
+func (b *Builder[T]) Key(id T, p Policy) *Builder[T] {
+	if b.keys == nil {
+		b.keys = make(map[T]Policy)
+	}
+	b.keys[id] = p
+	return b
+}
+
+// see AllowKeys()
+func (b *Builder[T]) Allow(ids ...T) *Builder[T] {
+	b.allow = append(b.allow, ids...)
+	return b
+}
+
+// see DenyKeys()
+func (b *Builder[T]) Deny(ids ...T) *Builder[T] {
+	b.deny = append(b.deny, ids...)
+	return b
+}
+
+// make limiter
+func (b *Builder[T]) New() *Limiter[T] {
+	l := New[T](
+		b.maxCount,
+		int64(b.window/time.Second),
+		b.mapLen,
+		b.maxMapLen,
+		b.cleanAtOnce,
+	)
+	if b.burst > 0 || b.schedule != nil {
+		l.SetPolicy(Policy{
+			MaxCount: b.maxCount,
+			Window:   b.window,
+			Burst:    b.burst,
+			Schedule: b.schedule,
+		})
+	}
+	l.fullPolicy = b.fullPolicy
+	l.idleTTL = int64(b.idleTTL / time.Second)
+	l.autoClean = !b.noAuto
+	l.dryRun = b.dryRun
+	for id, p := range b.keys {
+		l.SetKeyPolicy(id, p)
+	}
+	if len(b.allow) > 0 {
+		l.AllowKeys(b.allow...)
+	}
+	if len(b.deny) > 0 {
+		l.DenyKeys(b.deny...)
+	}
+	return l
+}
@@ -0,0 +1,89 @@
+package limiter
+
+import (
+	"context"
+	"time"
+
+	"golang.org/x/exp/constraints"
+)
+
+// small bursts of this instance over fleet wide
+// sustained rate, local limiter is usually a bucket
+// with Burst and global one counts the sustained rate
+// in the whole fleet over a longer window
+//
+// action must pass local limiter first, so bursts
+// over it never reach shared store, and then global one
+type LocalBurst[T constraints.Ordered] struct {
+	local  *Limiter[T]
+	global *Distributed[T]
+}
+
+// combine local limiter with global one
+func NewLocalBurst[T constraints.Ordered](
+	local *Limiter[T],
+	global *Distributed[T],
+) *LocalBurst[T] {
+	return &LocalBurst[T]{
+		local:  local,
+		global: global,
+	}
+}
+
+func (b *LocalBurst[T]) Local() *Limiter[T] {
+	return b.local
+}
+
+func (b *LocalBurst[T]) Global() *Distributed[T] {
+	return b.global
+}
+
+func (b *LocalBurst[T]) Try(id T) bool {
+	return b.TryN(id, 1)
+}
+
+// true if key has n units in both limiters
+// units spent locally are refunded
+// if global limiter denies them
+func (b *LocalBurst[T]) TryN(id T, n int) bool {
+	if !b.local.TryN(id, n) {
+		return false
+	}
+	if !b.global.TryN(id, n) {
+		b.local.Refund(id, n)
+		return false
+	}
+	return true
+}
+
+// wait until key has budget in both limiters
+// or ctx is done
+func (b *LocalBurst[T]) Wait(ctx context.Context, id T) error {
+	for {
+		if err := b.local.Wait(ctx, id); err != nil {
+			return err
+		}
+		if b.global.Try(id) {
+			return nil
+		}
+		b.local.Refund(id, 1)
+
+		timeNow := b.local.now()
+		next := timeNow.Unix() - timeNow.Unix()%b.global.window + b.global.window
+		t := time.NewTimer(time.Unix(next, 0).Sub(timeNow))
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+// reset key in local limiter and in global one
+// if it can be reset, see Distributed.Reset()
+func (b *LocalBurst[T]) Reset(id T) bool {
+	local := b.local.Reset(id)
+	global := b.global.Reset(id)
+	return local || global
+}
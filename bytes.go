@@ -0,0 +1,38 @@
+package limiter
+
+import "math"
+
+// set bytes in one unit of TryBytes()
+// so policy MaxCount is in units, counts are
+// kept in 32 bits so limits over 2 GiB per
+// window need unit like 1024
+//
+// unit < 1 sets it to 1
+func (l *Limiter[T]) SetByteUnit(unit int64) {
+	if unit < 1 {
+		unit = 1
+	}
+	l.byteUnit.Store(unit)
+}
+
+// spend n bytes of key, e.g. upload size
+// same limiter can govern payload heavy endpoints
+// with policy in bytes per window
+// n is rounded up to byte units, see SetByteUnit()
+// and n < 1 is counted as one unit
+func (l *Limiter[T]) TryBytes(id T, n int64) bool {
+	return l.TryN(id, l.units(n))
+}
+
+// units of n bytes
+func (l *Limiter[T]) units(n int64) int {
+	unit := l.byteUnit.Load()
+	if unit < 1 {
+		unit = 1
+	}
+	u := (n + unit - 1) / unit
+	if u > math.MaxInt32 {
+		u = math.MaxInt32
+	}
+	return int(u)
+}
@@ -0,0 +1,607 @@
+package limiter
+
+import (
+	"context"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/ssleert/mu"
+)
+
+// check next cleanAtOnce entries and remove expired ones
+// each call continues from the key where previous one stopped
+// and new scan starts when all keys are checked
+//
+// namespaces of l are cleaned the same way
+//
+// returns count of removed and checked entries
+// if other clean up is running it returns zeros
+func (l *Limiter[T]) Clean() (removed int, scanned int) {
+	for _, ns := range l.children() {
+		r, s := ns.Clean()
+		removed += r
+		scanned += s
+	}
+	if !l.cleaning.CompareAndSwap(false, true) {
+		return removed, scanned
+	}
+	defer l.cleaning.Store(false)
+
+	start := l.wallNow()
+	r, s, _ := l.cleanOnce(context.Background())
+	l.cleanDone(start, r, s)
+
+	return removed + r, scanned + s
+}
+
+// clean expired entries until ctx is done or budget is spent
+// next call continues from the key where previous one stopped
+// namespaces of l are cleaned too and budget
+// is split equally between l and them
+//
+// if budget <= 0 run is bounded only by ctx
+// returns count of removed and checked entries
+// and ctx.Err() if run was interrupted by ctx
+func (l *Limiter[T]) CleanContext(
+	ctx context.Context,
+	budget time.Duration,
+) (removed int, scanned int, err error) {
+	children := l.children()
+	if budget > 0 {
+		budget /= time.Duration(len(children) + 1)
+	}
+
+	removed, scanned, err = l.cleanOwn(ctx, budget)
+	for _, ns := range children {
+		if err != nil {
+			break
+		}
+		var r, s int
+		r, s, err = ns.CleanContext(ctx, budget)
+		removed += r
+		scanned += s
+	}
+	return removed, scanned, err
+}
+
+// CleanContext() of l without namespaces
+func (l *Limiter[T]) cleanOwn(
+	ctx context.Context,
+	budget time.Duration,
+) (removed int, scanned int, err error) {
+	if !l.cleaning.CompareAndSwap(false, true) {
+		return 0, 0, nil
+	}
+	defer l.cleaning.Store(false)
+
+	start := l.wallNow()
+	defer func() {
+		l.cleanDone(start, removed, scanned)
+	}()
+	for {
+		if err := ctx.Err(); err != nil {
+			return removed, scanned, err
+		}
+		if budget > 0 && l.wallNow().Sub(start) >= budget {
+			return removed, scanned, nil
+		}
+
+		r, s, done := l.cleanOnce(ctx)
+		removed += r
+		scanned += s
+		if done {
+			return removed, scanned, nil
+		}
+		runtime.Gosched()
+	}
+}
+
+// interval of Janitor() if given one is not positive
+const defaultJanitorInterval = time.Second
+
+// run CleanContext() with budget every interval until ctx is done
+// it blocks so run it in your own goroutine
+// together with SetAutoClean(false)
+// interval <= 0 runs it every second
+//
+// returns ctx.Err()
+func (l *Limiter[T]) Janitor(
+	ctx context.Context,
+	interval,
+	budget time.Duration,
+) error {
+	ctx, unlabel := l.label(ctx, "janitor")
+	defer unlabel()
+
+	if interval <= 0 {
+		interval = defaultJanitorInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			l.CleanContext(ctx, budget)
+		}
+	}
+}
+
+// check n entries on every Try() and remove expired ones
+// so clean up cost is spread over calls instead of
+// Clean() bursts, entries are taken from random place
+// of map, and it's off in deterministic mode
+//
+// n <= 0 disables it
+func (l *Limiter[T]) SetTryClean(n int) {
+	mu.ExecMutex(&l.mu, func() {
+		l.tryClean = n
+	})
+}
+
+// remove expired ones of l.tryClean entries except id
+//
+// l.mu must be held
+func (l *Limiter[T]) expireSome(id T, timeNow int64) {
+	if l.tryClean <= 0 || l.deterministic.Load() {
+		return
+	}
+	var removed, i int
+	for key, val := range l.m {
+		if i == l.tryClean {
+			break
+		}
+		i++
+		if key == id {
+			continue
+		}
+		a, p := val.unpack(), l.policyOf(key)
+		if l.expired(a, p, timeNow) {
+			l.expire(key, a, p, timeNow)
+			removed++
+		}
+	}
+	l.stats.cleaned.Add(uint64(removed))
+}
+
+// maps smaller than it are never rebuilt
+const shrinkMin = 1024
+
+type shrink struct {
+	// 0 disables shrinking
+	ratio float64
+	// max entries since map was made
+	peak int
+}
+
+// rebuild map after full clean up scan when live entries
+// are under ratio of max entries since it was made,
+// go maps never give memory of removed entries back
+// so map stays as big as it was at traffic peak
+//
+// rebuild copies all entries under lock
+// so ratio like 0.25 makes it rare
+// ratio <= 0 disables it
+func (l *Limiter[T]) SetShrink(ratio float64) {
+	mu.ExecMutex(&l.mu, func() {
+		l.shrink.ratio = ratio
+	})
+}
+
+// rebuild map if it's mostly empty
+//
+// l.mu must be held
+func (l *Limiter[T]) shrinkMap() {
+	s := l.shrink
+	if s.ratio <= 0 || s.peak < shrinkMin ||
+		float64(len(l.m)) >= s.ratio*float64(s.peak) {
+		return
+	}
+	m := make(map[T]packed, len(l.m))
+	for key, val := range l.m {
+		m[key] = val
+	}
+	l.m = m
+	l.shrink.peak = len(m)
+}
+
+// record and log finished clean up run
+func (l *Limiter[T]) cleanDone(start time.Time, removed, scanned int) {
+	d := l.wallNow().Sub(start)
+	l.cleanDuration.Store(int64(d))
+
+	var log LogFunc
+	mu.ExecMutex(&l.mu, func() {
+		log = l.logs.clean
+		l.cleanInfo.runs++
+		l.cleanInfo.scanned += uint64(scanned)
+		l.cleanInfo.lastRemoved = removed
+		l.cleanInfo.lastScanned = scanned
+		l.cleanInfo.lastRun = l.now()
+	})
+	if log != nil {
+		log(
+			"limiter: clean up",
+			"removed", removed,
+			"scanned", scanned,
+			"duration", d,
+		)
+	}
+}
+
+// duration of last Clean() or CleanContext() run
+func (l *Limiter[T]) LastCleanDuration() time.Duration {
+	return time.Duration(l.cleanDuration.Load())
+}
+
+// check entries of clean up step with n goroutines
+// each of them checks its own part of step keys
+// under read lock, so Try() calls run between
+// them and write lock is taken only to remove
+// expired entries, step is n times bigger than
+// one of Clean() without workers
+//
+// it makes scans of maps with millions of keys
+// faster on multicore machines, removal itself
+// is still done by one goroutine
+// CleanContext() budget is checked between steps
+// so step length is budget resolution
+//
+// n is bounded by GOMAXPROCS
+// n <= 1 checks entries in caller goroutine
+func (l *Limiter[T]) SetCleanWorkers(n int) {
+	mu.ExecMutex(&l.mu, func() {
+		l.cleanWorkers = n
+	})
+}
+
+// one clean up step with or without workers
+func (l *Limiter[T]) cleanOnce(ctx context.Context) (removed int, scanned int, done bool) {
+	var workers int
+	mu.ExecRWMutex(&l.mu, func() {
+		workers = l.cleanWorkers
+	})
+	if procs := runtime.GOMAXPROCS(0); workers > procs {
+		workers = procs
+	}
+	if workers > 1 {
+		removed, scanned, done = l.cleanParallel(ctx, workers)
+	} else {
+		mu.ExecMutex(&l.mu, func() {
+			removed, scanned, done = l.cleanStep()
+		})
+	}
+	if removed > 0 {
+		l.notifyExpired()
+	}
+	return removed, scanned, done
+}
+
+// clean next cleanAtOnce keys from snapshot
+// returns count of removed and checked keys
+// and true when snapshot is fully processed
+//
+// l.mu must be held
+func (l *Limiter[T]) cleanStep() (removed int, scanned int, done bool) {
+	keys := l.cleanNext(l.cleanAtOnce)
+	timeNow := l.now().Unix()
+	removed = l.removeExpired(keys, timeNow)
+	return removed, len(keys), l.cleanFinish(timeNow)
+}
+
+// entry found expired by clean up worker
+type seenEntry[T any] struct {
+	key T
+	p   packed
+}
+
+// cleanStep() of workers*cleanAtOnce keys
+// checked by workers goroutines
+func (l *Limiter[T]) cleanParallel(
+	ctx context.Context,
+	workers int,
+) (removed int, scanned int, done bool) {
+	var (
+		keys    []T
+		timeNow int64
+	)
+	mu.ExecMutex(&l.mu, func() {
+		keys = l.cleanNext(workers * l.cleanAtOnce)
+		timeNow = l.now().Unix()
+	})
+
+	// expired entries found by each worker
+	found := make([][]seenEntry[T], workers)
+	var wg sync.WaitGroup
+	for i := range found {
+		part := keys[len(keys)*i/workers : len(keys)*(i+1)/workers]
+		if len(part) == 0 {
+			continue
+		}
+		res := &found[i]
+		wg.Add(1)
+		go l.do(ctx, "clean", func(context.Context) {
+			defer wg.Done()
+			mu.ExecRWMutex(&l.mu, func() {
+				for _, key := range part {
+					p, ok := l.m[key]
+					if !ok || !l.expired(p.unpack(), l.policyOf(key), timeNow) {
+						continue
+					}
+					*res = append(*res, seenEntry[T]{key, p})
+				}
+			})
+		})
+	}
+	wg.Wait()
+
+	// entries changed after check can be
+	// not expired anymore, so they stay
+	mu.ExecMutex(&l.mu, func() {
+		for _, part := range found {
+			for _, e := range part {
+				if p, ok := l.m[e.key]; ok && p == e.p {
+					l.expire(e.key, p.unpack(), l.policyOf(e.key), timeNow)
+					removed++
+				}
+			}
+		}
+		for _, key := range keys {
+			if _, ok := l.m[key]; ok {
+				l.keep(key)
+			}
+		}
+		l.stats.cleaned.Add(uint64(removed))
+		done = l.cleanFinish(timeNow)
+	})
+	return removed, len(keys), done
+}
+
+// take next n keys of clean up snapshot
+// new snapshot is made when previous one is done
+//
+// l.mu must be held
+func (l *Limiter[T]) cleanNext(n int) []T {
+	if l.cleanKeys == nil {
+		l.cleanKeys = l.cleanOrder()
+		l.cleanPos = 0
+		// deterministic order needs sorted copy every scan
+		l.cleanLog = !l.deterministic.Load()
+		if len(l.cleanKeys) > l.shrink.peak {
+			l.shrink.peak = len(l.cleanKeys)
+		}
+	}
+
+	end := l.cleanPos + n
+	if end > len(l.cleanKeys) {
+		end = len(l.cleanKeys)
+	}
+	keys := l.cleanKeys[l.cleanPos:end]
+	l.cleanPos = end
+	return keys
+}
+
+// remove expired entries of keys
+// returns count of removed ones
+//
+// l.mu must be held
+func (l *Limiter[T]) removeExpired(keys []T, timeNow int64) int {
+	var removed int
+	for _, key := range keys {
+		val, ok := l.get(key)
+		if !ok {
+			continue
+		}
+		if p := l.policyOf(key); l.expired(val, p, timeNow) {
+			l.expire(key, val, p, timeNow)
+			removed++
+			continue
+		}
+		l.keep(key)
+	}
+	l.stats.cleaned.Add(uint64(removed))
+	return removed
+}
+
+// key checked by scan stays for next one
+//
+// l.mu must be held
+func (l *Limiter[T]) keep(id T) {
+	if l.cleanLog {
+		l.cleanKept = append(l.cleanKept, id)
+	}
+}
+
+// queue key added to map for next scan
+// log is dropped if keys churn so much that
+// it outgrows map, next scan copies map then
+//
+// l.mu must be held
+func (l *Limiter[T]) logNew(id T) {
+	if len(l.cleanNew) > 2*len(l.m)+l.cleanAtOnce {
+		l.cleanLog = false
+		l.cleanKept = nil
+		l.cleanNew = nil
+		return
+	}
+	l.cleanNew = append(l.cleanNew, id)
+}
+
+// make snapshot of next scan from log
+// or leave it to cleanNext() if log is off
+//
+// l.mu must be held
+func (l *Limiter[T]) nextScan() {
+	// keys removed and added again are logged twice
+	if !l.cleanLog ||
+		len(l.cleanKept)+len(l.cleanNew) > 2*len(l.m)+l.cleanAtOnce {
+		l.resetScan()
+		return
+	}
+	// snapshot is done, its array keeps next kept keys
+	old := l.cleanKeys[:0]
+	l.cleanKeys = append(l.cleanKept, l.cleanNew...)
+	l.cleanPos = 0
+	l.cleanKept = old
+	l.cleanNew = l.cleanNew[:0]
+	if len(l.cleanKeys) > l.shrink.peak {
+		l.shrink.peak = len(l.cleanKeys)
+	}
+}
+
+// drop snapshot and log
+// next scan copies map again
+//
+// l.mu must be held
+func (l *Limiter[T]) resetScan() {
+	l.cleanKeys = nil
+	l.cleanPos = 0
+	l.cleanKept = nil
+	l.cleanNew = nil
+	l.cleanLog = false
+}
+
+// end full scan when snapshot is fully processed
+// returns true if it was
+//
+// l.mu must be held
+func (l *Limiter[T]) cleanFinish(timeNow int64) bool {
+	if l.cleanPos < len(l.cleanKeys) {
+		return false
+	}
+	l.nextScan()
+	l.cleanBookings(timeNow)
+	l.denyCache.clean(timeNow)
+	l.cleanTombstones(timeNow)
+	l.cleanResolved()
+	l.shrinkMap()
+	l.cleanInfo.lastFullScan = l.now()
+	return true
+}
+
+// clean up counters
+type CleanStats struct {
+	// finished Clean() and CleanContext() runs
+	Runs uint64
+	// entries checked by all runs
+	Scanned uint64
+	// entries removed by all runs
+	Removed uint64
+
+	LastDuration time.Duration
+	LastScanned  int
+	LastRemoved  int
+	// when last run finished
+	LastRun time.Time
+
+	// when all keys were last checked
+	// runs resume each other, so full scan
+	// can take many of them
+	LastFullScan time.Time
+	// time since LastFullScan
+	// grows if clean up falls behind
+	SinceFullScan time.Duration
+}
+
+type cleanInfo struct {
+	runs         uint64
+	scanned      uint64
+	lastScanned  int
+	lastRemoved  int
+	lastRun      time.Time
+	lastFullScan time.Time
+}
+
+// get clean up counters
+func (l *Limiter[T]) CleanStats() CleanStats {
+	var ci cleanInfo
+	mu.ExecRWMutex(&l.mu, func() {
+		ci = l.cleanInfo
+	})
+
+	var since time.Duration
+	if !ci.lastFullScan.IsZero() {
+		since = l.now().Sub(ci.lastFullScan)
+	}
+
+	return CleanStats{
+		Runs:          ci.runs,
+		Scanned:       ci.scanned,
+		Removed:       l.stats.cleaned.Load(),
+		LastDuration:  l.LastCleanDuration(),
+		LastScanned:   ci.lastScanned,
+		LastRemoved:   ci.lastRemoved,
+		LastRun:       ci.lastRun,
+		LastFullScan:  ci.lastFullScan,
+		SinceFullScan: since,
+	}
+}
+
+// remove least recently used not banned entry
+// other than keep, e.g. key that is just added,
+// among first cleanAtOnce entries of map
+// or among all of them in deterministic mode
+// returns removed key
+//
+// l.mu must be held
+func (l *Limiter[T]) evict(timeNow int64, keep ...T) (T, bool) {
+	var (
+		oldest  T
+		oldTime = timeNow + 1
+		found   bool
+		i       int
+		all     = l.deterministic.Load()
+	)
+next:
+	for key, p := range l.m {
+		for _, k := range keep {
+			if key == k {
+				continue next
+			}
+		}
+		if i == l.cleanAtOnce && !all {
+			break
+		}
+		val := p.unpack()
+		older := val.lastTime < oldTime ||
+			all && val.lastTime == oldTime && key < oldest
+		if older && !val.banned(timeNow) {
+			oldest = key
+			oldTime = val.lastTime
+			found = true
+		}
+		i++
+	}
+	if !found {
+		return oldest, false
+	}
+	l.emit(EventEvicted, oldest, timeNow)
+	l.bury(oldest, l.m[oldest], timeNow)
+	l.remove(oldest)
+	l.stats.evicted.Add(1)
+	return oldest, true
+}
+
+// make space for n new keys in map like Try()
+// does, evicted keys are not in keep
+// returns false if full policy keeps them out
+//
+// l.mu must be held
+func (l *Limiter[T]) makeSpace(n int, timeNow int64, keep ...T) bool {
+	over := len(l.m) + n - l.maxMapLen
+	if n <= 0 || l.maxMapLen <= 0 || over <= 0 {
+		return true
+	}
+	switch l.fullPolicy {
+	case FullDeny:
+		return false
+	case FullEvict:
+		for i := 0; i < over && i < n; i++ {
+			l.evict(timeNow, keep...)
+		}
+	}
+	return true
+}
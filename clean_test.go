@@ -0,0 +1,79 @@
+package limiter_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/ssleert/limiter"
+	"github.com/ssleert/limiter/limitertest"
+)
+
+func TestCleanScans(t *testing.T) {
+	tests := []struct {
+		name    string
+		workers int
+		// keys added before each scan
+		rounds []int
+	}{
+		{"one scan", 1, []int{5}},
+		{"keys added between scans", 1, []int{5, 3, 7}},
+		{"parallel", 2, []int{6, 4, 9}},
+		{"keys gone and back", 1, []int{4, 4, 4}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := limiter.New[string](10, 1, 16, 1024, 2)
+			l.SetAutoClean(false)
+			l.SetCleanWorkers(tt.workers)
+			c := limitertest.Use(l)
+
+			for r, n := range tt.rounds {
+				for i := 0; i < n; i++ {
+					l.Try(fmt.Sprintf("k%d", i))
+					// keys added during scan
+					if i == n/2 {
+						l.Clean()
+					}
+				}
+				c.Advance(2 * time.Second)
+				for i := 0; i < 4*n && l.Len() > 0; i++ {
+					l.Clean()
+				}
+				if got := l.Len(); got != 0 {
+					t.Fatalf("round %d: %d keys left after clean, want 0", r, got)
+				}
+			}
+		})
+	}
+}
+
+func TestJanitorInterval(t *testing.T) {
+	tests := []struct {
+		name     string
+		interval time.Duration
+	}{
+		{"zero", 0},
+		{"negative", -time.Second},
+		{"short", 10 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := limiter.New[string](10, 1, 16, 1024, 16)
+			l.SetAutoClean(false)
+			c := limitertest.Use(l)
+			l.Try("a")
+			c.Advance(2 * time.Second)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 1500*time.Millisecond)
+			defer cancel()
+			if err := l.Janitor(ctx, tt.interval, 0); err != context.DeadlineExceeded {
+				t.Fatalf("Janitor() = %v, want deadline exceeded", err)
+			}
+			if got := l.Len(); got != 0 {
+				t.Fatalf("%d keys left after Janitor(), want 0", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,27 @@
+package limiter
+
+import (
+	"time"
+)
+
+// use now instead of time.Now() for all decisions
+// and timestamps, e.g. for tests and simulations
+// clean up run durations are still measured in real time
+//
+// nil now resets clock to time.Now()
+func (l *Limiter[T]) SetClock(now func() time.Time) {
+	defer l.moveBack()
+	if now == nil {
+		l.clock.Store(nil)
+		return
+	}
+	l.clock.Store(&now)
+}
+
+// current time of limiter clock
+func (l *Limiter[T]) now() time.Time {
+	if f := l.clock.Load(); f != nil {
+		return (*f)()
+	}
+	return time.Now()
+}
@@ -0,0 +1,147 @@
+package limiter
+
+import (
+	"math/rand"
+
+	"github.com/ssleert/mu"
+	"golang.org/x/exp/maps"
+)
+
+// make new limiter with same config as l
+// and copy of its entries if state is true
+// e.g. for shadow or test limiter from live one
+//
+// stats, usage, event stream, audit, shadow limiter,
+// namespaces, waiters, bookings, denied samples,
+// trusted keys and isolation of hot keys are not copied,
+// deterministic clone starts rand from seed again
+func (l *Limiter[T]) Clone(state bool) *Limiter[T] {
+	c := &Limiter[T]{}
+	l.sharedTo(c)
+	mu.ExecRWMutex(&l.mu, func() {
+		c.m = make(map[T]packed, len(l.m))
+		if state {
+			c.m = maps.Clone(l.m)
+			c.resolved = maps.Clone(l.resolved)
+			c.meta = maps.Clone(l.meta)
+			c.health.rates = maps.Clone(l.health.rates)
+			c.tomb.m = maps.Clone(l.tomb.m)
+			l.eachHot(func(id T, hl *Limiter[T], a action) {
+				c.m[id] = hl.m[id]
+				if p, ok := hl.resolved[id]; ok {
+					c.setResolved(id, p)
+				}
+				c.setMeta(id, hl.meta[id])
+				if rate, ok := hl.health.rates[id]; ok {
+					if c.health.rates == nil {
+						c.health.rates = make(map[T]float64)
+					}
+					c.health.rates[id] = rate
+				}
+			})
+		}
+		l.configTo(c)
+		c.keyPolicies = maps.Clone(l.keyPolicies)
+		c.keyScales = maps.Clone(l.keyScales)
+		c.fair.weights = maps.Clone(l.fair.weights)
+		c.allowlist = maps.Clone(l.allowlist)
+		if d := l.denylist.Load(); d != nil {
+			dl := maps.Clone(*d)
+			c.denylist.Store(&dl)
+		}
+	})
+	if state {
+		mu.ExecRWMutex(&l.denyCache.mu, func() {
+			c.denyCache.m = maps.Clone(l.denyCache.m)
+		})
+	}
+	return c
+}
+
+// copy settings of l that are not per key to c
+//
+// l.mu must be held
+func (l *Limiter[T]) configTo(c *Limiter[T]) {
+	c.maxTime = l.maxTime
+	c.maxCount = l.maxCount
+	c.burst = l.burst
+	c.schedule = l.schedule
+	c.maxMapLen = l.maxMapLen
+	c.cleanAtOnce = l.cleanAtOnce
+	c.autoClean = l.autoClean
+	c.tryClean = l.tryClean
+	c.cleanWorkers = l.cleanWorkers
+	c.shrink.ratio = l.shrink.ratio
+	c.health.threshold = l.health.threshold
+	c.health.min = l.health.min
+	c.tomb.ttl = l.tomb.ttl
+	c.fullPolicy = l.fullPolicy
+	c.dryRun = l.dryRun
+	c.ban = l.ban
+	c.cooldown = l.cooldown
+	c.warmup = l.warmup
+	c.prio = append(priorities(nil), l.prio...)
+	c.fair.total = l.fair.total
+	c.fair.period = l.fair.period
+	c.overdraft = l.overdraft
+	c.carryPart = l.carryPart
+	c.carryMax = l.carryMax
+	c.jitter = l.jitter
+	c.pacing = l.pacing
+	c.idleTTL = l.idleTTL
+	c.logs = l.logs
+	c.onDeny = l.onDeny
+	c.onExpire = l.onExpire
+	c.spike = l.spike
+	c.inv = l.inv
+	c.hot.share = l.hot.share
+	c.hot.interval = l.hot.interval
+	c.trust.calls = l.trust.calls
+	c.trust.usage = l.trust.usage
+	c.trust.period = l.trust.period
+	c.seed = l.seed
+	if l.rng != nil {
+		c.rng = rand.New(rand.NewSource(l.seed))
+	}
+}
+
+// copy settings of l that have own locks
+// or are atomic to c
+//
+// l.mu must not be held
+func (l *Limiter[T]) sharedTo(c *Limiter[T]) {
+	c.hot.on.Store(l.hot.on.Load())
+	c.hot.atomic.Store(l.hot.atomic.Load())
+	c.trust.on.Store(l.trust.on.Load())
+	c.denyCache.on.Store(l.denyCache.on.Load())
+
+	l.samples.mu.Lock()
+	c.samples.size = l.samples.size
+	c.samples.on.Store(l.samples.on.Load())
+	if l.samples.rng != nil {
+		c.samples.rng = rand.New(rand.NewSource(l.seed))
+	}
+	l.samples.mu.Unlock()
+
+	l.queue.mu.Lock()
+	c.queue.depth = l.queue.depth
+	c.queue.maxWait = l.queue.maxWait
+	c.queue.order = l.queue.order
+	c.queue.codel = l.queue.codel
+	l.queue.mu.Unlock()
+
+	l.dedup.mu.Lock()
+	c.dedup.window = l.dedup.window
+	l.dedup.mu.Unlock()
+
+	c.hooks.Store(l.hooks.Load())
+	c.exempt.Store(l.exempt.Load())
+	c.resolver.Store(l.resolver.Load())
+	c.clock.Store(l.clock.Load())
+	c.lockEvery.Store(l.lockEvery.Load())
+	c.byteUnit.Store(l.byteUnit.Load())
+	c.loadFactor.Store(l.loadFactor.Load())
+	c.name.Store(l.name.Load())
+	c.paused.Store(l.paused.Load())
+	c.deterministic.Store(l.deterministic.Load())
+}
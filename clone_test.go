@@ -0,0 +1,84 @@
+package limiter_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ssleert/limiter"
+)
+
+func TestClone(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(l *limiter.Limiter[string])
+		check func(t *testing.T, c *limiter.Limiter[string])
+	}{
+		{
+			name:  "name",
+			setup: func(l *limiter.Limiter[string]) { l.SetName("api") },
+			check: func(t *testing.T, c *limiter.Limiter[string]) {
+				if c.Name() != "api" {
+					t.Fatalf("Name() = %q, want api", c.Name())
+				}
+			},
+		},
+		{
+			name:  "paused",
+			setup: func(l *limiter.Limiter[string]) { l.Pause(limiter.PauseAllow) },
+			check: func(t *testing.T, c *limiter.Limiter[string]) {
+				for i := 0; i < 5; i++ {
+					if !c.Try("a") {
+						t.Fatal("paused clone denied action")
+					}
+				}
+			},
+		},
+		{
+			name:  "denylist",
+			setup: func(l *limiter.Limiter[string]) { l.DenyKeys("a") },
+			check: func(t *testing.T, c *limiter.Limiter[string]) {
+				if c.Try("a") {
+					t.Fatal("clone allowed denied key")
+				}
+			},
+		},
+		{
+			name: "samples",
+			setup: func(l *limiter.Limiter[string]) {
+				l.SampleDenials(4)
+			},
+			check: func(t *testing.T, c *limiter.Limiter[string]) {
+				c.TryMeta("a", nil)
+				c.TryMeta("a", nil)
+				c.TryMeta("a", "x")
+				if s, seen := c.DeniedSamples(); len(s) != 1 || seen != 1 {
+					t.Fatalf("DeniedSamples() = %v, %d, want one sample", s, seen)
+				}
+			},
+		},
+		{
+			name: "deterministic",
+			setup: func(l *limiter.Limiter[string]) {
+				l.SetDeterministic(func() time.Time { return time.Unix(100, 0) }, 1)
+				l.SetJitter(30 * time.Second)
+			},
+			check: func(t *testing.T, c *limiter.Limiter[string]) {
+				d := c.Clone(false)
+				c.Try("a")
+				d.Try("a")
+				a, _ := c.KeyStats("a")
+				b, _ := d.KeyStats("a")
+				if !a.ResetAt.Equal(b.ResetAt) {
+					t.Fatalf("clones reset at %v and %v, want same", a.ResetAt, b.ResetAt)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := limiter.New[string](2, 60, 16, 1024, 16)
+			tt.setup(l)
+			tt.check(t, l.Clone(false))
+		})
+	}
+}
@@ -0,0 +1,172 @@
+/*
+limiterctl talks to admin api of limiter
+served by httplimit.Admin
+
+usage:
+
+	limiterctl [-addr url] [-token token] command [args]
+
+commands:
+
+	stats                     limiter stats
+	key ID                    state of key
+	reset ID                  forget state of key
+	unban ID                  lift ban of key
+	top [denied|allowed] [N]  top keys
+	policy                    default policy
+	set-policy RATE [BURST]   set rate of default policy like 100/m
+	                          and burst if it is given
+
+token can be given in LIMITERCTL_TOKEN env var
+*/
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ssleert/limiter"
+)
+
+func main() {
+	addr := flag.String("addr", "http://localhost:6060/debug/limiter", "admin api url")
+	token := flag.String("token", os.Getenv("LIMITERCTL_TOKEN"), "bearer token")
+	timeout := flag.Duration("timeout", 10*time.Second, "request timeout")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "usage: limiterctl [flags] stats|key|reset|unban|top|policy|set-policy [args]")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	c := &client{
+		addr:  strings.TrimSuffix(*addr, "/"),
+		token: *token,
+		http:  &http.Client{Timeout: *timeout},
+	}
+	if err := run(c, flag.Args()); err != nil {
+		fmt.Fprintln(os.Stderr, "limiterctl:", err)
+		os.Exit(1)
+	}
+}
+
+func run(c *client, args []string) error {
+	if len(args) == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	cmd, args := args[0], args[1:]
+	switch cmd {
+	case "stats":
+		return c.do(http.MethodGet, "/stats", nil, nil)
+	case "policy":
+		return c.do(http.MethodGet, "/policy", nil, nil)
+	case "key", "reset", "unban":
+		if len(args) != 1 {
+			return fmt.Errorf("%s needs key id", cmd)
+		}
+		m := http.MethodPost
+		if cmd == "key" {
+			m = http.MethodGet
+		}
+		return c.do(m, "/"+cmd, url.Values{"id": {args[0]}}, nil)
+	case "top":
+		q := url.Values{}
+		if len(args) > 0 {
+			q.Set("by", args[0])
+		}
+		if len(args) > 1 {
+			q.Set("n", args[1])
+		}
+		return c.do(http.MethodGet, "/top", q, nil)
+	case "set-policy":
+		return setPolicy(c, args)
+	}
+	return fmt.Errorf("unknown command %q", cmd)
+}
+
+// only fields given in args are sent
+// so burst and schedule of policy stay
+// when they are not given
+func setPolicy(c *client, args []string) error {
+	if len(args) < 1 || len(args) > 2 {
+		return fmt.Errorf("set-policy needs rate and optional burst")
+	}
+	count, window, err := limiter.ParseRate(args[0])
+	if err != nil {
+		return err
+	}
+	p := map[string]any{
+		"max_count": count,
+		"window":    window.String(),
+	}
+	if len(args) == 2 {
+		burst, err := strconv.Atoi(args[1])
+		if err != nil || burst < 0 {
+			return fmt.Errorf("bad burst %q", args[1])
+		}
+		p["burst"] = burst
+	}
+
+	b, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	return c.do(http.MethodPut, "/policy", nil, b)
+}
+
+type client struct {
+	addr  string
+	token string
+	http  *http.Client
+}
+
+// make request and print indented json response
+func (c *client) do(method, path string, q url.Values, body []byte) error {
+	u := c.addr + path
+	if len(q) > 0 {
+		u += "?" + q.Encode()
+	}
+	req, err := http.NewRequest(method, u, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(b)))
+	}
+
+	var out bytes.Buffer
+	if err := json.Indent(&out, b, "", "  "); err != nil {
+		os.Stdout.Write(b)
+		return nil
+	}
+	out.WriteByte('\n')
+	_, err = out.WriteTo(os.Stdout)
+	return err
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ssleert/limiter"
+	"github.com/ssleert/limiter/httplimit"
+)
+
+func TestSetPolicy(t *testing.T) {
+	start := limiter.Policy{MaxCount: 10, Window: time.Minute, Burst: 20}
+	tests := []struct {
+		name string
+		args []string
+		want limiter.Policy
+		err  bool
+	}{
+		{
+			name: "rate keeps burst",
+			args: []string{"5/h"},
+			want: limiter.Policy{MaxCount: 5, Window: time.Hour, Burst: 20},
+		},
+		{
+			name: "rate and burst",
+			args: []string{"5/h", "7"},
+			want: limiter.Policy{MaxCount: 5, Window: time.Hour, Burst: 7},
+		},
+		{
+			name: "bad rate",
+			args: []string{"5"},
+			want: start,
+			err:  true,
+		},
+		{
+			name: "bad burst",
+			args: []string{"5/h", "-1"},
+			want: start,
+			err:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := limiter.New[string](1, 1, 16, 1024, 16)
+			l.SetPolicy(start)
+			srv := httptest.NewServer(httplimit.NewAdmin[string](
+				l, httplimit.ParseString, httplimit.TokenAuth("t"),
+			))
+			defer srv.Close()
+
+			c := &client{addr: srv.URL, token: "t", http: srv.Client()}
+			err := run(c, append([]string{"set-policy"}, tt.args...))
+			if (err != nil) != tt.err {
+				t.Fatalf("set-policy err = %v, want error %v", err, tt.err)
+			}
+			if got := l.Policy(); got != tt.want {
+				t.Fatalf("Policy() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
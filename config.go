@@ -0,0 +1,191 @@
+package limiter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/ssleert/mu"
+)
+
+// set of policies applied at once
+type Config[T comparable] struct {
+	// policy for keys without own one
+	Default Policy `json:"default"`
+	// own policies of keys
+	// replace all previous ones
+	Keys map[T]Policy `json:"keys"`
+}
+
+var ErrInvalidPolicy = errors.New("limiter: invalid policy")
+
+// check that all policies of config are valid
+// zero values are valid and mean defaults
+func (c Config[T]) Validate() error {
+	if err := c.Default.Validate(); err != nil {
+		return fmt.Errorf("default: %w", err)
+	}
+	for id, p := range c.Keys {
+		if err := p.Validate(); err != nil {
+			return fmt.Errorf("key %v: %w", id, err)
+		}
+	}
+	return nil
+}
+
+// decode json config like this
+// {"default": {"max_count": 100, "window": "1m"}, "keys": {...}}
+func DecodeJSON[T comparable](data []byte) (Config[T], error) {
+	var cfg Config[T]
+	err := json.Unmarshal(data, &cfg)
+	return cfg, err
+}
+
+// source of new limiter configs
+type ConfigSource[T comparable] interface {
+	// block until config changes or ctx is done
+	Next(ctx context.Context) (Config[T], error)
+}
+
+// replace default and all key policies with cfg atomically
+// invalid cfg is not applied and its
+// Validate() error is returned
+func (l *Limiter[T]) Apply(cfg Config[T]) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	defer l.moveBack()
+	def := cfg.Default.internal()
+	keys := make(map[T]policy, len(cfg.Keys))
+	for id, p := range cfg.Keys {
+		keys[id] = p.internal()
+	}
+
+	mu.ExecMutex(&l.mu, func() {
+		l.maxCount = def.maxCount
+		l.maxTime = def.maxTime
+		l.burst = def.burst
+		l.schedule = def.schedule
+		l.keyPolicies = keys
+		l.ownPolicy = true
+		l.distrust()
+	})
+	l.denyCache.clear()
+	l.inherit()
+	return nil
+}
+
+// apply every config from src until ctx is done
+// it blocks so run it in your own goroutine
+//
+// src errors and invalid configs are passed to onErr
+// and current config stays as is, nil onErr ignores them
+//
+// returns ctx.Err()
+func (l *Limiter[T]) Watch(
+	ctx context.Context,
+	src ConfigSource[T],
+	onErr func(err error),
+) error {
+	ctx, unlabel := l.label(ctx, "watch")
+	defer unlabel()
+
+	for {
+		cfg, err := src.Next(ctx)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err == nil {
+			err = l.Apply(cfg)
+		}
+		if err != nil && onErr != nil {
+			onErr(err)
+		}
+	}
+}
+
+// ConfigSource that reads configs from channel
+type ChanSource[T comparable] <-chan Config[T]
+
+func (c ChanSource[T]) Next(ctx context.Context) (Config[T], error) {
+	select {
+	case <-ctx.Done():
+		return Config[T]{}, ctx.Err()
+	case cfg, ok := <-c:
+		if !ok {
+			<-ctx.Done()
+			return Config[T]{}, ctx.Err()
+		}
+		return cfg, nil
+	}
+}
+
+// interval of FuncSource if it has no positive one
+const defaultSourceInterval = time.Second
+
+// ConfigSource that calls f every interval
+// and returns config when f reports change
+type FuncSource[T comparable] struct {
+	// if <= 0 f is called every second
+	Interval time.Duration
+	F        func() (cfg Config[T], changed bool, err error)
+}
+
+func (s FuncSource[T]) Next(ctx context.Context) (Config[T], error) {
+	interval := s.Interval
+	if interval <= 0 {
+		interval = defaultSourceInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return Config[T]{}, ctx.Err()
+		case <-ticker.C:
+			cfg, changed, err := s.F()
+			if err != nil || changed {
+				return cfg, err
+			}
+		}
+	}
+}
+
+// make ConfigSource that checks file at path every interval
+// and decodes it when its content changes
+//
+// if decode is nil file is decoded as json
+func FileSource[T comparable](
+	path string,
+	interval time.Duration,
+	decode func(data []byte) (Config[T], error),
+) ConfigSource[T] {
+	if decode == nil {
+		decode = DecodeJSON[T]
+	}
+
+	var last []byte
+	return FuncSource[T]{
+		Interval: interval,
+		F: func() (Config[T], bool, error) {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return Config[T]{}, false, err
+			}
+			if last != nil && bytes.Equal(data, last) {
+				return Config[T]{}, false, nil
+			}
+			cfg, err := decode(data)
+			if err != nil {
+				return Config[T]{}, false, err
+			}
+			last = data
+			return cfg, true, nil
+		},
+	}
+}
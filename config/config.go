@@ -0,0 +1,291 @@
+/*
+yaml and json policy files for limiters
+
+	limiters:
+	  login:
+	    max_count: 5
+	    window: 1m
+	    burst: 10
+	    max_keys: 100000
+	    keys:
+	      admin:
+	        rate: 100/m
+	    patterns:
+	      - match: "partner-*"
+	        rate: 1000/m
+	  email:
+	    max_count: 1000
+	    schedule: daily Europe/Berlin
+*/
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ssleert/limiter"
+	"golang.org/x/exp/constraints"
+	"gopkg.in/yaml.v3"
+)
+
+// duration written as string like "1m"
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	return d.parse(s)
+}
+
+func (d Duration) MarshalYAML() (any, error) {
+	return time.Duration(d).String(), nil
+}
+
+func (d *Duration) UnmarshalYAML(n *yaml.Node) error {
+	var s string
+	if err := n.Decode(&s); err != nil {
+		return err
+	}
+	return d.parse(s)
+}
+
+func (d *Duration) parse(s string) error {
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// limit of actions
+// zero values mean limiter defaults
+type Policy struct {
+	MaxCount int      `yaml:"max_count" json:"max_count"`
+	Window   Duration `yaml:"window" json:"window"`
+	// see limiter.Policy.Burst
+	Burst int `yaml:"burst" json:"burst"`
+
+	// rate like "100/m", see limiter.ParseRate()
+	// if set it overrides MaxCount and Window
+	Rate string `yaml:"rate" json:"rate"`
+
+	// schedule like "daily UTC", see limiter.ParseSchedule()
+	Schedule string `yaml:"schedule" json:"schedule"`
+}
+
+func (p Policy) limiter() (limiter.Policy, error) {
+	lp := limiter.Policy{
+		MaxCount: p.MaxCount,
+		Window:   time.Duration(p.Window),
+		Burst:    p.Burst,
+	}
+	if p.Rate != "" {
+		var err error
+		lp.MaxCount, lp.Window, err = limiter.ParseRate(p.Rate)
+		if err != nil {
+			return limiter.Policy{}, err
+		}
+	}
+	if p.Schedule != "" {
+		var err error
+		lp.Schedule, err = limiter.ParseSchedule(p.Schedule)
+		if err != nil {
+			return limiter.Policy{}, err
+		}
+	}
+	return lp, nil
+}
+
+// policy of keys matching pattern
+type Pattern struct {
+	// pattern of key in path.Match() syntax
+	// like "partner-*", keys that are not
+	// strings are matched by fmt.Sprint() of them
+	Match  string `yaml:"match" json:"match"`
+	Policy `yaml:",inline"`
+}
+
+// config of one named limiter
+type Limiter[T comparable] struct {
+	Policy `yaml:",inline"`
+
+	// see limiter.New()
+	// nil pointers mean limiter.Default
+	MapLen      *int `yaml:"map_len" json:"map_len"`
+	MaxKeys     *int `yaml:"max_keys" json:"max_keys"`
+	CleanAtOnce *int `yaml:"clean_at_once" json:"clean_at_once"`
+
+	// own policies of keys
+	Keys map[T]Policy `yaml:"keys" json:"keys"`
+
+	// policies of keys without own one
+	// first matching pattern wins
+	// keys matching none get default policy
+	Patterns []Pattern `yaml:"patterns" json:"patterns"`
+}
+
+// policy file with named limiters
+type File[T comparable] struct {
+	Limiters map[string]Limiter[T] `yaml:"limiters" json:"limiters"`
+}
+
+// parse yaml policy file
+func ParseYAML[T comparable](data []byte) (File[T], error) {
+	var f File[T]
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return f, err
+	}
+	return f, f.Validate()
+}
+
+// parse json policy file
+func ParseJSON[T comparable](data []byte) (File[T], error) {
+	var f File[T]
+	if err := json.Unmarshal(data, &f); err != nil {
+		return f, err
+	}
+	return f, f.Validate()
+}
+
+// read and parse policy file at path
+// .json files are parsed as json, all other as yaml
+func Load[T comparable](path string) (File[T], error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return File[T]{}, err
+	}
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		return ParseJSON[T](data)
+	}
+	return ParseYAML[T](data)
+}
+
+// check all policies of file
+func (f File[T]) Validate() error {
+	for name, l := range f.Limiters {
+		cfg, err := l.Config()
+		if err == nil {
+			err = cfg.Validate()
+		}
+		if err == nil {
+			_, err = l.Resolver()
+		}
+		if err != nil {
+			return fmt.Errorf("limiter %q: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// limiter.Config with policies of l
+func (l Limiter[T]) Config() (limiter.Config[T], error) {
+	def, err := l.Policy.limiter()
+	if err != nil {
+		return limiter.Config[T]{}, fmt.Errorf("default: %w", err)
+	}
+
+	cfg := limiter.Config[T]{
+		Default: def,
+		Keys:    make(map[T]limiter.Policy, len(l.Keys)),
+	}
+	for id, p := range l.Keys {
+		cfg.Keys[id], err = p.limiter()
+		if err != nil {
+			return limiter.Config[T]{}, fmt.Errorf("key %v: %w", id, err)
+		}
+	}
+	return cfg, nil
+}
+
+// limiter.PolicyResolver of l patterns
+// nil if l has no patterns
+func (l Limiter[T]) Resolver() (limiter.PolicyResolver[T], error) {
+	if len(l.Patterns) == 0 {
+		return nil, nil
+	}
+
+	type rule struct {
+		match string
+		p     limiter.Policy
+	}
+	rules := make([]rule, len(l.Patterns))
+	for i, pt := range l.Patterns {
+		if _, err := path.Match(pt.Match, ""); err != nil {
+			return nil, fmt.Errorf("pattern %q: %w", pt.Match, err)
+		}
+		p, err := pt.Policy.limiter()
+		if err == nil {
+			err = p.Validate()
+		}
+		if err != nil {
+			return nil, fmt.Errorf("pattern %q: %w", pt.Match, err)
+		}
+		rules[i] = rule{pt.Match, p}
+	}
+
+	return func(id T) limiter.Policy {
+		s, isString := any(id).(string)
+		if !isString {
+			s = fmt.Sprint(id)
+		}
+		for _, r := range rules {
+			if ok, _ := path.Match(r.match, s); ok {
+				return r.p
+			}
+		}
+		return limiter.Policy{}
+	}, nil
+}
+
+// make limiters from file by their names
+func Build[T constraints.Ordered](
+	f File[T],
+) (map[string]*limiter.Limiter[T], error) {
+	res := make(map[string]*limiter.Limiter[T], len(f.Limiters))
+	for name, c := range f.Limiters {
+		cfg, err := c.Config()
+		if err != nil {
+			return nil, fmt.Errorf("limiter %q: %w", name, err)
+		}
+
+		l := limiter.New[T](
+			cfg.Default.MaxCount,
+			int64(cfg.Default.Window/time.Second),
+			orDefault(c.MapLen),
+			orDefault(c.MaxKeys),
+			orDefault(c.CleanAtOnce),
+		)
+		if err := l.Apply(cfg); err != nil {
+			return nil, fmt.Errorf("limiter %q: %w", name, err)
+		}
+
+		r, err := c.Resolver()
+		if err != nil {
+			return nil, fmt.Errorf("limiter %q: %w", name, err)
+		}
+		if r != nil {
+			l.SetPolicyResolver(r)
+		}
+		res[name] = l
+	}
+	return res, nil
+}
+
+func orDefault(v *int) int {
+	if v == nil {
+		return limiter.Default
+	}
+	return *v
+}
@@ -0,0 +1,109 @@
+package config_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ssleert/limiter"
+	"github.com/ssleert/limiter/config"
+	"github.com/ssleert/limiter/limitertest"
+)
+
+const file = `
+limiters:
+  api:
+    max_count: 5
+    window: 1m
+    keys:
+      partner-big:
+        rate: 2/m
+    patterns:
+      - match: "partner-*"
+        rate: 10/m
+      - match: "*"
+        max_count: 1
+        window: 1m
+  email:
+    max_count: 3
+    schedule: daily UTC
+`
+
+func TestBuild(t *testing.T) {
+	f, err := config.ParseYAML[string]([]byte(file))
+	if err != nil {
+		t.Fatalf("ParseYAML() err = %v", err)
+	}
+	ls, err := config.Build(f)
+	if err != nil {
+		t.Fatalf("Build() err = %v", err)
+	}
+	limitertest.Use(ls["api"], ls["email"])
+
+	tests := []struct {
+		limiter string
+		key     string
+		allowed int
+	}{
+		{"api", "partner-a", 10},
+		{"api", "partner-big", 2},
+		{"api", "user", 1},
+		{"email", "user", 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.limiter+"/"+tt.key, func(t *testing.T) {
+			l := ls[tt.limiter]
+			limitertest.AssertAllowed[string](t, l, tt.key, tt.allowed)
+			limitertest.AssertDenied[string](t, l, tt.key)
+		})
+	}
+
+	want := limiter.Policy{MaxCount: 3, Window: time.Minute, Schedule: limiter.Daily(time.UTC)}
+	if got := ls["email"].Policy(); got.MaxCount != want.MaxCount || got.Schedule != want.Schedule {
+		t.Fatalf("email Policy() = %+v, want %+v", got, want)
+	}
+}
+
+func TestDefaultPattern(t *testing.T) {
+	f, err := config.ParseJSON[string]([]byte(`{"limiters": {"api": {
+		"max_count": 5, "window": "1m",
+		"patterns": [{"match": "partner-*", "rate": "10/m"}]
+	}}}`))
+	if err != nil {
+		t.Fatalf("ParseJSON() err = %v", err)
+	}
+	ls, err := config.Build(f)
+	if err != nil {
+		t.Fatalf("Build() err = %v", err)
+	}
+	l := ls["api"]
+	limitertest.Use(l)
+
+	// keys matching no pattern follow default policy
+	limitertest.AssertAllowed[string](t, l, "user", 1)
+	l.SetPolicy(limiter.Policy{MaxCount: 2, Window: time.Minute})
+	limitertest.AssertAllowed[string](t, l, "user", 1)
+	limitertest.AssertDenied[string](t, l, "user")
+	if _, ok := l.KeyPolicy("user"); ok {
+		t.Fatal("KeyPolicy() of user is own, want default")
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"bad pattern", `{"limiters": {"a": {"patterns": [{"match": "[", "rate": "1/m"}]}}}`},
+		{"bad pattern rate", `{"limiters": {"a": {"patterns": [{"match": "*", "rate": "1"}]}}}`},
+		{"negative pattern burst", `{"limiters": {"a": {"patterns": [{"match": "*", "burst": -1}]}}}`},
+		{"bad schedule", `{"limiters": {"a": {"schedule": "weekly"}}}`},
+		{"bad key schedule", `{"limiters": {"a": {"keys": {"k": {"schedule": "cron * *"}}}}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := config.ParseJSON[string]([]byte(tt.data)); err == nil {
+				t.Fatal("ParseJSON() err = nil, want error")
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package limiter_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ssleert/limiter"
+)
+
+func TestApply(t *testing.T) {
+	start := limiter.Policy{MaxCount: 10, Window: time.Minute}
+	tests := []struct {
+		name string
+		cfg  limiter.Config[string]
+		want limiter.Policy
+		err  error
+	}{
+		{
+			name: "valid",
+			cfg:  limiter.Config[string]{Default: limiter.Policy{MaxCount: 5, Window: time.Hour}},
+			want: limiter.Policy{MaxCount: 5, Window: time.Hour},
+		},
+		{
+			name: "sub-second window",
+			cfg:  limiter.Config[string]{Default: limiter.Policy{MaxCount: 5, Window: time.Millisecond}},
+			want: start,
+			err:  limiter.ErrInvalidPolicy,
+		},
+		{
+			name: "negative key count",
+			cfg: limiter.Config[string]{
+				Default: limiter.Policy{MaxCount: 5, Window: time.Hour},
+				Keys:    map[string]limiter.Policy{"a": {MaxCount: -1}},
+			},
+			want: start,
+			err:  limiter.ErrInvalidPolicy,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := limiter.New[string](10, 60, 16, 1024, 16)
+			if err := l.Apply(tt.cfg); !errors.Is(err, tt.err) {
+				t.Fatalf("Apply() err = %v, want %v", err, tt.err)
+			}
+			if got := l.Policy(); got != tt.want {
+				t.Fatalf("Policy() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWatchInvalid(t *testing.T) {
+	l := limiter.New[string](10, 60, 16, 1024, 16)
+	ch := make(chan limiter.Config[string], 1)
+	ch <- limiter.Config[string]{Default: limiter.Policy{Window: -time.Minute}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var reported error
+	l.Watch(ctx, limiter.ChanSource[string](ch), func(err error) {
+		reported = err
+		cancel()
+	})
+	if !errors.Is(reported, limiter.ErrInvalidPolicy) {
+		t.Fatalf("Watch() reported %v, want %v", reported, limiter.ErrInvalidPolicy)
+	}
+	if w := l.Policy().Window; w != time.Minute {
+		t.Fatalf("Policy().Window = %v, want %v", w, time.Minute)
+	}
+}
+
+func TestFuncSource(t *testing.T) {
+	tests := []struct {
+		name     string
+		interval time.Duration
+	}{
+		{"interval", time.Millisecond},
+		{"zero interval", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := limiter.Config[string]{Default: limiter.Policy{MaxCount: 5}}
+			src := limiter.FuncSource[string]{
+				Interval: tt.interval,
+				F: func() (limiter.Config[string], bool, error) {
+					return want, true, nil
+				},
+			}
+			cfg, err := src.Next(context.Background())
+			if err != nil || cfg.Default != want.Default {
+				t.Fatalf("Next() = %+v, %v, want %+v", cfg, err, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,335 @@
+package limiter
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/ssleert/mu"
+	"golang.org/x/exp/constraints"
+)
+
+// counters of one key window in every region
+// region counts only grow so states
+// merged in any order give same result
+type CounterState[T any] struct {
+	Key T `json:"key"`
+	// window start in unix seconds
+	Window int64 `json:"window"`
+	// actions taken in every region
+	Inc map[string]uint64 `json:"inc"`
+	// actions given back in every region
+	Dec map[string]uint64 `json:"dec,omitempty"`
+}
+
+type pnCounter struct {
+	window int64
+	inc    map[string]uint64
+	dec    map[string]uint64
+	// local actions since last Merge()
+	unsynced int
+}
+
+// limiter for many regions where every region
+// counts locally with pn counters and states of
+// regions are merged periodically by State() and Merge()
+//
+// region admits action if sum of all counters it
+// knows of is under limit, so keys can go over limit
+// by actions of other regions since last merge
+//
+// windows are aligned to unix epoch
+// so they are same in every region
+type PNCounter[T constraints.Ordered] struct {
+	m  map[T]*pnCounter
+	mu sync.Mutex
+
+	region string
+	limit  int
+	window int64
+	// max local actions of key between merges
+	maxDivergence int
+}
+
+// make new counter for region with limit
+// of actions per window for every key
+// resolution is one second
+func NewPNCounter[T constraints.Ordered](
+	region string,
+	limit int,
+	window time.Duration,
+) *PNCounter[T] {
+	w := int64(window / time.Second)
+	if w < 1 {
+		w = 1
+	}
+	return &PNCounter[T]{
+		m:      make(map[T]*pnCounter, defaultMapLen),
+		region: region,
+		limit:  limit,
+		window: w,
+	}
+}
+
+// let key take at most n actions in this region
+// between merges, so it can't go over limit by
+// more than n actions of every other region
+// even if merges are late
+//
+// n <= 0 means no limit
+func (c *PNCounter[T]) SetMaxDivergence(n int) {
+	mu.ExecMutex(&c.mu, func() {
+		c.maxDivergence = n
+	})
+}
+
+// interval of Sync() if given one is not positive
+const defaultSyncInterval = time.Second
+
+// exchange states with other regions every interval
+// exchange sends local states and returns merged
+// states of other regions, like gossip or shared store
+// it blocks so run it in your own goroutine
+//
+// interval is how stale counters of other
+// regions can be, errors of exchange go to onErr
+// interval <= 0 exchanges them every second
+// returns ctx.Err()
+func (c *PNCounter[T]) Sync(
+	ctx context.Context,
+	interval time.Duration,
+	exchange func(ctx context.Context, local []CounterState[T]) ([]CounterState[T], error),
+	onErr func(err error),
+) error {
+	ctx, unlabel := setLabels(ctx, "region", c.region, "op", "sync")
+	defer unlabel()
+
+	if interval <= 0 {
+		interval = defaultSyncInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			remote, err := exchange(ctx, c.State())
+			if err != nil {
+				if onErr != nil {
+					onErr(err)
+				}
+				continue
+			}
+			c.Merge(remote)
+		}
+	}
+}
+
+// true if key has budget by all known counters
+func (c *PNCounter[T]) Try(id T) bool {
+	return c.TryN(id, 1)
+}
+
+// true if key has budget for n actions by all known counters
+// n < 1 is counted as 1
+func (c *PNCounter[T]) TryN(id T, n int) bool {
+	if n < 1 {
+		n = 1
+	}
+	start := c.start(time.Now().Unix())
+
+	var ok bool
+	mu.ExecMutex(&c.mu, func() {
+		pn := c.current(id, start)
+		if pn.total()+int64(n) > int64(c.limit) {
+			return
+		}
+		if c.maxDivergence > 0 && pn.unsynced+n > c.maxDivergence {
+			return
+		}
+		pn.inc[c.region] += uint64(n)
+		pn.unsynced += n
+		ok = true
+	})
+	return ok
+}
+
+// wait until key has budget or ctx is done
+// it tries again at start of every window
+// so budget freed by Refund() or Merge()
+// is not noticed until then
+//
+// returns ErrDenied if limit is 0
+func (c *PNCounter[T]) Wait(ctx context.Context, id T) error {
+	if c.limit <= 0 {
+		return ErrDenied
+	}
+	return waitWindows(ctx, c.window, time.Now, func() bool {
+		return c.Try(id)
+	})
+}
+
+// give back all actions of key taken in this region
+// actions of other regions stay until window ends
+// returns false if key has no current window
+func (c *PNCounter[T]) Reset(id T) bool {
+	start := c.start(time.Now().Unix())
+
+	var ok bool
+	mu.ExecMutex(&c.mu, func() {
+		pn, found := c.m[id]
+		if !found || pn.window < start {
+			return
+		}
+		if pn.dec[c.region] < pn.inc[c.region] {
+			pn.dec[c.region] = pn.inc[c.region]
+		}
+		pn.unsynced = 0
+		ok = true
+	})
+	return ok
+}
+
+// give one action of key back
+func (c *PNCounter[T]) Refund(id T) {
+	start := c.start(time.Now().Unix())
+	mu.ExecMutex(&c.mu, func() {
+		pn := c.current(id, start)
+		if pn.total() > 0 {
+			pn.dec[c.region]++
+		}
+	})
+}
+
+// actions of key in current window in all known regions
+func (c *PNCounter[T]) Count(id T) int {
+	start := c.start(time.Now().Unix())
+
+	var n int64
+	mu.ExecMutex(&c.mu, func() {
+		if pn, ok := c.m[id]; ok && pn.window >= start {
+			n = pn.total()
+		}
+	})
+	return int(n)
+}
+
+// copy counters of current windows
+// to send them to other regions
+func (c *PNCounter[T]) State() []CounterState[T] {
+	start := c.start(time.Now().Unix())
+
+	var res []CounterState[T]
+	mu.ExecMutex(&c.mu, func() {
+		res = make([]CounterState[T], 0, len(c.m))
+		for id, pn := range c.m {
+			if pn.window < start {
+				continue
+			}
+			res = append(res, CounterState[T]{
+				Key:    id,
+				Window: pn.window,
+				Inc:    copyCounts(pn.inc),
+				Dec:    copyCounts(pn.dec),
+			})
+		}
+	})
+	return res
+}
+
+// merge states of other regions
+// newer window replaces older one and in same
+// window every region counter takes max value
+//
+// it also resets local actions counted
+// by SetMaxDivergence()
+func (c *PNCounter[T]) Merge(states []CounterState[T]) {
+	mu.ExecMutex(&c.mu, func() {
+		for _, pn := range c.m {
+			pn.unsynced = 0
+		}
+		for _, s := range states {
+			pn, ok := c.m[s.Key]
+			if !ok || pn.window < s.Window {
+				pn = &pnCounter{
+					window: s.Window,
+					inc:    make(map[string]uint64, len(s.Inc)),
+					dec:    make(map[string]uint64, len(s.Dec)),
+				}
+				c.m[s.Key] = pn
+			}
+			if pn.window > s.Window {
+				continue
+			}
+			mergeCounts(pn.inc, s.Inc)
+			mergeCounts(pn.dec, s.Dec)
+		}
+	})
+}
+
+// remove keys with ended windows
+// returns count of removed keys
+func (c *PNCounter[T]) Clean() int {
+	start := c.start(time.Now().Unix())
+
+	var removed int
+	mu.ExecMutex(&c.mu, func() {
+		for id, pn := range c.m {
+			if pn.window < start {
+				delete(c.m, id)
+				removed++
+			}
+		}
+	})
+	return removed
+}
+
+func (c *PNCounter[T]) start(timeNow int64) int64 {
+	return timeNow - timeNow%c.window
+}
+
+// counter of key in window that starts at start
+//
+// c.mu must be held
+func (c *PNCounter[T]) current(id T, start int64) *pnCounter {
+	// window from region with clock ahead is kept
+	pn, ok := c.m[id]
+	if !ok || pn.window < start {
+		pn = &pnCounter{
+			window: start,
+			inc:    make(map[string]uint64, 1),
+			dec:    make(map[string]uint64),
+		}
+		c.m[id] = pn
+	}
+	return pn
+}
+
+func (pn *pnCounter) total() int64 {
+	var n int64
+	for _, v := range pn.inc {
+		n += int64(v)
+	}
+	for _, v := range pn.dec {
+		n -= int64(v)
+	}
+	return n
+}
+
+func copyCounts(m map[string]uint64) map[string]uint64 {
+	res := make(map[string]uint64, len(m))
+	for k, v := range m {
+		res[k] = v
+	}
+	return res
+}
+
+func mergeCounts(dst, src map[string]uint64) {
+	for k, v := range src {
+		if v > dst[k] {
+			dst[k] = v
+		}
+	}
+}
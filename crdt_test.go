@@ -0,0 +1,78 @@
+package limiter_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ssleert/limiter"
+)
+
+func TestPNCounterSync(t *testing.T) {
+	tests := []struct {
+		name     string
+		interval time.Duration
+	}{
+		{"interval", 5 * time.Millisecond},
+		{"zero interval", 0},
+		{"negative interval", -time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			eu := limiter.NewPNCounter[string]("eu", 2, time.Minute)
+			us := limiter.NewPNCounter[string]("us", 2, time.Minute)
+			if !us.TryN("a", 2) {
+				t.Fatal("us TryN() = false, want true")
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			err := eu.Sync(ctx, tt.interval, func(ctx context.Context, local []limiter.CounterState[string]) ([]limiter.CounterState[string], error) {
+				cancel()
+				return us.State(), nil
+			}, nil)
+			if !errors.Is(err, context.Canceled) {
+				t.Fatalf("Sync() err = %v, want %v", err, context.Canceled)
+			}
+			if eu.Try("a") {
+				t.Fatal("eu Try() after Sync() = true, want false")
+			}
+		})
+	}
+}
+
+func TestPNCounterReconcile(t *testing.T) {
+	tests := []struct {
+		name     string
+		interval time.Duration
+		// true if peer is asked before ctx is done
+		asked bool
+	}{
+		{"interval", 5 * time.Millisecond, true},
+		{"zero interval", 0, false},
+		{"negative interval", -time.Second, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			eu := limiter.NewPNCounter[string]("eu", 2, time.Minute)
+			us := limiter.NewPNCounter[string]("us", 2, time.Minute)
+			if !us.TryN("a", 2) {
+				t.Fatal("us TryN() = false, want true")
+			}
+
+			// default interval is a minute
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			defer cancel()
+			err := eu.Reconcile(ctx, tt.interval, func(ctx context.Context, d limiter.Digest[string]) ([]limiter.CounterState[string], error) {
+				cancel()
+				return us.Diff(d), nil
+			}, nil)
+			if !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded) {
+				t.Fatalf("Reconcile() err = %v, want ctx error", err)
+			}
+			if got := !eu.Try("a"); got != tt.asked {
+				t.Fatalf("merged = %v, want %v", got, tt.asked)
+			}
+		})
+	}
+}
@@ -0,0 +1,194 @@
+package limiter
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var ErrInvalidCron = errors.New("limiter: invalid cron spec")
+
+// windows between fire times of cron spec
+// with 5 fields: minute hour day month weekday
+// fields support *, lists, ranges and steps
+//
+//	"0 0 * * 1"    every monday at 00:00
+//	"0 9,18 * * *" at 09:00 and 18:00 every day
+//	"*/15 * * * *" every 15 minutes
+//
+// if loc is nil time.Local is used
+func Cron(spec string, loc *time.Location) (Schedule, error) {
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("%w: %q needs 5 fields", ErrInvalidCron, spec)
+	}
+	if loc == nil {
+		loc = time.Local
+	}
+
+	c := &cron{spec: strings.Join(fields, " "), loc: loc}
+	ranges := [5][2]int{{0, 59}, {0, 23}, {1, 31}, {1, 12}, {0, 7}}
+	sets := [5]*uint64{&c.minute, &c.hour, &c.dom, &c.month, &c.dow}
+	for i, f := range fields {
+		set, err := parseCronField(f, ranges[i][0], ranges[i][1])
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q: %v", ErrInvalidCron, spec, err)
+		}
+		*sets[i] = set
+	}
+	// 7 is sunday too
+	if c.dow&(1<<7) != 0 {
+		c.dow |= 1
+	}
+	c.anyDom = fields[2] == "*"
+	c.anyDow = fields[4] == "*"
+
+	// spec that never fires, like 31 of february
+	if _, ok := c.next(time.Now()); !ok {
+		return nil, fmt.Errorf("%w: %q never fires", ErrInvalidCron, spec)
+	}
+	return c, nil
+}
+
+type cron struct {
+	minute, hour, dom, month, dow uint64
+	anyDom, anyDow                bool
+	loc                           *time.Location
+	spec                          string
+
+	// last computed window
+	mu         sync.Mutex
+	start, end time.Time
+}
+
+func (c *cron) String() string {
+	return "cron " + c.spec + " " + c.loc.String()
+}
+
+func (c *cron) Window(t time.Time) (time.Time, time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !t.Before(c.start) && t.Before(c.end) {
+		return c.start, c.end
+	}
+	start, _ := c.prev(t)
+	end, _ := c.next(t)
+	c.start, c.end = start, end
+	return start, end
+}
+
+// at most this many years are searched for fire time
+const cronSearchYears = 5
+
+// first fire time after t
+func (c *cron) next(t time.Time) (time.Time, bool) {
+	t = t.In(c.loc).Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(cronSearchYears, 0, 0)
+
+	for t.Before(limit) {
+		y, m, d := t.Date()
+		switch {
+		case !c.has(c.month, int(m)):
+			t = time.Date(y, m+1, 1, 0, 0, 0, 0, c.loc)
+		case !c.day(t):
+			t = time.Date(y, m, d+1, 0, 0, 0, 0, c.loc)
+		case !c.has(c.hour, t.Hour()):
+			t = time.Date(y, m, d, t.Hour()+1, 0, 0, 0, c.loc)
+		case !c.has(c.minute, t.Minute()):
+			t = t.Add(time.Minute)
+		default:
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// last fire time before or at t
+func (c *cron) prev(t time.Time) (time.Time, bool) {
+	t = t.In(c.loc).Truncate(time.Minute)
+	limit := t.AddDate(-cronSearchYears, 0, 0)
+
+	for t.After(limit) {
+		y, m, d := t.Date()
+		switch {
+		case !c.has(c.month, int(m)):
+			t = time.Date(y, m, 1, 0, 0, 0, 0, c.loc).Add(-time.Minute)
+		case !c.day(t):
+			t = time.Date(y, m, d, 0, 0, 0, 0, c.loc).Add(-time.Minute)
+		case !c.has(c.hour, t.Hour()):
+			t = time.Date(y, m, d, t.Hour(), 0, 0, 0, c.loc).Add(-time.Minute)
+		case !c.has(c.minute, t.Minute()):
+			t = t.Add(-time.Minute)
+		default:
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+func (c *cron) has(set uint64, v int) bool {
+	return set&(1<<uint(v)) != 0
+}
+
+// day matching like in classic cron
+// if both day and weekday are set any of them matches
+func (c *cron) day(t time.Time) bool {
+	dom := c.has(c.dom, t.Day())
+	dow := c.has(c.dow, int(t.Weekday()))
+	switch {
+	case c.anyDom && c.anyDow:
+		return true
+	case c.anyDom:
+		return dow
+	case c.anyDow:
+		return dom
+	}
+	return dom || dow
+}
+
+// parse cron field into bit set of values
+func parseCronField(f string, min, max int) (uint64, error) {
+	var set uint64
+	for _, part := range strings.Split(f, ",") {
+		step := 1
+		if r, s, ok := strings.Cut(part, "/"); ok {
+			var err error
+			step, err = strconv.Atoi(s)
+			if err != nil || step <= 0 {
+				return 0, fmt.Errorf("bad step in %q", part)
+			}
+			part = r
+		}
+
+		lo, hi := min, max
+		if part != "*" {
+			l, h, isRange := strings.Cut(part, "-")
+			var err error
+			lo, err = strconv.Atoi(l)
+			if err != nil {
+				return 0, fmt.Errorf("bad value in %q", part)
+			}
+			hi = lo
+			if isRange {
+				hi, err = strconv.Atoi(h)
+				if err != nil {
+					return 0, fmt.Errorf("bad value in %q", part)
+				}
+			} else if step > 1 {
+				hi = max
+			}
+		}
+		if lo < min || hi > max || lo > hi {
+			return 0, fmt.Errorf("%q out of range %d-%d", part, min, max)
+		}
+
+		for v := lo; v <= hi; v += step {
+			set |= 1 << uint(v)
+		}
+	}
+	return set, nil
+}
@@ -0,0 +1,155 @@
+package limiter
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ssleert/mu"
+	"golang.org/x/exp/constraints"
+)
+
+type pendingCall struct {
+	timer *time.Timer
+	// time of first trigger since last call
+	first time.Time
+}
+
+// collapses rapid triggers of key into one call
+// made after key was quiet for some time
+// e.g. for notifications and webhook fan out
+type Debouncer[T constraints.Ordered] struct {
+	m  map[T]*pendingCall
+	mu sync.Mutex
+
+	quiet time.Duration
+	// 0 means key can be postponed forever
+	maxWait time.Duration
+	f       func(id T)
+
+	// see SetClock()
+	clock atomic.Pointer[func() time.Time]
+}
+
+// make new debouncer that calls f for key
+// when it was not triggered for quiet
+// f is called in its own goroutine
+// or in goroutine of Flush()
+func NewDebouncer[T constraints.Ordered](
+	quiet time.Duration,
+	f func(id T),
+) *Debouncer[T] {
+	return &Debouncer[T]{
+		m:     make(map[T]*pendingCall, defaultMapLen),
+		quiet: quiet,
+		f:     f,
+	}
+}
+
+// call f for key no later than max after its first
+// trigger even if key is triggered all the time
+//
+// 0 disables it
+func (d *Debouncer[T]) SetMaxWait(max time.Duration) {
+	mu.ExecMutex(&d.mu, func() {
+		d.maxWait = max
+	})
+}
+
+// use now instead of time.Now() to count max wait
+// of keys, e.g. for tests, calls are still made
+// by real timers
+//
+// nil now resets clock to time.Now()
+func (d *Debouncer[T]) SetClock(now func() time.Time) {
+	if now == nil {
+		d.clock.Store(nil)
+		return
+	}
+	d.clock.Store(&now)
+}
+
+// current time of debouncer clock
+func (d *Debouncer[T]) now() time.Time {
+	if f := d.clock.Load(); f != nil {
+		return (*f)()
+	}
+	return time.Now()
+}
+
+// trigger key and postpone its call
+// to quiet after now
+func (d *Debouncer[T]) Trigger(id T) {
+	now := d.now()
+	mu.ExecMutex(&d.mu, func() {
+		wait := d.quiet
+		c, ok := d.m[id]
+		if !ok {
+			c = &pendingCall{first: now}
+			c.timer = time.AfterFunc(wait, func() {
+				d.fire(id, c)
+			})
+			d.m[id] = c
+			return
+		}
+
+		if d.maxWait > 0 {
+			if left := d.maxWait - now.Sub(c.first); left < wait {
+				wait = left
+			}
+		}
+		c.timer.Reset(wait)
+	})
+}
+
+// call f for key now if it has pending call
+// returns false if it has none
+func (d *Debouncer[T]) Flush(id T) bool {
+	var c *pendingCall
+	mu.ExecMutex(&d.mu, func() {
+		c = d.m[id]
+	})
+	if c == nil || !c.timer.Stop() {
+		return false
+	}
+	d.fire(id, c)
+	return true
+}
+
+// drop pending call of key
+// returns false if it has none
+func (d *Debouncer[T]) Cancel(id T) bool {
+	var ok bool
+	mu.ExecMutex(&d.mu, func() {
+		var c *pendingCall
+		c, ok = d.m[id]
+		if ok {
+			c.timer.Stop()
+			delete(d.m, id)
+		}
+	})
+	return ok
+}
+
+// count of keys with pending calls
+func (d *Debouncer[T]) Len() int {
+	var n int
+	mu.ExecMutex(&d.mu, func() {
+		n = len(d.m)
+	})
+	return n
+}
+
+// remove call c of key and make it
+func (d *Debouncer[T]) fire(id T, c *pendingCall) {
+	var ok bool
+	mu.ExecMutex(&d.mu, func() {
+		if d.m[id] == c {
+			delete(d.m, id)
+			ok = true
+		}
+	})
+	if ok {
+		d.f(id)
+	}
+}
@@ -0,0 +1,69 @@
+package limiter_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ssleert/limiter"
+	"github.com/ssleert/limiter/limitertest"
+)
+
+func TestDebouncerMaxWait(t *testing.T) {
+	tests := []struct {
+		name string
+		// clock moved between triggers
+		advance time.Duration
+		fired   bool
+	}{
+		{"postponed", time.Second, false},
+		{"max wait passed", time.Hour, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fired := make(chan string, 1)
+			d := limiter.NewDebouncer[string](time.Hour, func(id string) {
+				fired <- id
+			})
+			d.SetMaxWait(time.Minute)
+			c := limitertest.NewClock(time.Unix(1000, 0))
+			c.Attach(d)
+
+			d.Trigger("a")
+			c.Advance(tt.advance)
+			d.Trigger("a")
+
+			select {
+			case id := <-fired:
+				if !tt.fired || id != "a" {
+					t.Fatalf("fired %q, want fired = %v", id, tt.fired)
+				}
+			case <-time.After(100 * time.Millisecond):
+				if tt.fired {
+					t.Fatal("call of a was not made after max wait")
+				}
+				if n := d.Len(); n != 1 {
+					t.Fatalf("Len() = %d, want 1", n)
+				}
+			}
+		})
+	}
+}
+
+func TestDebouncerFlush(t *testing.T) {
+	var calls []string
+	d := limiter.NewDebouncer[string](time.Hour, func(id string) {
+		calls = append(calls, id)
+	})
+	d.Trigger("a")
+	d.Trigger("a")
+	d.Trigger("b")
+	if !d.Flush("a") || d.Flush("a") {
+		t.Fatal("Flush(a) must be true only once")
+	}
+	if !d.Cancel("b") || d.Len() != 0 {
+		t.Fatalf("Cancel(b) left %d pending", d.Len())
+	}
+	if len(calls) != 1 || calls[0] != "a" {
+		t.Fatalf("calls = %v, want [a]", calls)
+	}
+}
@@ -0,0 +1,92 @@
+package limiter
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ssleert/mu"
+)
+
+type dedupKey[T comparable] struct {
+	id  T
+	key string
+}
+
+type dedup[T comparable] struct {
+	mu sync.Mutex
+	// seconds allowed action is remembered
+	// 0 means limiter window
+	window int64
+	// expire time of allowed actions
+	m map[dedupKey[T]]int64
+	// map len that starts next sweep
+	sweepAt int
+}
+
+// remember allowed TryIdempotent() actions for d
+// if d <= 0 limiter window is used
+// resolution is one second
+func (l *Limiter[T]) SetDedupWindow(d time.Duration) {
+	l.dedup.mu.Lock()
+	defer l.dedup.mu.Unlock()
+
+	l.dedup.window = int64(d / time.Second)
+}
+
+// like Try() but actions of key with same
+// idempotency key are one action, e.g. retries
+// of same request, so they are allowed again
+// without spending units
+//
+// denied action is tried again as usual
+// empty idempotency key works like Try()
+func (l *Limiter[T]) TryIdempotent(id T, key string) bool {
+	if key == "" {
+		return l.Try(id)
+	}
+	timeNow := l.now().Unix()
+	k := dedupKey[T]{id: id, key: key}
+
+	d := &l.dedup
+	d.mu.Lock()
+	exp, seen := d.m[k]
+	window := d.window
+	d.mu.Unlock()
+	if seen && timeNow < exp {
+		return true
+	}
+
+	if !l.Try(id) {
+		return false
+	}
+	if window <= 0 {
+		mu.ExecRWMutex(&l.mu, func() {
+			window = l.policyOf(id).maxTime
+		})
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.m == nil {
+		d.m = make(map[dedupKey[T]]int64)
+	}
+	d.m[k] = timeNow + window
+	if len(d.m) >= d.sweepAt {
+		d.sweep(timeNow)
+	}
+	return true
+}
+
+// remove expired actions
+// d.mu must be held
+func (d *dedup[T]) sweep(timeNow int64) {
+	for k, exp := range d.m {
+		if timeNow >= exp {
+			delete(d.m, k)
+		}
+	}
+	d.sweepAt = 2 * len(d.m)
+	if d.sweepAt < defaultMapLen {
+		d.sweepAt = defaultMapLen
+	}
+}
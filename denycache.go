@@ -0,0 +1,80 @@
+package limiter
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/ssleert/mu"
+)
+
+// denied keys and unix time until they are denied
+// it has own lock so cached denials don't wait for l.mu
+type denials[T comparable] struct {
+	on atomic.Bool
+	mu sync.RWMutex
+	m  map[T]int64
+}
+
+// cache denials of keys until they can act again
+// so next Try() calls of denied key return
+// false without locking limiter map
+//
+// cached denials are counted in Stats() but don't
+// emit events, audit records or call OnDeny()
+// cache of key is dropped on Reset(), Refund()
+// and Unban() and whole cache on policy change
+// other settings apply to denied keys after cache ends
+func (l *Limiter[T]) SetDenyCache(on bool) {
+	defer l.moveBack()
+	l.denyCache.on.Store(on)
+	if !on {
+		l.denyCache.clear()
+	}
+}
+
+// true if id is denied at timeNow by cache
+func (c *denials[T]) get(id T, timeNow int64) bool {
+	if !c.on.Load() {
+		return false
+	}
+	var until int64
+	mu.ExecRWMutex(&c.mu, func() {
+		until = c.m[id]
+	})
+	return until > timeNow
+}
+
+func (c *denials[T]) put(id T, until int64) {
+	mu.ExecMutex(&c.mu, func() {
+		if c.m == nil {
+			c.m = make(map[T]int64)
+		}
+		c.m[id] = until
+	})
+}
+
+func (c *denials[T]) forget(id T) {
+	if !c.on.Load() {
+		return
+	}
+	mu.ExecMutex(&c.mu, func() {
+		delete(c.m, id)
+	})
+}
+
+func (c *denials[T]) clear() {
+	mu.ExecMutex(&c.mu, func() {
+		c.m = nil
+	})
+}
+
+// remove ended denials
+func (c *denials[T]) clean(timeNow int64) {
+	mu.ExecMutex(&c.mu, func() {
+		for id, until := range c.m {
+			if until <= timeNow {
+				delete(c.m, id)
+			}
+		}
+	})
+}
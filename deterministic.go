@@ -0,0 +1,82 @@
+package limiter
+
+import (
+	"math/rand"
+	"time"
+
+	"github.com/ssleert/mu"
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
+)
+
+// make limiter fully deterministic for tests and simulations
+// all time comes from now, random jitter and denial
+// sampling use rand seeded with seed and Try() never
+// spawns clean up goroutines, so clean up happens only
+// on Clean() or CleanContext() calls from your code
+//
+// same calls with same clock give same decisions
+// also clean up scans keys in order and evicts least
+// recently used of all keys, which is slower on big maps
+//
+// Wait(), Janitor() and other loops still use real
+// timers, so don't run them in this mode
+// nil now turns mode off and resets clock to time.Now()
+func (l *Limiter[T]) SetDeterministic(now func() time.Time, seed int64) {
+	l.SetClock(now)
+	on := now != nil
+
+	mu.ExecMutex(&l.mu, func() {
+		l.rng = nil
+		l.seed = seed
+		if on {
+			l.rng = rand.New(rand.NewSource(seed))
+		}
+		l.resetScan()
+	})
+	r := &l.samples
+	r.mu.Lock()
+	r.rng = nil
+	if on {
+		r.rng = rand.New(rand.NewSource(seed))
+	}
+	r.mu.Unlock()
+
+	l.deterministic.Store(on)
+}
+
+// true if SetDeterministic() mode is on
+func (l *Limiter[T]) Deterministic() bool {
+	return l.deterministic.Load()
+}
+
+// time for measuring durations
+// limiter clock in deterministic mode
+func (l *Limiter[T]) wallNow() time.Time {
+	if l.deterministic.Load() {
+		return l.now()
+	}
+	return time.Now()
+}
+
+// random int in [0, n)
+//
+// l.mu must be held
+func (l *Limiter[T]) int63n(n int64) int64 {
+	if l.rng != nil {
+		return l.rng.Int63n(n)
+	}
+	return rand.Int63n(n)
+}
+
+// keys of map to clean up
+// sorted in deterministic mode
+//
+// l.mu must be held
+func (l *Limiter[T]) cleanOrder() []T {
+	keys := maps.Keys(l.m)
+	if l.deterministic.Load() {
+		slices.Sort(keys)
+	}
+	return keys
+}
@@ -0,0 +1,205 @@
+package limiter
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/ssleert/mu"
+	"golang.org/x/exp/constraints"
+)
+
+// how distributed limiter makes decisions
+type Consistency int
+
+const (
+	// every decision goes to shared store
+	// so limit is exact but each Try() is
+	// a round trip, for expensive quotas
+	Strict Consistency = iota
+
+	// decisions are local and counters of instances
+	// are reconciled in background by Sync(), so
+	// limit can be passed by actions of other instances
+	// since last sync, for cheap limits
+	Eventual
+)
+
+func (c Consistency) String() string {
+	switch c {
+	case Strict:
+		return "strict"
+	case Eventual:
+		return "eventual"
+	}
+	return "unknown"
+}
+
+// shared counters for Strict limiters, like redis INCRBY
+// with expire or sql upsert
+type StrictStore[T any] interface {
+	// add n to count of key in window starting at
+	// window unix seconds and return new count of
+	// all instances, count can expire after window ends
+	Add(ctx context.Context, id T, window int64, n int) (int64, error)
+}
+
+// limiter of many instances with consistency
+// chosen per limiter, windows are aligned to
+// unix epoch so they are same in every instance
+type Distributed[T constraints.Ordered] struct {
+	mode   Consistency
+	limit  int
+	window int64
+
+	store StrictStore[T]
+	// guards failOpen and onErr
+	mu       sync.RWMutex
+	failOpen bool
+	onErr    func(err error)
+
+	local *PNCounter[T]
+}
+
+// make Strict limiter with limit of actions
+// per window for every key counted in store
+// resolution is one second
+func NewStrict[T constraints.Ordered](
+	store StrictStore[T],
+	limit int,
+	window time.Duration,
+) *Distributed[T] {
+	w := int64(window / time.Second)
+	if w < 1 {
+		w = 1
+	}
+	return &Distributed[T]{
+		mode:   Strict,
+		limit:  limit,
+		window: w,
+		store:  store,
+	}
+}
+
+// make Eventual limiter for region with limit
+// of actions per window for every key
+// run Sync() to reconcile it with other regions
+func NewEventual[T constraints.Ordered](
+	region string,
+	limit int,
+	window time.Duration,
+) *Distributed[T] {
+	local := NewPNCounter[T](region, limit, window)
+	return &Distributed[T]{
+		mode:   Eventual,
+		limit:  limit,
+		window: local.window,
+		local:  local,
+	}
+}
+
+// consistency of limiter
+func (d *Distributed[T]) Mode() Consistency {
+	return d.mode
+}
+
+// decide on store errors of Strict limiter
+// allow actions if open is true, deny otherwise
+// errors are passed to onErr, default is
+// to deny and ignore them
+func (d *Distributed[T]) SetFailOpen(open bool, onErr func(err error)) {
+	mu.ExecMutex(&d.mu, func() {
+		d.failOpen = open
+		d.onErr = onErr
+	})
+}
+
+// local counters of Eventual limiter
+// nil for Strict one
+func (d *Distributed[T]) Local() *PNCounter[T] {
+	return d.local
+}
+
+func (d *Distributed[T]) Try(id T) bool {
+	return d.TryN(id, 1)
+}
+
+// like TryContext() without ctx
+// store errors are decided by SetFailOpen()
+func (d *Distributed[T]) TryN(id T, n int) bool {
+	ok, err := d.TryContext(context.Background(), id, n)
+	if err != nil {
+		var (
+			open  bool
+			onErr func(err error)
+		)
+		mu.ExecRWMutex(&d.mu, func() {
+			open, onErr = d.failOpen, d.onErr
+		})
+		if onErr != nil {
+			onErr(err)
+		}
+		return open
+	}
+	return ok
+}
+
+// true if key can take n actions
+// returns store error of Strict limiter
+// n < 1 is counted as 1
+func (d *Distributed[T]) TryContext(ctx context.Context, id T, n int) (bool, error) {
+	if n < 1 {
+		n = 1
+	}
+	if d.mode == Eventual {
+		return d.local.TryN(id, n), nil
+	}
+
+	timeNow := time.Now().Unix()
+	count, err := d.store.Add(ctx, id, timeNow-timeNow%d.window, n)
+	if err != nil {
+		return false, err
+	}
+	return count <= int64(d.limit), nil
+}
+
+// wait until key has budget or ctx is done
+// it tries again at start of every window
+//
+// returns ErrDenied if limit is 0
+func (d *Distributed[T]) Wait(ctx context.Context, id T) error {
+	if d.limit <= 0 {
+		return ErrDenied
+	}
+	return waitWindows(ctx, d.window, time.Now, func() bool {
+		return d.Try(id)
+	})
+}
+
+// give back actions of key taken in this instance
+// for Eventual limiter, see PNCounter.Reset()
+// Strict counters can't be reset so it returns false
+func (d *Distributed[T]) Reset(id T) bool {
+	if d.mode == Eventual {
+		return d.local.Reset(id)
+	}
+	return false
+}
+
+// reconcile Eventual limiter with other instances
+// every interval, see PNCounter.Sync()
+// for Strict limiter it just waits for ctx
+//
+// returns ctx.Err()
+func (d *Distributed[T]) Sync(
+	ctx context.Context,
+	interval time.Duration,
+	exchange func(ctx context.Context, local []CounterState[T]) ([]CounterState[T], error),
+	onErr func(err error),
+) error {
+	if d.mode == Strict {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	return d.local.Sync(ctx, interval, exchange, onErr)
+}
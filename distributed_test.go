@@ -0,0 +1,67 @@
+package limiter_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ssleert/limiter"
+)
+
+// store that fails every call
+type downStore struct{}
+
+var errDown = errors.New("store is down")
+
+func (downStore) Add(context.Context, string, int64, int) (int64, error) {
+	return 0, errDown
+}
+
+func TestFailOpen(t *testing.T) {
+	tests := []struct {
+		name string
+		open bool
+	}{
+		{"open", true},
+		{"closed", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := limiter.NewStrict[string](downStore{}, 1, time.Minute)
+			var errs int
+			d.SetFailOpen(tt.open, func(err error) {
+				if errors.Is(err, errDown) {
+					errs++
+				}
+			})
+			if got := d.Try("a"); got != tt.open {
+				t.Fatalf("Try(a) = %v, want %v", got, tt.open)
+			}
+			if errs != 1 {
+				t.Fatalf("onErr calls = %d, want 1", errs)
+			}
+		})
+	}
+}
+
+// run with -race
+func TestFailOpenConcurrent(t *testing.T) {
+	d := limiter.NewStrict[string](downStore{}, 1, time.Minute)
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			d.SetFailOpen(i%2 == 0, func(error) {})
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			d.Try("a")
+		}
+	}()
+	wg.Wait()
+}
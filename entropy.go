@@ -0,0 +1,134 @@
+package limiter
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"time"
+
+	"github.com/ssleert/mu"
+)
+
+// hashes of counter states to compare
+// regions without sending whole state
+type Digest[T comparable] struct {
+	// xor of all key hashes
+	// equal sums mean equal states
+	Sum  uint64       `json:"sum"`
+	Keys map[T]uint64 `json:"keys"`
+}
+
+// hashes of current windows
+func (c *PNCounter[T]) Digest() Digest[T] {
+	start := c.start(time.Now().Unix())
+
+	d := Digest[T]{Keys: make(map[T]uint64)}
+	mu.ExecMutex(&c.mu, func() {
+		for id, pn := range c.m {
+			if pn.window < start {
+				continue
+			}
+			h := pn.hash(id)
+			d.Keys[id] = h
+			d.Sum ^= h
+		}
+	})
+	return d
+}
+
+// states of keys that differ from digest d
+// of other region or that it doesn't have
+func (c *PNCounter[T]) Diff(d Digest[T]) []CounterState[T] {
+	start := c.start(time.Now().Unix())
+
+	var res []CounterState[T]
+	mu.ExecMutex(&c.mu, func() {
+		for id, pn := range c.m {
+			if pn.window < start {
+				continue
+			}
+			if h, ok := d.Keys[id]; ok && h == pn.hash(id) {
+				continue
+			}
+			res = append(res, CounterState[T]{
+				Key:    id,
+				Window: pn.window,
+				Inc:    copyCounts(pn.inc),
+				Dec:    copyCounts(pn.dec),
+			})
+		}
+	})
+	return res
+}
+
+// interval of Reconcile() if given one is not positive
+const defaultReconcileInterval = time.Minute
+
+// send digest to peer every interval and merge
+// states it returns, peer must answer with its Diff()
+// so regions that missed Sync() converge again
+// it blocks so run it in your own goroutine
+//
+// errors of peer go to onErr
+// interval <= 0 sends it every minute
+// returns ctx.Err()
+func (c *PNCounter[T]) Reconcile(
+	ctx context.Context,
+	interval time.Duration,
+	peer func(ctx context.Context, d Digest[T]) ([]CounterState[T], error),
+	onErr func(err error),
+) error {
+	ctx, unlabel := setLabels(ctx, "region", c.region, "op", "reconcile")
+	defer unlabel()
+
+	if interval <= 0 {
+		interval = defaultReconcileInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			diff, err := peer(ctx, c.Digest())
+			if err != nil {
+				if onErr != nil {
+					onErr(err)
+				}
+				continue
+			}
+			c.Merge(diff)
+		}
+	}
+}
+
+// hash of key with its counters
+func (pn *pnCounter) hash(id any) uint64 {
+	h := fnv.New64a()
+	fmt.Fprint(h, id)
+
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], uint64(pn.window))
+	h.Write(b[:])
+
+	for _, m := range []map[string]uint64{pn.inc, pn.dec} {
+		regions := make([]string, 0, len(m))
+		for r, v := range m {
+			if v > 0 {
+				regions = append(regions, r)
+			}
+		}
+		sort.Strings(regions)
+		for _, r := range regions {
+			h.Write([]byte(r))
+			binary.LittleEndian.PutUint64(b[:], m[r])
+			h.Write(b[:])
+		}
+		h.Write([]byte{0})
+	}
+	return h.Sum64()
+}
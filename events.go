@@ -0,0 +1,118 @@
+package limiter
+
+import (
+	"time"
+
+	"github.com/ssleert/mu"
+)
+
+// kind of limiter event
+type EventKind int
+
+const (
+	// action allowed by Try()
+	EventAllowed EventKind = iota
+
+	// action denied by Try()
+	EventDenied
+
+	// key removed to make space for new key
+	EventEvicted
+
+	// expired key removed by clean up
+	EventCleaned
+
+	// quota moved from key by MoveQuota()
+	EventMovedOut
+
+	// quota moved to key by MoveQuota()
+	EventMovedIn
+)
+
+func (k EventKind) String() string {
+	switch k {
+	case EventAllowed:
+		return "allowed"
+	case EventDenied:
+		return "denied"
+	case EventEvicted:
+		return "evicted"
+	case EventCleaned:
+		return "cleaned"
+	case EventMovedOut:
+		return "moved_out"
+	case EventMovedIn:
+		return "moved_in"
+	}
+	return "unknown"
+}
+
+type Event[T any] struct {
+	Kind EventKind
+	Key  T
+	Time time.Time
+	// see Namespace()
+	Namespace string
+
+	// units moved by MoveQuota()
+	// 0 for other events
+	N int
+	// other key of MoveQuota()
+	Peer T
+	// value of key, see SetMeta()
+	Meta any
+}
+
+// enable event stream with buffer for n events and return it
+// if stream is already enabled returns it as is
+//
+// limiter never blocks on stream, when buffer is full
+// new events are dropped and counted in Stats().DroppedEvents
+func (l *Limiter[T]) Events(n int) <-chan Event[T] {
+	var ch chan Event[T]
+	mu.ExecMutex(&l.mu, func() {
+		if l.events == nil {
+			l.events = make(chan Event[T], n)
+			l.shareStreams()
+		}
+		ch = l.events
+	})
+	return ch
+}
+
+// disable event stream and close its channel
+func (l *Limiter[T]) StopEvents() {
+	mu.ExecMutex(&l.mu, func() {
+		if ch := l.events; ch != nil {
+			// isolated keys stop sending first
+			l.events = nil
+			l.shareStreams()
+			close(ch)
+		}
+	})
+}
+
+// send event to stream without blocking
+//
+// l.mu must be held
+func (l *Limiter[T]) emit(kind EventKind, id T, timeNow int64) {
+	if l.events == nil {
+		return
+	}
+	l.send(Event[T]{
+		Kind: kind,
+		Key:  id,
+		Time: time.Unix(timeNow, 0),
+	})
+}
+
+// l.mu must be held
+func (l *Limiter[T]) send(e Event[T]) {
+	e.Namespace = l.nsName
+	e.Meta = l.meta[e.Key]
+	select {
+	case l.events <- e:
+	default:
+		l.counters().stats.dropped.Add(1)
+	}
+}
@@ -0,0 +1,68 @@
+package limiter
+
+import (
+	"github.com/ssleert/mu"
+)
+
+// key removed by clean up and its last state
+type expiredKey[T any] struct {
+	id T
+	st KeyState
+}
+
+// call f for every key removed by clean up
+// when its window ended and it was idle, e.g. to close
+// sessions or drop caches of key, evicted and reset
+// keys are not expired and are not passed to f
+//
+// f is called outside of limiter lock by goroutine
+// that removed key, in Clean(), CleanContext(), Janitor()
+// or Try() with SetTryClean(), so it can use limiter methods
+// but slow f slows them down, for channel of
+// expired keys see EventCleaned of Events()
+//
+// nil f removes callback
+func (l *Limiter[T]) OnExpire(f func(id T, st KeyState)) {
+	defer l.moveBack()
+	mu.ExecMutex(&l.mu, func() {
+		l.onExpire = f
+		l.expiredKeys = nil
+	})
+}
+
+// remove expired entry of key and queue it for OnExpire()
+//
+// l.mu must be held
+func (l *Limiter[T]) expire(id T, a action, p policy, timeNow int64) {
+	l.endWindow(a, p)
+	l.emit(EventCleaned, id, timeNow)
+	if l.onExpire != nil {
+		st := l.keyState(a, p, timeNow)
+		st.Meta = l.meta[id]
+		l.expiredKeys = append(l.expiredKeys, expiredKey[T]{
+			id: id,
+			st: st,
+		})
+	}
+	l.remove(id)
+}
+
+// pass queued expired keys to OnExpire() callback
+// l.mu must not be held
+func (l *Limiter[T]) notifyExpired() {
+	var (
+		f    func(id T, st KeyState)
+		keys []expiredKey[T]
+	)
+	mu.ExecMutex(&l.mu, func() {
+		f = l.onExpire
+		keys = l.expiredKeys
+		l.expiredKeys = nil
+	})
+	if f == nil {
+		return
+	}
+	for _, k := range keys {
+		f(k.id, k.st)
+	}
+}
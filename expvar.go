@@ -0,0 +1,13 @@
+package limiter
+
+import (
+	"expvar"
+)
+
+// publish Stats() on standard expvar endpoint with name
+// panics if name is already published, like expvar.Publish()
+func (l *Limiter[T]) ExpvarPublish(name string) {
+	expvar.Publish(name, expvar.Func(func() any {
+		return l.Stats()
+	}))
+}
@@ -0,0 +1,180 @@
+package limiter
+
+import (
+	"time"
+
+	"github.com/ssleert/mu"
+)
+
+type fairShare[T comparable] struct {
+	// global actions per period
+	// 0 disables fair share
+	total  int
+	period int64
+
+	weights map[T]float64
+
+	// period weights are summed for
+	current int64
+	// weight of keys active in current period
+	sum float64
+	// weight of keys active in previous period
+	prevSum float64
+}
+
+// divide total actions per window across keys active
+// in last window in proportion to their weights
+// so one busy key can't take whole global budget
+//
+// key limit is its share of total, but it is never
+// over key own policy limit, so set default policy
+// high enough and use SetWeight() for tenants
+//
+// total <= 0 disables fair share
+// resolution is one second
+func (l *Limiter[T]) SetFairShare(total int, window time.Duration) {
+	defer l.moveBack()
+	period := int64(window / time.Second)
+	if period < 1 {
+		period = 1
+	}
+	mu.ExecMutex(&l.mu, func() {
+		l.fair.total = total
+		l.fair.period = period
+	})
+}
+
+// set fair share weight of key
+// keys without weight have weight 1
+//
+// w <= 0 resets weight to 1
+func (l *Limiter[T]) SetWeight(id T, w float64) {
+	defer l.moveBack(id)
+	mu.ExecMutex(&l.mu, func() {
+		if w <= 0 {
+			delete(l.fair.weights, id)
+			return
+		}
+		if l.fair.weights == nil {
+			l.fair.weights = make(map[T]float64)
+		}
+		l.fair.weights[id] = w
+	})
+}
+
+// policy p limited to fair share of id
+// and a marked as active
+//
+// l.mu must be held
+func (l *Limiter[T]) fairScale(id T, a *action, p policy, timeNow int64) policy {
+	f := &l.fair
+	if f.total <= 0 {
+		return p
+	}
+
+	w, ok := f.weights[id]
+	if !ok {
+		w = 1
+	}
+
+	period := timeNow / f.period
+	switch {
+	case period == f.current+1:
+		f.prevSum = f.sum
+		f.sum = 0
+		f.current = period
+	case period != f.current:
+		f.prevSum = 0
+		f.sum = 0
+		f.current = period
+	}
+	if a.fairPeriod != period {
+		a.fairPeriod = period
+		f.sum += w
+	}
+
+	limit := f.limit(w, f.active(), p)
+	if limit < p.maxCount {
+		p.maxCount = limit
+	}
+	if p.burst > limit {
+		p.burst = limit
+	}
+	return p
+}
+
+// take back weight of key that fairScale()
+// counted active in period
+//
+// l.mu must be held
+func (l *Limiter[T]) unfair(id T, period int64) {
+	f := &l.fair
+	if f.total <= 0 || f.current != period {
+		return
+	}
+	w, ok := f.weights[id]
+	if !ok {
+		w = 1
+	}
+	f.sum -= w
+}
+
+// weight of active keys
+func (f *fairShare[T]) active() float64 {
+	// keys of previous period are likely
+	// to come back in this one
+	if f.prevSum > f.sum {
+		return f.prevSum
+	}
+	return f.sum
+}
+
+// share of key with weight w for window of p
+func (f *fairShare[T]) limit(w, active float64, p policy) int {
+	share := float64(f.total) * w / active
+	share *= float64(p.maxTime) / float64(f.period)
+	return scaled(1, share)
+}
+
+// current fair share of key per its window
+// and weight of keys active now, share is
+// recomputed as keys come and go
+//
+// returns false if fair share is disabled
+func (l *Limiter[T]) FairShare(id T) (share int, active float64, ok bool) {
+	timeNow := l.now().Unix()
+	mu.ExecRWMutex(&l.mu, func() {
+		f := &l.fair
+		if f.total <= 0 {
+			return
+		}
+		w, found := f.weights[id]
+		if !found {
+			w = 1
+		}
+
+		// same as fairScale() does on next call
+		g := fairShare[T]{sum: f.sum, prevSum: f.prevSum}
+		period := timeNow / f.period
+		switch {
+		case period == f.current+1:
+			g.prevSum = f.sum
+			g.sum = 0
+		case period != f.current:
+			g.prevSum = 0
+			g.sum = 0
+		}
+		if a, found := l.get(id); !found || a.fairPeriod != period {
+			g.sum += w
+		}
+		active = g.active()
+
+		p := l.policyOf(id)
+		share = f.limit(w, active, p)
+		if share > p.maxCount {
+			share = p.maxCount
+		}
+		ok = true
+	})
+	return share, active, ok
+}
@@ -0,0 +1,118 @@
+package limiter
+
+import (
+	"math"
+	"sync/atomic"
+
+	"github.com/ssleert/mu"
+)
+
+// count of closed window
+// it's over any limit so adds fail
+const fastClosed = math.MaxInt64 / 2
+
+// window of isolated key counted by atomic add
+// count and last are moved back to entry when
+// window is closed by call that needs lock
+type fastWindow struct {
+	count atomic.Int64
+	last  atomic.Int64
+	limit int64
+	end   int64
+}
+
+// count allowed actions of isolated hot keys with one
+// atomic add instead of taking their lock, denials,
+// window ends and other state changes still take it
+//
+// window is counted this way only if key has plain
+// fixed window, e.g. no burst, pacing, warmup, fair share,
+// load factor, error shedding, bookings, priorities
+// or spike detection, so it gets the same decisions
+// as with lock, see SetHotKeys()
+func (l *Limiter[T]) SetAtomicHotKeys(on bool) {
+	l.hot.atomic.Store(on)
+}
+
+// add n to window if it's not over limit at timeNow
+func (w *fastWindow) add(n, timeNow int64) bool {
+	if timeNow >= w.end {
+		return false
+	}
+	w.last.Store(timeNow)
+	for {
+		c := w.count.Load()
+		if c+n > w.limit {
+			return false
+		}
+		if w.count.CompareAndSwap(c, c+n) {
+			return true
+		}
+	}
+}
+
+// close atomic window of key and move its count to entry
+// hk.l.mu must be held
+func (hk *hotKey[T]) settleLocked(id T) {
+	w := hk.fast.Swap(nil)
+	if w == nil {
+		return
+	}
+	count := w.count.Swap(fastClosed)
+	l := hk.l
+	a, ok := l.get(id)
+	if !ok {
+		return
+	}
+	a.count = int(count)
+	if last := w.last.Load(); last > a.lastTime {
+		a.lastTime = last
+	}
+	l.set(id, a)
+}
+
+func (hk *hotKey[T]) settle(id T) {
+	mu.ExecMutex(&hk.l.mu, func() {
+		hk.settleLocked(id)
+	})
+}
+
+// open atomic window of key if its state allows it
+func (hk *hotKey[T]) arm(id T, timeNow int64) {
+	l := hk.l
+	mu.ExecMutex(&l.mu, func() {
+		if hk.fast.Load() != nil || !l.plain() {
+			return
+		}
+		if _, listed := l.listed(id); listed {
+			return
+		}
+		a, ok := l.get(id)
+		if !ok || a.banned(timeNow) || a.cooldownUntil > timeNow {
+			return
+		}
+		p := l.policyOf(id)
+		if p.burst > 0 || p.ended(a, timeNow) ||
+			l.warmup.scale(p, a, timeNow).maxCount != p.maxCount ||
+			l.healthScale(id, p).maxCount != p.maxCount {
+			return
+		}
+		w := &fastWindow{
+			limit: int64(p.maxCount + a.carry),
+			end:   p.end(a),
+		}
+		w.count.Store(int64(a.count))
+		w.last.Store(a.lastTime)
+		hk.fast.Store(w)
+	})
+}
+
+// true if no limiter setting changes
+// decisions under limit of fixed windows
+//
+// l.mu must be held
+func (l *Limiter[T]) plain() bool {
+	return !l.pacing && l.fair.total <= 0 && l.bookings == nil &&
+		l.spike.interval <= 0 && len(l.prio) == 0 &&
+		!l.inv.on && l.LoadFactor() >= 1
+}
@@ -0,0 +1,73 @@
+package limiter
+
+import (
+	"errors"
+	"sync"
+)
+
+var ErrLimited = errors.New("limiter: action denied")
+
+var errPanicked = errors.New("limiter: DoOnce() fn panicked")
+
+type flight struct {
+	wg   sync.WaitGroup
+	v    any
+	err  error
+	dups int
+}
+
+type flights[T comparable] struct {
+	mu sync.Mutex
+	m  map[T]*flight
+}
+
+// run fn for key once for all concurrent calls
+// with same key, they all get its result
+// and key is charged only once
+//
+// if key is over limit fn is not run
+// and ErrLimited in RetryError is returned
+// shared is true if result was given to many callers
+func (l *Limiter[T]) DoOnce(id T, fn func() (any, error)) (v any, err error, shared bool) {
+	fs := &l.flights
+	fs.mu.Lock()
+	if f, ok := fs.m[id]; ok {
+		f.dups++
+		fs.mu.Unlock()
+		f.wg.Wait()
+		return f.v, f.err, true
+	}
+	if fs.m == nil {
+		fs.m = make(map[T]*flight)
+	}
+	f := &flight{}
+	f.wg.Add(1)
+	fs.m[id] = f
+	fs.mu.Unlock()
+
+	done := func() bool {
+		fs.mu.Lock()
+		delete(fs.m, id)
+		dups := f.dups
+		fs.mu.Unlock()
+		f.wg.Done()
+		return dups > 0
+	}
+	defer func() {
+		// fn panicked, don't leave waiters hanging
+		if f != nil {
+			f.err = errPanicked
+			done()
+		}
+	}()
+
+	if l.Try(id) {
+		f.v, f.err = fn()
+	} else {
+		f.err = l.retryError(ErrLimited, id, 1)
+	}
+	v, err = f.v, f.err
+	shared = done()
+	f = nil
+	return v, err, shared
+}
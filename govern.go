@@ -0,0 +1,111 @@
+package limiter
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// set part of policy limits keys can use now
+// 1 means full limits, 0.5 half of them
+// every key keeps at least one action
+//
+// k is clamped to [0, 1]
+func (l *Limiter[T]) SetLoadFactor(k float64) {
+	defer l.moveBack()
+	if k < 0 || math.IsNaN(k) {
+		k = 0
+	}
+	if k > 1 {
+		k = 1
+	}
+	l.loadFactor.Store(math.Float64bits(1 - k))
+}
+
+// part of policy limits keys can use now
+func (l *Limiter[T]) LoadFactor() float64 {
+	return 1 - math.Float64frombits(l.loadFactor.Load())
+}
+
+// interval of Govern() if given one is not positive
+const defaultGovernInterval = time.Second
+
+// call signal every interval and use its result
+// as load factor, so limits go down when
+// service is overloaded, see ShedCurve()
+// it blocks so run it in your own goroutine
+//
+// interval <= 0 calls it every second
+//
+// load factor is reset to 1 when ctx is done
+// returns ctx.Err()
+func (l *Limiter[T]) Govern(
+	ctx context.Context,
+	interval time.Duration,
+	signal func() float64,
+) error {
+	ctx, unlabel := l.label(ctx, "govern")
+	defer unlabel()
+
+	if interval <= 0 {
+		interval = defaultGovernInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	defer l.SetLoadFactor(1)
+
+	l.SetLoadFactor(signal())
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			l.SetLoadFactor(signal())
+		}
+	}
+}
+
+// make Govern() signal from load like cpu
+// utilization, memory pressure or queue depth
+//
+// load <= low gives full limits, load >= high gives
+// min part of them and load between them goes linearly
+func ShedCurve(low, high, min float64, load func() float64) func() float64 {
+	return func() float64 {
+		v := load()
+		switch {
+		case v <= low:
+			return 1
+		case v >= high:
+			return min
+		}
+		return 1 - (1-min)*(v-low)/(high-low)
+	}
+}
+
+// signal with lowest of signals
+// so limits follow the most loaded resource
+func MinSignal(signals ...func() float64) func() float64 {
+	return func() float64 {
+		k := 1.0
+		for _, f := range signals {
+			if v := f(); v < k {
+				k = v
+			}
+		}
+		return k
+	}
+}
+
+// policy p scaled by load factor
+func (l *Limiter[T]) loadScale(p policy) policy {
+	k := l.LoadFactor()
+	if k >= 1 {
+		return p
+	}
+	p.maxCount = scaled(p.maxCount, k)
+	if p.burst > 0 {
+		p.burst = scaled(p.burst, k)
+	}
+	return p
+}
@@ -0,0 +1,41 @@
+package limiter_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ssleert/limiter"
+)
+
+func TestGovern(t *testing.T) {
+	tests := []struct {
+		name     string
+		interval time.Duration
+	}{
+		{"interval", 10 * time.Millisecond},
+		{"zero interval", 0},
+		{"negative interval", -time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := limiter.New[string](10, 60, 16, 1024, 16)
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan error)
+			go func() {
+				done <- l.Govern(ctx, tt.interval, func() float64 {
+					cancel()
+					return 0.5
+				})
+			}()
+			if err := <-done; !errors.Is(err, context.Canceled) {
+				t.Fatalf("Govern() err = %v, want %v", err, context.Canceled)
+			}
+			// load factor is reset on return
+			if f := l.LoadFactor(); f != 1 {
+				t.Fatalf("LoadFactor() = %v, want 1", f)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package limiter
+
+import "context"
+
+// gives name of budget group for key
+// like "payment-provider" for its hosts
+// empty name is default group
+type GroupResolver[T any] func(id T) string
+
+// named budgets shared by groups of keys like
+// third party apis, so one instance governs
+// all outbound calls and every group has own limit
+//
+// group of key is resolved on every call
+// and groups without own budget use default policy
+type Groups[T any] struct {
+	l     *Limiter[string]
+	group GroupResolver[T]
+}
+
+// make new groups with default budget policy
+// and resolver of key group
+func NewGroups[T any](def Policy, group GroupResolver[T]) *Groups[T] {
+	l := New[string](def.MaxCount, Default, Default, 0, Default)
+	l.SetPolicy(def)
+	return &Groups[T]{
+		l:     l,
+		group: group,
+	}
+}
+
+// limiter of groups for own settings
+// keys of it are group names
+func (g *Groups[T]) Limiter() *Limiter[string] {
+	return g.l
+}
+
+// set budget policy of named group
+func (g *Groups[T]) SetBudget(name string, p Policy) {
+	g.l.SetKeyPolicy(name, p)
+}
+
+// group of key
+func (g *Groups[T]) Group(id T) string {
+	return g.group(id)
+}
+
+func (g *Groups[T]) Try(id T) bool {
+	return g.l.Try(g.group(id))
+}
+
+// true if group of key has n units
+func (g *Groups[T]) TryN(id T, n int) bool {
+	return g.l.TryN(g.group(id), n)
+}
+
+// wait until group of key has budget
+func (g *Groups[T]) Wait(ctx context.Context, id T) error {
+	return g.l.Wait(ctx, g.group(id))
+}
+
+// reset budget of key whole group
+func (g *Groups[T]) Reset(id T) bool {
+	return g.l.Reset(g.group(id))
+}
+
+// GroupResolver from group of every key
+// with def group for others, e.g. hosts of
+// one provider, keys are put through norm
+// like Host() if it is not nil
+func GroupMap(
+	groups map[string][]string,
+	def string,
+	norm func(id string) string,
+) GroupResolver[string] {
+	m := make(map[string]string)
+	for name, ids := range groups {
+		for _, id := range ids {
+			if norm != nil {
+				id = norm(id)
+			}
+			m[id] = name
+		}
+	}
+	return func(id string) string {
+		if norm != nil {
+			id = norm(id)
+		}
+		name, ok := m[id]
+		if !ok {
+			return def
+		}
+		return name
+	}
+}
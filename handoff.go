@@ -0,0 +1,182 @@
+package limiter
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/ssleert/mu"
+)
+
+const handoffVersion = 1
+
+var ErrHandoff = errors.New("limiter: bad handoff stream")
+
+// first line of handoff stream
+type handoffHeader struct {
+	Version int `json:"version"`
+	// sender clock in unix seconds
+	Time int64 `json:"time"`
+}
+
+// line of handoff stream
+// last one has End set
+type handoffLine[T any] struct {
+	Entry *Entry[T] `json:"entry,omitempty"`
+	End   bool      `json:"end,omitempty"`
+	Count int       `json:"count,omitempty"`
+}
+
+// stream all entries to w for Accept() on new instance
+// e.g. in blue green deploy, and drain l
+//
+// entries are sent in two passes, second one
+// resends entries changed during first one
+// after that entries are removed from l
+// so stop traffic to l before second pass ends
+//
+// returns count of sent entries
+func (l *Limiter[T]) Handoff(w io.Writer) (int, error) {
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+
+	start := l.now().Unix()
+	err := enc.Encode(handoffHeader{
+		Version: handoffVersion,
+		Time:    start,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	var sent int
+	send := func(entries []Entry[T]) error {
+		for i := range entries {
+			err := enc.Encode(handoffLine[T]{Entry: &entries[i]})
+			if err != nil {
+				return err
+			}
+			sent++
+		}
+		return nil
+	}
+
+	if err := send(l.Snapshot()); err != nil {
+		return sent, err
+	}
+
+	// isolated keys are drained from shared map
+	l.moveBack()
+	var changed []Entry[T]
+	mu.ExecMutex(&l.mu, func() {
+		for id, p := range l.m {
+			if a := p.unpack(); a.lastTime >= start {
+				changed = append(changed, l.export(id, a))
+			}
+		}
+		for id := range l.m {
+			l.remove(id)
+		}
+	})
+	if err := send(changed); err != nil {
+		return sent, err
+	}
+
+	err = enc.Encode(handoffLine[T]{End: true, Count: sent})
+	if err != nil {
+		return sent, err
+	}
+	return sent, bw.Flush()
+}
+
+// read stream written by Handoff() from r
+// entry times are moved by difference of clocks
+// and entries are merged with ones in l
+//
+// second pass entries replace first pass ones
+// returns count of applied entries
+func (l *Limiter[T]) Accept(r io.Reader) (int, error) {
+	dec := json.NewDecoder(bufio.NewReader(r))
+
+	var h handoffHeader
+	if err := dec.Decode(&h); err != nil {
+		return 0, err
+	}
+	if h.Version != handoffVersion {
+		return 0, fmt.Errorf("%w: version %d", ErrHandoff, h.Version)
+	}
+	skew := l.now().Unix() - h.Time
+
+	got := make(map[T]Entry[T])
+	var n int
+	for {
+		var line handoffLine[T]
+		if err := dec.Decode(&line); err != nil {
+			if err == io.EOF {
+				err = fmt.Errorf("%w: no end line", ErrHandoff)
+			}
+			return 0, err
+		}
+		if line.End {
+			if line.Count != n {
+				return 0, fmt.Errorf("%w: got %d of %d entries", ErrHandoff, n, line.Count)
+			}
+			break
+		}
+		if line.Entry == nil {
+			return 0, fmt.Errorf("%w: empty line", ErrHandoff)
+		}
+		e := *line.Entry
+		got[e.Key] = e.shift(skew)
+		n++
+	}
+
+	ids := make([]T, 0, len(got))
+	for id := range got {
+		ids = append(ids, id)
+	}
+	l.moveBackOf(ids)
+	timeNow := l.now().Unix()
+	mu.ExecMutex(&l.mu, func() {
+		for id, e := range got {
+			l.accept(id, e, timeNow)
+		}
+	})
+	return len(got), nil
+}
+
+// merge received entry into entry of key
+//
+// l.mu must be held
+func (l *Limiter[T]) accept(id T, e Entry[T], timeNow int64) {
+	if e.Meta != nil {
+		l.setMeta(id, e.Meta)
+	}
+	b := e.action()
+	a, ok := l.get(id)
+	if !ok {
+		l.set(id, b)
+		return
+	}
+	l.set(id, l.policyOf(id).merge(a, b, timeNow))
+}
+
+// entry with all times moved by d seconds
+func (e Entry[T]) shift(d int64) Entry[T] {
+	move := func(t *int64) {
+		if *t != 0 {
+			*t += d
+		}
+	}
+	move(&e.WindowStart)
+	move(&e.LastSeen)
+	move(&e.FirstSeen)
+	move(&e.LastDenied)
+	move(&e.ViolationStart)
+	move(&e.BannedUntil)
+	move(&e.CooldownUntil)
+	move(&e.Refilled)
+	return e
+}
@@ -0,0 +1,106 @@
+package limiter
+
+import (
+	"github.com/ssleert/mu"
+)
+
+// weight of last result in key error rate
+const healthAlpha = 0.1
+
+// error rate under it is forgotten
+const healthForget = 0.01
+
+type health[T comparable] struct {
+	// error rate over which limit is cut
+	// 0 disables shedding
+	threshold float64
+	// part of limit left at error rate 1
+	min float64
+
+	// ewma error rates of keys
+	rates map[T]float64
+}
+
+// cut limit of key when its downstream error rate
+// reported by ReportResult() goes over threshold
+// down to min part of limit when all calls fail
+// and give it back as errors go away
+//
+// threshold <= 0 disables it
+func (l *Limiter[T]) SetErrorShedding(threshold, min float64) {
+	defer l.moveBack()
+	if min < 0 {
+		min = 0
+	}
+	if min > 1 {
+		min = 1
+	}
+	mu.ExecMutex(&l.mu, func() {
+		l.health.threshold = threshold
+		l.health.min = min
+		if threshold <= 0 {
+			l.health.rates = nil
+		}
+	})
+}
+
+// report result of downstream call of key
+// nil err is success, see SetErrorShedding()
+func (l *Limiter[T]) ReportResult(id T, err error) {
+	if hk := l.hotOf(id); hk != nil {
+		hk.l.ReportResult(id, err)
+		return
+	}
+	var v float64
+	if err != nil {
+		v = 1
+	}
+	mu.ExecMutex(&l.mu, func() {
+		h := &l.health
+		if h.threshold <= 0 {
+			return
+		}
+		if h.rates == nil {
+			h.rates = make(map[T]float64)
+		}
+		rate := h.rates[id]*(1-healthAlpha) + v*healthAlpha
+		if rate < healthForget {
+			delete(h.rates, id)
+			return
+		}
+		h.rates[id] = rate
+	})
+}
+
+// error rate of key reported by ReportResult()
+func (l *Limiter[T]) ErrorRate(id T) float64 {
+	if hk := l.hotOf(id); hk != nil {
+		return hk.l.ErrorRate(id)
+	}
+	var rate float64
+	mu.ExecRWMutex(&l.mu, func() {
+		rate = l.health.rates[id]
+	})
+	return rate
+}
+
+// policy p cut by error rate of id
+//
+// l.mu must be held
+func (l *Limiter[T]) healthScale(id T, p policy) policy {
+	h := l.health
+	if h.threshold <= 0 || h.threshold >= 1 {
+		return p
+	}
+	rate, ok := h.rates[id]
+	if !ok || rate <= h.threshold {
+		return p
+	}
+
+	k := 1 - (rate-h.threshold)/(1-h.threshold)*(1-h.min)
+	p.maxCount = scaled(p.maxCount, k)
+	if p.burst > 0 {
+		p.burst = scaled(p.burst, k)
+	}
+	return p
+}
@@ -0,0 +1,66 @@
+package limiter
+
+import (
+	"hash/maphash"
+	"math"
+	"math/bits"
+)
+
+// hyperloglog precision
+// 2^14 registers give about 0.8% error
+const hllPrecision = 14
+
+// approximate count of unique keys
+type hll struct {
+	regs []uint8
+	seed maphash.Seed
+}
+
+func (h *hll) add(sum uint64) {
+	if h.regs == nil {
+		h.regs = make([]uint8, 1<<hllPrecision)
+	}
+	i := sum >> (64 - hllPrecision)
+	rank := uint8(bits.LeadingZeros64(sum<<hllPrecision|1<<(hllPrecision-1))) + 1
+	if rank > h.regs[i] {
+		h.regs[i] = rank
+	}
+}
+
+func (h *hll) count() uint64 {
+	if h.regs == nil {
+		return 0
+	}
+
+	m := float64(len(h.regs))
+	var (
+		sum   float64
+		zeros int
+	)
+	for _, r := range h.regs {
+		sum += math.Ldexp(1, -int(r))
+		if r == 0 {
+			zeros++
+		}
+	}
+	est := 0.7213 / (1 + 1.079/m) * m * m / sum
+
+	// linear counting is better for small counts
+	if est <= 2.5*m && zeros > 0 {
+		est = m * math.Log(m/float64(zeros))
+	}
+	return uint64(est + 0.5)
+}
+
+// add key to unique keys count
+//
+// l.mu must be held
+func (l *Limiter[T]) addUnique(id T) {
+	if l.unique.regs == nil {
+		l.unique.seed = maphash.MakeSeed()
+	}
+	var h maphash.Hash
+	h.SetSeed(l.unique.seed)
+	writeKey(&h, id)
+	l.unique.add(h.Sum64())
+}
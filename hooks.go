@@ -0,0 +1,47 @@
+package limiter
+
+// hooks run around Try() decision
+type hooks[T any] struct {
+	before []func(id T) bool
+	after  []func(id T, ok bool) bool
+}
+
+// add f to chain called before Try() decision
+// if f returns false action is denied
+// without touching key state and rest of chain
+//
+// hooks are called outside of limiter lock
+// in order they were added
+func (l *Limiter[T]) BeforeTry(f func(id T) bool) {
+	l.addHooks(func(h *hooks[T]) {
+		h.before = append(h.before, f)
+	})
+}
+
+// add f to chain called after Try() decision
+// f gets current result and returns new one
+// so it can override decision
+//
+// hooks are called outside of limiter lock
+// in order they were added
+func (l *Limiter[T]) AfterTry(f func(id T, ok bool) bool) {
+	l.addHooks(func(h *hooks[T]) {
+		h.after = append(h.after, f)
+	})
+}
+
+// copy current hooks, change copy and store it
+func (l *Limiter[T]) addHooks(f func(h *hooks[T])) {
+	for {
+		old := l.hooks.Load()
+		h := &hooks[T]{}
+		if old != nil {
+			h.before = append(h.before, old.before...)
+			h.after = append(h.after, old.after...)
+		}
+		f(h)
+		if l.hooks.CompareAndSwap(old, h) {
+			return
+		}
+	}
+}
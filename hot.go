@@ -0,0 +1,377 @@
+package limiter
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ssleert/mu"
+	"golang.org/x/exp/constraints"
+)
+
+const (
+	// one of hotSample calls is counted per key
+	hotSample = 16
+	// min sampled calls of key before it is isolated
+	hotMinSamples = 4
+)
+
+type hotKeys[T constraints.Ordered] struct {
+	// part of all calls that makes key hot
+	// 0 disables detection
+	share    float64
+	interval int64
+
+	// interval start and sampled calls of keys in it
+	start  int64
+	counts map[T]int
+	// calls at interval start
+	lastCalls uint64
+
+	on atomic.Bool
+	// all Try() calls while on
+	calls atomic.Uint64
+	// held by rebalance and moveBack so key
+	// is not isolated with old settings
+	balance sync.Mutex
+	// see SetAtomicHotKeys()
+	atomic atomic.Bool
+
+	// isolated keys, copy on write
+	keys atomic.Pointer[map[T]*hotKey[T]]
+}
+
+// isolated key with own limiter
+// so it has own lock
+type hotKey[T constraints.Ordered] struct {
+	l     *Limiter[T]
+	calls atomic.Uint64
+	// nil if key is counted under lock
+	fast atomic.Pointer[fastWindow]
+}
+
+// sampled calls of interval
+type hotRound[T constraints.Ordered] struct {
+	counts map[T]int
+	total  uint64
+}
+
+// move keys that get over share of all Try() calls
+// in interval to own state with own lock, so one
+// hot key doesn't slow down others, and move them
+// back when they get under half of share
+//
+// changing settings of limiter moves isolated keys
+// back so they get new ones, keys of changed keys
+// only for per key settings, isolated keys are in
+// events and audit of l, keys are not isolated
+// while fair share is on
+//
+// share <= 0 moves all keys back and disables it
+// resolution is one second
+func (l *Limiter[T]) SetHotKeys(share float64, interval time.Duration) {
+	secs := int64(interval / time.Second)
+	if secs < 1 {
+		secs = 1
+	}
+	mu.ExecMutex(&l.mu, func() {
+		l.hot.share = share
+		l.hot.interval = secs
+		l.hot.start = 0
+		l.hot.counts = nil
+	})
+	l.hot.on.Store(share > 0)
+	if share <= 0 {
+		l.moveBack()
+	}
+}
+
+// move isolated ids or all isolated keys if ids are
+// empty back to shared map, so they get settings
+// changed by caller, next rebalance isolates them
+// again if they are still hot
+//
+// l.mu must not be held
+func (l *Limiter[T]) moveBack(ids ...T) {
+	// rebalance that is running could copy
+	// old settings, so it is waited for
+	l.hot.balance.Lock()
+	defer l.hot.balance.Unlock()
+	if len(ids) == 0 {
+		for id := range l.hotKeys() {
+			l.unisolate(id)
+		}
+		return
+	}
+	for _, id := range ids {
+		l.unisolate(id)
+	}
+}
+
+// isolated keys
+func (l *Limiter[T]) HotKeys() []T {
+	keys := l.hotKeys()
+	ids := make([]T, 0, len(keys))
+	for id := range keys {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+func (l *Limiter[T]) hotKeys() map[T]*hotKey[T] {
+	if p := l.hot.keys.Load(); p != nil {
+		return *p
+	}
+	return nil
+}
+
+// own state of key if it is isolated
+func (l *Limiter[T]) hotOf(id T) *hotKey[T] {
+	if p := l.hot.keys.Load(); p != nil {
+		return (*p)[id]
+	}
+	return nil
+}
+
+// move back those of ids that are isolated
+func (l *Limiter[T]) moveBackOf(ids []T) {
+	var hot []T
+	for _, id := range ids {
+		if l.hotOf(id) != nil {
+			hot = append(hot, id)
+		}
+	}
+	if len(hot) > 0 {
+		l.moveBack(hot...)
+	}
+}
+
+// call f with entry of every isolated key
+// and limiter that has it
+//
+// l.mu must be held
+func (l *Limiter[T]) eachHot(f func(id T, hl *Limiter[T], a action)) {
+	for id, hk := range l.hotKeys() {
+		mu.ExecMutex(&hk.l.mu, func() {
+			hk.settleLocked(id)
+			if a, ok := hk.l.get(id); ok {
+				f(id, hk.l, a)
+			}
+		})
+	}
+}
+
+// sample call of key and end interval if needed
+// returns round to rebalance or nil
+//
+// l.mu must be held
+func (l *Limiter[T]) countHot(id T, timeNow int64) *hotRound[T] {
+	h := &l.hot
+	if !h.on.Load() {
+		return nil
+	}
+	calls := h.calls.Load()
+	// random so keys called in turns are sampled too
+	if l.int63n(hotSample) == 0 {
+		if h.counts == nil {
+			h.counts = make(map[T]int)
+		}
+		h.counts[id]++
+	}
+	if h.start == 0 {
+		h.start = timeNow
+		h.lastCalls = calls
+	}
+	if timeNow-h.start < h.interval {
+		return nil
+	}
+
+	r := &hotRound[T]{
+		counts: h.counts,
+		total:  calls - h.lastCalls,
+	}
+	h.start = timeNow
+	h.lastCalls = calls
+	h.counts = nil
+	return r
+}
+
+// isolate keys of round that got hot
+// and move back ones that got cold
+func (l *Limiter[T]) rebalance(r *hotRound[T]) {
+	if !l.hot.balance.TryLock() {
+		return
+	}
+	defer l.hot.balance.Unlock()
+
+	var share float64
+	mu.ExecRWMutex(&l.mu, func() {
+		share = l.hot.share
+		// fair share is counted over all keys
+		if l.fair.total > 0 {
+			share = 0
+		}
+	})
+	if share <= 0 {
+		return
+	}
+	threshold := share * float64(r.total)
+
+	for id, hk := range l.hotKeys() {
+		if float64(hk.calls.Swap(0)) < threshold/2 {
+			l.unisolate(id)
+		}
+	}
+	for id, count := range r.counts {
+		if count >= hotMinSamples && float64(count*hotSample) >= threshold {
+			l.isolate(id)
+		}
+	}
+}
+
+// move entry of key to own limiter
+func (l *Limiter[T]) isolate(id T) {
+	if l.hotOf(id) != nil {
+		return
+	}
+	c := &Limiter[T]{m: make(map[T]packed, 1)}
+	l.sharedTo(c)
+	// key is already hot and trusted by l
+	c.hot.on.Store(false)
+	c.trust.on.Store(false)
+	hk := &hotKey[T]{l: c}
+
+	mu.ExecMutex(&l.mu, func() {
+		if _, listed := l.listed(id); listed {
+			return
+		}
+		// bookings are counted in shared map
+		if _, booked := l.bookings[id]; booked {
+			return
+		}
+		l.configTo(c)
+		c.autoClean = false
+		if p, ok := l.keyPolicies[id]; ok {
+			c.keyPolicies = map[T]policy{id: p}
+		}
+		if s, ok := l.keyScales[id]; ok {
+			c.keyScales = map[T]float64{id: s}
+		}
+		c.nsName = l.nsName
+		c.parent = l.parent
+		c.owner = l
+		c.events = l.events
+		c.audit = l.audit
+		if a, ok := l.m[id]; ok {
+			hk.l.m[id] = a
+			if p, ok := l.resolved[id]; ok {
+				hk.l.setResolved(id, p)
+			}
+			hk.l.setMeta(id, l.meta[id])
+			if rate, ok := l.health.rates[id]; ok {
+				hk.l.health.rates = map[T]float64{id: rate}
+				delete(l.health.rates, id)
+			}
+			l.remove(id)
+		}
+		keys := make(map[T]*hotKey[T], len(l.hotKeys())+1)
+		for k, v := range l.hotKeys() {
+			keys[k] = v
+		}
+		keys[id] = hk
+		l.hot.keys.Store(&keys)
+	})
+}
+
+// move entry of key back to shared map
+func (l *Limiter[T]) unisolate(id T) {
+	hk := l.hotOf(id)
+	if hk == nil {
+		return
+	}
+	mu.ExecMutex(&l.mu, func() {
+		mu.ExecMutex(&hk.l.mu, func() {
+			hk.settleLocked(id)
+			if a, ok := hk.l.m[id]; ok {
+				l.put(id, a)
+				if p, ok := hk.l.resolved[id]; ok {
+					l.setResolved(id, p)
+				}
+				l.setMeta(id, hk.l.meta[id])
+			}
+			if rate, ok := hk.l.health.rates[id]; ok && l.health.threshold > 0 {
+				if l.health.rates == nil {
+					l.health.rates = make(map[T]float64)
+				}
+				l.health.rates[id] = rate
+			}
+		})
+		keys := make(map[T]*hotKey[T], len(l.hotKeys()))
+		for k, v := range l.hotKeys() {
+			if k != id {
+				keys[k] = v
+			}
+		}
+		l.hot.keys.Store(&keys)
+	})
+}
+
+// give isolated keys event stream
+// and audit of l after they change
+//
+// l.mu must be held
+func (l *Limiter[T]) shareStreams() {
+	for _, hk := range l.hotKeys() {
+		mu.ExecMutex(&hk.l.mu, func() {
+			hk.l.events = l.events
+			hk.l.audit = l.audit
+		})
+	}
+}
+
+// call f with limiter that has entry of key
+// own one if key is isolated or l
+//
+// l.mu must be held
+func (l *Limiter[T]) holder(id T, f func(hl *Limiter[T])) {
+	hk := l.hotOf(id)
+	if hk == nil {
+		f(l)
+		return
+	}
+	mu.ExecMutex(&hk.l.mu, func() {
+		hk.settleLocked(id)
+		f(hk.l)
+	})
+}
+
+// limiter that counts dropped events and records
+// of l, one that isolated key belongs to or l
+func (l *Limiter[T]) counters() *Limiter[T] {
+	if l.owner != nil {
+		return l.owner
+	}
+	return l
+}
+
+// decide for isolated key
+func (l *Limiter[T]) decideHot(hk *hotKey[T], id T, n, prio int, now time.Time) bool {
+	hk.calls.Add(1)
+	fast := l.hot.atomic.Load()
+	if w := hk.fast.Load(); fast && w != nil && w.add(int64(n), now.Unix()) {
+		l.stats.allowed.Add(1)
+		return true
+	}
+
+	hk.settle(id)
+	ok := hk.l.decide(id, n, prio, now)
+	if ok && fast {
+		hk.arm(id, now.Unix())
+	}
+	if ok {
+		l.stats.allowed.Add(1)
+	} else {
+		l.stats.denied.Add(1)
+	}
+	return ok
+}
@@ -0,0 +1,171 @@
+package limiter_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ssleert/limiter"
+	"github.com/ssleert/limiter/limitertest"
+)
+
+const hotLimit = 300
+
+// limiter with isolated key a that spent its limit
+func hotLimiter(t *testing.T) *limiter.Limiter[string] {
+	t.Helper()
+	c := limitertest.NewClock(time.Unix(1000, 0))
+	l := limiter.New[string](hotLimit, 60, 16, 1024, 16)
+	l.SetDeterministic(c.Now, 1)
+	l.SetHotKeys(0.5, time.Second)
+
+	limitertest.AssertAllowed[string](t, l, "a", 200)
+	c.Advance(2 * time.Second)
+	// ends interval and isolates a
+	limitertest.AssertAllowed[string](t, l, "a", 1)
+	if got := l.HotKeys(); len(got) != 1 || got[0] != "a" {
+		t.Fatalf("HotKeys() = %v, want [a]", got)
+	}
+
+	limitertest.AssertAllowed[string](t, l, "a", hotLimit-201)
+	limitertest.AssertDenied[string](t, l, "a")
+	return l
+}
+
+func TestHotKeyState(t *testing.T) {
+	tests := []struct {
+		name  string
+		check func(t *testing.T, l *limiter.Limiter[string])
+	}{
+		{"snapshot", func(t *testing.T, l *limiter.Limiter[string]) {
+			for _, e := range l.Snapshot() {
+				if e.Key == "a" && e.Count == hotLimit {
+					return
+				}
+			}
+			t.Fatalf("Snapshot() = %+v, want a with count %d", l.Snapshot(), hotLimit)
+		}},
+		{"top consumers", func(t *testing.T, l *limiter.Limiter[string]) {
+			top := l.TopConsumers(1)
+			if len(top) != 1 || top[0].Key != "a" || top[0].Count != hotLimit {
+				t.Fatalf("TopConsumers(1) = %v, want a with %d", top, hotLimit)
+			}
+		}},
+		{"len", func(t *testing.T, l *limiter.Limiter[string]) {
+			if n := l.Len(); n != 1 {
+				t.Fatalf("Len() = %d, want 1", n)
+			}
+		}},
+		{"clone", func(t *testing.T, l *limiter.Limiter[string]) {
+			limitertest.AssertDenied[string](t, l.Clone(true), "a")
+		}},
+		{"retry after", func(t *testing.T, l *limiter.Limiter[string]) {
+			if d, ok := l.RetryAfter("a", 1); d <= 0 || !ok {
+				t.Fatalf("RetryAfter(a) = %v, %v, want wait", d, ok)
+			}
+		}},
+		{"try many", func(t *testing.T, l *limiter.Limiter[string]) {
+			if l.TryMany("a", "b") {
+				t.Fatal("TryMany(a, b) allowed spent key")
+			}
+			limitertest.AssertAllowed[string](t, l, "b", 1)
+		}},
+		{"move quota", func(t *testing.T, l *limiter.Limiter[string]) {
+			if err := l.MoveQuota("a", "b", 1); !errors.Is(err, limiter.ErrNoQuota) {
+				t.Fatalf("MoveQuota(a, b) = %v, want ErrNoQuota", err)
+			}
+		}},
+		{"key ttl", func(t *testing.T, l *limiter.Limiter[string]) {
+			if !l.SetKeyTTL("a", time.Hour) {
+				t.Fatal("SetKeyTTL(a) = false, want true")
+			}
+			limitertest.AssertDenied[string](t, l, "a")
+		}},
+		{"reserve", func(t *testing.T, l *limiter.Limiter[string]) {
+			if _, err := l.ReserveAt("a", time.Unix(1002, 0)); !errors.Is(err, limiter.ErrWindowFull) {
+				t.Fatalf("ReserveAt(a) = %v, want ErrWindowFull", err)
+			}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.check(t, hotLimiter(t))
+		})
+	}
+}
+
+func TestHotKeySettings(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(l *limiter.Limiter[string])
+		allow  bool
+	}{
+		{"max count", func(l *limiter.Limiter[string]) { l.SetMaxCount(hotLimit + 1) }, true},
+		{"policy", func(l *limiter.Limiter[string]) {
+			l.SetPolicy(limiter.Policy{MaxCount: hotLimit + 1, Window: time.Minute})
+		}, true},
+		{"key policy", func(l *limiter.Limiter[string]) {
+			l.SetKeyPolicy("a", limiter.Policy{MaxCount: hotLimit + 1, Window: time.Minute})
+		}, true},
+		{"allow keys", func(l *limiter.Limiter[string]) { l.AllowKeys("a") }, true},
+		{"deny keys", func(l *limiter.Limiter[string]) {
+			l.SetMaxCount(hotLimit + 1)
+			l.DenyKeys("a")
+		}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := hotLimiter(t)
+			tt.change(l)
+			if got := l.Try("a"); got != tt.allow {
+				t.Fatalf("Try(a) after change = %v, want %v", got, tt.allow)
+			}
+		})
+	}
+}
+
+func TestHotKeySinks(t *testing.T) {
+	tests := []struct {
+		name  string
+		check func(t *testing.T, l *limiter.Limiter[string])
+	}{
+		{"events", func(t *testing.T, l *limiter.Limiter[string]) {
+			events := l.Events(16)
+			limitertest.AssertDenied[string](t, l, "a")
+			select {
+			case e := <-events:
+				if e.Kind != limiter.EventDenied || e.Key != "a" {
+					t.Fatalf("event = %+v, want denied a", e)
+				}
+			default:
+				t.Fatal("no event of isolated key")
+			}
+		}},
+		{"stop events", func(t *testing.T, l *limiter.Limiter[string]) {
+			l.Events(16)
+			l.StopEvents()
+			limitertest.AssertDenied[string](t, l, "a")
+		}},
+		{"dropped events", func(t *testing.T, l *limiter.Limiter[string]) {
+			l.Events(0)
+			limitertest.AssertDenied[string](t, l, "a")
+			if got := l.Stats().DroppedEvents; got != 1 {
+				t.Fatalf("DroppedEvents = %d, want 1", got)
+			}
+		}},
+		{"audit", func(t *testing.T, l *limiter.Limiter[string]) {
+			if r := audited(t, l, "a", time.Millisecond); r.Key != "a" || r.Count != hotLimit {
+				t.Fatalf("record = %+v, want a with count %d", r, hotLimit)
+			}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := hotLimiter(t)
+			tt.check(t, l)
+			if got := l.HotKeys(); len(got) != 1 {
+				t.Fatalf("HotKeys() = %v, want a still isolated", got)
+			}
+		})
+	}
+}
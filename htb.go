@@ -0,0 +1,214 @@
+package limiter
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ssleert/mu"
+	"golang.org/x/exp/constraints"
+)
+
+// hierarchical token bucket, every child bucket
+// like tenant draws from one parent bucket like
+// cluster, so children together never go over
+// parent capacity whatever their own limits are
+//
+// action of key is allowed only if both its
+// bucket and parent bucket have tokens for it
+// or key can borrow them, see SetBorrow()
+// policies without burst get MaxCount burst
+// resolution is one second
+type HTB[T constraints.Ordered] struct {
+	mu sync.Mutex
+
+	parent  policy
+	root    action
+	child   policy
+	classes map[T]policy
+	m       map[T]action
+
+	// borrow buckets of keys
+	ceils    map[T]policy
+	borrowed map[T]action
+	// parent tokens borrowers can't take
+	floor float64
+}
+
+// make new hierarchy with parent bucket
+// and default child bucket policy
+func NewHTB[T constraints.Ordered](parent, child Policy) *HTB[T] {
+	timeNow := time.Now().Unix()
+	pp := bucketPolicy(parent)
+	return &HTB[T]{
+		parent: pp,
+		root:   pp.fresh(timeNow),
+		child:  bucketPolicy(child),
+		m:      make(map[T]action, defaultMapLen),
+	}
+}
+
+// policy in bucket mode
+func bucketPolicy(p Policy) policy {
+	ip := p.internal()
+	ip.schedule = nil
+	if ip.burst <= 0 {
+		ip.burst = ip.maxCount
+	}
+	return ip
+}
+
+// set own bucket policy for child key
+// its bucket is refilled by new policy from now on
+func (h *HTB[T]) SetClass(id T, p Policy) {
+	mu.ExecMutex(&h.mu, func() {
+		if h.classes == nil {
+			h.classes = make(map[T]policy)
+		}
+		h.classes[id] = bucketPolicy(p)
+	})
+}
+
+// let key borrow unused parent tokens when its own
+// bucket is empty, up to ceil bucket on top of own one
+// like ceil of htb class in traffic shaping
+//
+// zero ceil MaxCount disables borrowing for key
+func (h *HTB[T]) SetBorrow(id T, ceil Policy) {
+	mu.ExecMutex(&h.mu, func() {
+		if ceil.MaxCount <= 0 {
+			delete(h.ceils, id)
+			delete(h.borrowed, id)
+			return
+		}
+		if h.ceils == nil {
+			h.ceils = make(map[T]policy)
+			h.borrowed = make(map[T]action)
+		}
+		h.ceils[id] = bucketPolicy(ceil)
+	})
+}
+
+// keep part of parent burst for keys with own tokens
+// so keys borrow only when parent is mostly idle
+// part is in [0, 1], default is 0
+func (h *HTB[T]) SetBorrowFloor(part float64) {
+	if part < 0 {
+		part = 0
+	}
+	if part > 1 {
+		part = 1
+	}
+	mu.ExecMutex(&h.mu, func() {
+		h.floor = part * float64(h.parent.burst)
+	})
+}
+
+// true if key and parent have one token
+func (h *HTB[T]) Try(id T) bool {
+	return h.TryN(id, 1)
+}
+
+// true if key and parent have n tokens
+// tokens are taken from both or none
+// n < 1 is counted as 1
+func (h *HTB[T]) TryN(id T, n int) bool {
+	if n < 1 {
+		n = 1
+	}
+	timeNow := time.Now().Unix()
+
+	var ok bool
+	mu.ExecMutex(&h.mu, func() {
+		p := h.classOf(id)
+		a, found := h.m[id]
+		if !found {
+			a = p.fresh(timeNow)
+		}
+		root := h.root
+		if p.admit(&a, n, timeNow) {
+			if !h.parent.admit(&root, n, timeNow) {
+				return
+			}
+			h.m[id] = a
+			h.root = root
+			ok = true
+			return
+		}
+		ok = h.borrow(id, n, timeNow)
+	})
+	return ok
+}
+
+// take n tokens from borrow bucket of key and parent
+// if parent stays over floor
+//
+// h.mu must be held
+func (h *HTB[T]) borrow(id T, n int, timeNow int64) bool {
+	cp, ok := h.ceils[id]
+	if !ok {
+		return false
+	}
+	if h.parent.tokens(h.root, timeNow)-float64(n) < h.floor {
+		return false
+	}
+
+	c, found := h.borrowed[id]
+	if !found {
+		c = cp.fresh(timeNow)
+	}
+	root := h.root
+	if !cp.admit(&c, n, timeNow) || !h.parent.admit(&root, n, timeNow) {
+		return false
+	}
+	h.borrowed[id] = c
+	h.root = root
+	return true
+}
+
+// tokens left in bucket of key and in parent bucket
+func (h *HTB[T]) Tokens(id T) (child, parent float64) {
+	timeNow := time.Now().Unix()
+	mu.ExecMutex(&h.mu, func() {
+		p := h.classOf(id)
+		a, found := h.m[id]
+		if !found {
+			a = p.fresh(timeNow)
+		}
+		child = p.tokens(a, timeNow)
+		parent = h.parent.tokens(h.root, timeNow)
+	})
+	return child, parent
+}
+
+// remove keys with full buckets
+// they are same as new keys
+// returns count of removed keys
+func (h *HTB[T]) Clean() int {
+	timeNow := time.Now().Unix()
+
+	var removed int
+	mu.ExecMutex(&h.mu, func() {
+		for id, a := range h.m {
+			p := h.classOf(id)
+			if p.tokens(a, timeNow) >= float64(p.burst) {
+				delete(h.m, id)
+				removed++
+			}
+		}
+		for id, c := range h.borrowed {
+			cp := h.ceils[id]
+			if cp.tokens(c, timeNow) >= float64(cp.burst) {
+				delete(h.borrowed, id)
+			}
+		}
+	})
+	return removed
+}
+
+// h.mu must be held
+func (h *HTB[T]) classOf(id T) policy {
+	if p, ok := h.classes[id]; ok {
+		return p
+	}
+	return h.child
+}
@@ -0,0 +1,190 @@
+package httplimit
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/ssleert/limiter"
+	"golang.org/x/exp/constraints"
+)
+
+// http handler with admin api of limiter
+// mount it under debug mux like
+//
+//	mux.Handle("/debug/limiter/", http.StripPrefix("/debug/limiter", admin))
+//
+// endpoints:
+//
+//	GET  /                   html debug page
+//	GET  /stats              limiter stats
+//	GET  /key?id=K           state of key
+//	POST /reset?id=K         forget state of key
+//	POST /unban?id=K         lift ban of key
+//	GET  /top?by=denied&n=N  top keys by denied or allowed
+//	GET  /policy             default policy
+//	PUT  /policy             change fields of default policy
+//	                         given in json body
+type Admin[T constraints.Ordered] struct {
+	l     *limiter.Limiter[T]
+	parse func(s string) (T, error)
+	auth  func(r *http.Request) bool
+}
+
+// make new admin handler for l
+// parse makes key from id param
+//
+// auth must return true for allowed requests
+// if it is nil every request is forbidden
+func NewAdmin[T constraints.Ordered](
+	l *limiter.Limiter[T],
+	parse func(s string) (T, error),
+	auth func(r *http.Request) bool,
+) *Admin[T] {
+	return &Admin[T]{
+		l:     l,
+		parse: parse,
+		auth:  auth,
+	}
+}
+
+// parse func for string keys
+func ParseString(s string) (string, error) {
+	return s, nil
+}
+
+// auth func that checks bearer token
+func TokenAuth(token string) func(r *http.Request) bool {
+	return func(r *http.Request) bool {
+		t, ok := bearer(r)
+		return ok && token != "" &&
+			subtle.ConstantTimeCompare([]byte(t), []byte(token)) == 1
+	}
+}
+
+func (a *Admin[T]) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if a.auth == nil || !a.auth(r) {
+		http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		return
+	}
+
+	switch r.URL.Path {
+	case "/", "":
+		if method(w, r, http.MethodGet) {
+			a.debug(w)
+		}
+	case "/stats":
+		if method(w, r, http.MethodGet) {
+			writeJSON(w, a.l.Stats())
+		}
+	case "/key":
+		if id, ok := a.key(w, r, http.MethodGet); ok {
+			st, found := a.l.KeyStats(id)
+			if !found {
+				http.Error(w, "key not found", http.StatusNotFound)
+				return
+			}
+			writeJSON(w, st)
+		}
+	case "/reset":
+		if id, ok := a.key(w, r, http.MethodPost); ok {
+			writeJSON(w, map[string]bool{"found": a.l.Reset(id)})
+		}
+	case "/unban":
+		if id, ok := a.key(w, r, http.MethodPost); ok {
+			writeJSON(w, map[string]bool{"found": a.l.Unban(id)})
+		}
+	case "/top":
+		if method(w, r, http.MethodGet) {
+			a.top(w, r)
+		}
+	case "/policy":
+		a.policy(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (a *Admin[T]) top(w http.ResponseWriter, r *http.Request) {
+	n := 10
+	if s := r.URL.Query().Get("n"); s != "" {
+		v, err := strconv.Atoi(s)
+		if err != nil || v < 1 {
+			http.Error(w, "bad n", http.StatusBadRequest)
+			return
+		}
+		n = v
+	}
+
+	switch r.URL.Query().Get("by") {
+	case "", "denied":
+		writeJSON(w, a.l.TopDenied(n))
+	case "allowed":
+		writeJSON(w, a.l.TopConsumers(n))
+	default:
+		http.Error(w, "by must be denied or allowed", http.StatusBadRequest)
+	}
+}
+
+func (a *Admin[T]) policy(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, a.l.Policy())
+	case http.MethodPut:
+		// fields missing in body keep current values
+		p := a.l.Policy()
+		err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<16)).Decode(&p)
+		if err == nil {
+			err = p.Validate()
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		a.l.SetPolicy(p)
+		writeJSON(w, a.l.Policy())
+	default:
+		w.Header().Set("Allow", "GET, PUT")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+	}
+}
+
+// get key from id param of r with method m
+func (a *Admin[T]) key(w http.ResponseWriter, r *http.Request, m string) (T, bool) {
+	var id T
+	if !method(w, r, m) {
+		return id, false
+	}
+	s := r.URL.Query().Get("id")
+	if s == "" {
+		http.Error(w, "no id", http.StatusBadRequest)
+		return id, false
+	}
+	id, err := a.parse(s)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return id, false
+	}
+	return id, true
+}
+
+// true if r has method m
+func method(w http.ResponseWriter, r *http.Request, m string) bool {
+	if r.Method == m {
+		return true
+	}
+	w.Header().Set("Allow", m)
+	http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+	return false
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(append(b, '\n'))
+}
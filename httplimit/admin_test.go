@@ -0,0 +1,96 @@
+package httplimit_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ssleert/limiter"
+	"github.com/ssleert/limiter/httplimit"
+)
+
+func TestAdminPolicy(t *testing.T) {
+	start := limiter.Policy{MaxCount: 10, Window: time.Minute, Burst: 20}
+	tests := []struct {
+		name   string
+		method string
+		body   string
+		status int
+		want   limiter.Policy
+	}{
+		{
+			name:   "get",
+			method: http.MethodGet,
+			status: http.StatusOK,
+			want:   start,
+		},
+		{
+			name:   "rate keeps burst",
+			method: http.MethodPut,
+			body:   `{"max_count":5,"window":"1h"}`,
+			status: http.StatusOK,
+			want:   limiter.Policy{MaxCount: 5, Window: time.Hour, Burst: 20},
+		},
+		{
+			name:   "schedule",
+			method: http.MethodPut,
+			body:   `{"schedule":"daily UTC"}`,
+			status: http.StatusOK,
+			// burst is ignored with schedule
+			want: limiter.Policy{MaxCount: 10, Window: time.Minute, Schedule: limiter.Daily(time.UTC)},
+		},
+		{
+			name:   "bad schedule",
+			method: http.MethodPut,
+			body:   `{"schedule":"weekly"}`,
+			status: http.StatusBadRequest,
+			want:   start,
+		},
+		{
+			name:   "negative burst",
+			method: http.MethodPut,
+			body:   `{"burst":-1}`,
+			status: http.StatusBadRequest,
+			want:   start,
+		},
+		{
+			name:   "post",
+			method: http.MethodPost,
+			status: http.StatusMethodNotAllowed,
+			want:   start,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := limiter.New[string](1, 1, 16, 1024, 16)
+			l.SetPolicy(start)
+			a := httplimit.NewAdmin[string](l, httplimit.ParseString, httplimit.TokenAuth("t"))
+
+			r := httptest.NewRequest(tt.method, "/policy", strings.NewReader(tt.body))
+			r.Header.Set("Authorization", "Bearer t")
+			w := httptest.NewRecorder()
+			a.ServeHTTP(w, r)
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+			if got := l.Policy(); got != tt.want {
+				t.Fatalf("Policy() = %+v, want %+v", got, tt.want)
+			}
+			if w.Code != http.StatusOK {
+				return
+			}
+
+			// response shows policy with schedule
+			var got limiter.Policy
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatalf("Unmarshal(%s) err = %v", w.Body, err)
+			}
+			if got != tt.want {
+				t.Fatalf("response = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
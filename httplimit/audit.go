@@ -0,0 +1,42 @@
+package httplimit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/ssleert/limiter"
+)
+
+// audit sink that posts every batch to url
+// as json array, non 2xx response is error
+//
+// if c is nil http.DefaultClient is used
+func WebhookSink[T any](url string, c *http.Client) limiter.AuditSink[T] {
+	if c == nil {
+		c = http.DefaultClient
+	}
+	return limiter.AuditFunc[T](func(ctx context.Context, records []limiter.AuditRecord[T]) error {
+		b, err := json.Marshal(records)
+		if err != nil {
+			return err
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(b))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := c.Do(req)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			return fmt.Errorf("httplimit: webhook returned %s", resp.Status)
+		}
+		return nil
+	})
+}
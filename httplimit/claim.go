@@ -0,0 +1,145 @@
+package httplimit
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultClaimCache = 1024
+
+var ErrInvalidToken = errors.New("httplimit: invalid token")
+
+// key func that limits requests by claim of bearer token
+// so clients behind one NAT get own limits
+type ClaimKey struct {
+	// claim used as key, like sub or client_id
+	Claim string
+
+	// parse and verify token
+	// returns its claims
+	Parse func(token string) (map[string]any, error)
+
+	// key of request without valid token
+	// if nil RemoteIP() is used
+	Fallback func(r *http.Request) string
+
+	// max cached tokens
+	// if <= 0 default is used
+	CacheSize int
+
+	mu    sync.Mutex
+	cache map[string]claimEntry
+}
+
+type claimEntry struct {
+	key string
+	exp time.Time
+}
+
+// make new claim key func
+// parse must verify token signature
+// or use ParseUnverified if it was verified before
+func NewClaimKey(claim string, parse func(token string) (map[string]any, error)) *ClaimKey {
+	return &ClaimKey{
+		Claim: claim,
+		Parse: parse,
+	}
+}
+
+// get key of r
+func (c *ClaimKey) Key(r *http.Request) string {
+	token, ok := bearer(r)
+	if ok {
+		if key, ok := c.claim(token); ok {
+			return key
+		}
+	}
+
+	if c.Fallback != nil {
+		return c.Fallback(r)
+	}
+	return RemoteIP(r)
+}
+
+// get claim of token from cache or parse it
+func (c *ClaimKey) claim(token string) (string, bool) {
+	now := time.Now()
+
+	c.mu.Lock()
+	e, ok := c.cache[token]
+	c.mu.Unlock()
+	if ok {
+		if e.exp.IsZero() || now.Before(e.exp) {
+			return e.key, true
+		}
+		return "", false
+	}
+
+	claims, err := c.Parse(token)
+	if err != nil {
+		return "", false
+	}
+	v, ok := claims[c.Claim]
+	if !ok {
+		return "", false
+	}
+	e = claimEntry{key: fmt.Sprint(v)}
+	if exp, ok := claims["exp"].(float64); ok {
+		e.exp = time.Unix(int64(exp), 0)
+		if !now.Before(e.exp) {
+			return "", false
+		}
+	}
+
+	size := c.CacheSize
+	if size <= 0 {
+		size = defaultClaimCache
+	}
+
+	c.mu.Lock()
+	if c.cache == nil || len(c.cache) >= size {
+		c.cache = make(map[string]claimEntry, size)
+	}
+	c.cache[token] = e
+	c.mu.Unlock()
+
+	return e.key, true
+}
+
+// get bearer token from Authorization header
+func bearer(r *http.Request) (string, bool) {
+	h := r.Header.Get("Authorization")
+	if len(h) < 7 || !strings.EqualFold(h[:7], "bearer ") {
+		return "", false
+	}
+	token := strings.TrimSpace(h[7:])
+	return token, token != ""
+}
+
+// decode claims of jwt without checking its signature
+// use it only if token was verified before
+// like by api gateway
+func ParseUnverified(token string) (map[string]any, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrInvalidToken
+	}
+
+	b, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	var claims map[string]any
+	err = json.Unmarshal(b, &claims)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	return claims, nil
+}
@@ -0,0 +1,33 @@
+package httplimit
+
+import (
+	"math"
+	"net/http"
+)
+
+// Cost func that charges request by its body size
+// in units of unit bytes rounded up, like
+// limiter.TryBytes() with same byte unit
+//
+// bodies of unknown length like chunked ones
+// cost unknown units and requests without
+// body cost 1, unit < 1 means 1 byte
+func ContentLength(unit int64, unknown int) func(r *http.Request) int {
+	if unit < 1 {
+		unit = 1
+	}
+	return func(r *http.Request) int {
+		n := r.ContentLength
+		switch {
+		case n < 0:
+			return unknown
+		case n == 0:
+			return 1
+		}
+		u := (n + unit - 1) / unit
+		if u > math.MaxInt32 {
+			u = math.MaxInt32
+		}
+		return int(u)
+	}
+}
@@ -0,0 +1,60 @@
+package httplimit
+
+import (
+	"html/template"
+	"net/http"
+)
+
+var debugPage = template.Must(template.New("debug").Parse(`<!DOCTYPE html>
+<html>
+<head><title>limiter</title></head>
+<body>
+<h1>limiter</h1>
+<p>
+allowed {{.Stats.Allowed}} |
+denied {{.Stats.Denied}} |
+keys {{.Stats.Keys}}{{if .Stats.MaxKeys}} of {{.Stats.MaxKeys}} ({{printf "%.1f" .Fill}}% full){{end}} |
+unique keys ~{{.Stats.UniqueKeys}} |
+evicted {{.Stats.Evicted}} |
+cleaned {{.Stats.Cleaned}}
+</p>
+<h2>top denied</h2>
+<table>
+<tr><th>key</th><th>denied</th></tr>
+{{range .Denied}}<tr><td>{{.Key}}</td><td>{{.Count}}</td></tr>
+{{end}}</table>
+<h2>top consumers</h2>
+<table>
+<tr><th>key</th><th>allowed</th></tr>
+{{range .Consumers}}<tr><td>{{.Key}}</td><td>{{.Count}}</td></tr>
+{{end}}</table>
+<h2>sampled denials</h2>
+<p>{{len .Samples}} of {{.Seen}} denials</p>
+<table>
+<tr><th>time</th><th>key</th><th>meta</th></tr>
+{{range .Samples}}<tr><td>{{.Time.Format "2006-01-02 15:04:05"}}</td><td>{{.Key}}</td><td>{{.Meta}}</td></tr>
+{{end}}</table>
+</body>
+</html>
+`))
+
+// page with stats, top keys and sampled denials
+func (a *Admin[T]) debug(w http.ResponseWriter) {
+	st := a.l.Stats()
+	samples, seen := a.l.DeniedSamples()
+
+	var fill float64
+	if st.MaxKeys > 0 {
+		fill = 100 * float64(st.Keys) / float64(st.MaxKeys)
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	debugPage.Execute(w, map[string]any{
+		"Stats":     st,
+		"Fill":      fill,
+		"Denied":    a.l.TopDenied(10),
+		"Consumers": a.l.TopConsumers(10),
+		"Samples":   samples,
+		"Seen":      seen,
+	})
+}
@@ -0,0 +1,78 @@
+package httplimit
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ssleert/limiter"
+)
+
+// limiter that knows policy of key
+// every limiter.Limiter[T] implements it
+type PolicyGetter[T any] interface {
+	KeyPolicy(id T) (limiter.Policy, bool)
+}
+
+// limiter that knows state of key
+// every limiter.Limiter[T] implements it
+type Stater[T any] interface {
+	KeyStats(id T) (limiter.KeyState, bool)
+}
+
+// set rate limit headers of key
+//
+// RateLimit-Policy describes policy of key like
+// "100;w=60" so clients can find out their limits
+// it is set if l implements PolicyGetter
+//
+// RateLimit-Limit, RateLimit-Remaining and
+// RateLimit-Reset in seconds until window ends
+// are set if l implements Stater and key is tracked
+func WriteRateLimit[T any](w http.ResponseWriter, l Limiter[T], id T) {
+	h := w.Header()
+
+	limit := -1
+	if pg, ok := l.(PolicyGetter[T]); ok {
+		p, _ := pg.KeyPolicy(id)
+		h.Set("RateLimit-Policy", FormatPolicy(p))
+		limit = p.MaxCount
+		if p.Burst > 0 {
+			limit = p.Burst
+		}
+	}
+
+	sl, ok := l.(Stater[T])
+	if !ok {
+		return
+	}
+	st, ok := sl.KeyStats(id)
+	if !ok {
+		return
+	}
+	if limit < 0 {
+		limit = st.Count + st.Remaining
+	}
+	// last seen is time of this request by limiter clock
+	reset := int64((st.ResetAt.Sub(st.LastSeen) + time.Second - 1) / time.Second)
+	if reset < 0 {
+		reset = 0
+	}
+	h.Set("RateLimit-Limit", strconv.Itoa(limit))
+	h.Set("RateLimit-Remaining", strconv.Itoa(st.Remaining))
+	h.Set("RateLimit-Reset", strconv.FormatInt(reset, 10))
+}
+
+// format policy as RateLimit-Policy value
+// like "100;w=60" or "100;w=60;burst=500"
+func FormatPolicy(p limiter.Policy) string {
+	b := make([]byte, 0, 32)
+	b = strconv.AppendInt(b, int64(p.MaxCount), 10)
+	b = append(b, ";w="...)
+	b = strconv.AppendInt(b, int64(p.Window/time.Second), 10)
+	if p.Burst > 0 {
+		b = append(b, ";burst="...)
+		b = strconv.AppendInt(b, int64(p.Burst), 10)
+	}
+	return string(b)
+}
@@ -0,0 +1,380 @@
+/*
+net/http middleware for limiter
+*/
+package httplimit
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// limiter used by middleware
+// every limiter.Limiter[T] implements it
+type Limiter[T any] interface {
+	TryN(id T, n int) bool
+}
+
+// limiter that can give units back
+// every limiter.Limiter[T] implements it
+type Refunder[T any] interface {
+	Refund(id T, n int) bool
+}
+
+// http middleware that limits requests by key
+type Middleware[T any] struct {
+	l      Limiter[T]
+	routes []route[T]
+
+	// key of request
+	Key func(r *http.Request) T
+
+	// if returns true request bypasses limiter
+	// and no key is made for it
+	// for health checks, preflights, static files
+	Skip func(r *http.Request) bool
+
+	// resolves policy of key before limiting
+	// on error key keeps policy it has
+	// own policy of key set by SetKeyPolicy() wins
+	//
+	// limiter must implement PolicySetter
+	// nil disables it
+	Tiers TierResolver[T]
+
+	// called when Tiers fails
+	// if nil errors are ignored
+	TierError func(r *http.Request, err error)
+
+	// units of limit request costs
+	// if nil every request costs 1
+	// requests with cost <= 0 are not limited
+	Cost func(r *http.Request) int
+
+	// handler for denied requests
+	// if nil DeniedStatus is returned
+	// Retry-After is set before it is called
+	// if limiter implements Retrier
+	Denied http.Handler
+
+	// status of denied requests without Denied handler
+	// like 503 for load shedding style limits
+	// some clients retry 429 and 503 differently
+	// if 0 429 Too Many Requests is used
+	DeniedStatus int
+
+	// if returns true for response status
+	// request cost is given back to key
+	// so own outages don't eat client quotas
+	//
+	// limiter must implement Refunder
+	// nil disables refunds
+	Refund func(status int) bool
+
+	// set RateLimit-Policy and other rate limit
+	// headers on every limited response
+	// see WriteRateLimit()
+	Headers bool
+}
+
+// Refund func for all 5xx statuses
+func Refund5xx(status int) bool {
+	return status >= 500 && status <= 599
+}
+
+// Refund func for given statuses
+func RefundStatus(statuses ...int) func(status int) bool {
+	set := make(map[int]struct{}, len(statuses))
+	for _, s := range statuses {
+		set[s] = struct{}{}
+	}
+	return func(status int) bool {
+		_, ok := set[status]
+		return ok
+	}
+}
+
+// Skip func for OPTIONS preflight requests
+func SkipPreflight(r *http.Request) bool {
+	return r.Method == http.MethodOptions
+}
+
+// Skip func for requests with one of path prefixes
+func SkipPrefix(prefixes ...string) func(r *http.Request) bool {
+	return func(r *http.Request) bool {
+		for _, p := range prefixes {
+			if strings.HasPrefix(r.URL.Path, p) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Skip func that skips if any of fs skips
+func SkipAny(fs ...func(r *http.Request) bool) func(r *http.Request) bool {
+	return func(r *http.Request) bool {
+		for _, f := range fs {
+			if f(r) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// make new middleware for l with key func
+// l can be nil if only routes are limited
+func New[T any](l Limiter[T], key func(r *http.Request) T) *Middleware[T] {
+	return &Middleware[T]{
+		l:   l,
+		Key: key,
+	}
+}
+
+type route[T any] struct {
+	method string
+	path   string
+	l      Limiter[T]
+	// 0 means m.DeniedStatus
+	status int
+}
+
+// use l for requests with method and path
+// instead of middleware limiter
+//
+// empty method matches any method
+// path ending with / matches all paths under it
+// other paths match only themselves
+// most specific route wins
+//
+//	m.Route("POST", "/login", login).
+//		Route("GET", "/search", search)
+func (m *Middleware[T]) Route(method, path string, l Limiter[T]) *Middleware[T] {
+	return m.RouteStatus(method, path, l, 0)
+}
+
+// like Route() but denied requests of route get status
+// instead of DeniedStatus, 0 means DeniedStatus
+func (m *Middleware[T]) RouteStatus(
+	method,
+	path string,
+	l Limiter[T],
+	status int,
+) *Middleware[T] {
+	m.routes = append(m.routes, route[T]{
+		method: method,
+		path:   path,
+		l:      l,
+		status: status,
+	})
+	return m
+}
+
+// get limiter for r and status of its denials
+func (m *Middleware[T]) limiter(r *http.Request) (Limiter[T], int) {
+	var (
+		l      = m.l
+		status = m.DeniedStatus
+		best   = -1
+	)
+	for _, rt := range m.routes {
+		if rt.method != "" && rt.method != r.Method {
+			continue
+		}
+		if !matchPath(rt.path, r.URL.Path) {
+			continue
+		}
+
+		// longer path is more specific
+		// and route with method beats any method
+		score := len(rt.path) * 2
+		if rt.method != "" {
+			score++
+		}
+		if score > best {
+			l = rt.l
+			status = rt.status
+			if status == 0 {
+				status = m.DeniedStatus
+			}
+			best = score
+		}
+	}
+	if status == 0 {
+		status = http.StatusTooManyRequests
+	}
+	return l, status
+}
+
+func matchPath(pattern, path string) bool {
+	if strings.HasSuffix(pattern, "/") {
+		return strings.HasPrefix(path, pattern)
+	}
+	return pattern == path
+}
+
+// wrap next with limiter
+func (m *Middleware[T]) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if m.Skip != nil && m.Skip(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		cost := 1
+		if m.Cost != nil {
+			cost = m.Cost(r)
+		}
+		if cost <= 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		l, status := m.limiter(r)
+		if l == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		key := m.Key(r)
+		m.tier(r, l, key)
+		ok := l.TryN(key, cost)
+		if m.Headers {
+			WriteRateLimit(w, l, key)
+		}
+		if ok {
+			ref, ok := l.(Refunder[T])
+			if m.Refund == nil || !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			sw := &statusWriter{ResponseWriter: w}
+			next.ServeHTTP(sw, r)
+			if m.Refund(sw.status()) {
+				ref.Refund(key, cost)
+			}
+			return
+		}
+
+		WriteRetryAfter(w, l, key, cost)
+		if m.Denied != nil {
+			m.Denied.ServeHTTP(w, r)
+			return
+		}
+		http.Error(w, http.StatusText(status), status)
+	})
+}
+
+// set policy of key resolved by m.Tiers
+func (m *Middleware[T]) tier(r *http.Request, l Limiter[T], key T) {
+	if m.Tiers == nil {
+		return
+	}
+	ps, ok := l.(PolicySetter[T])
+	if !ok {
+		return
+	}
+
+	p, err := m.Tiers.Tier(r.Context(), key)
+	if err != nil {
+		if m.TierError != nil {
+			m.TierError(r, err)
+		}
+		return
+	}
+	ps.ResolvePolicy(key, p)
+}
+
+// ipv6 clients get whole /64 by default
+// one host can have many addresses in it
+// like with slaac privacy addresses
+const DefaultIPv6Prefix = 64
+
+// key func with ip of client from r.RemoteAddr
+// ipv6 address is cut to DefaultIPv6Prefix
+func RemoteIP(r *http.Request) string {
+	return prefix(remoteHost(r), 0, DefaultIPv6Prefix)
+}
+
+// key func with network of client from r.RemoteAddr
+// ipv4 address is cut to v4 bits and ipv6 one to v6 bits
+// like 1.2.3.0/24, so clients rotating
+// addresses in one subnet share limit
+//
+// v4 <= 0 keeps full ipv4 address
+// v6 <= 0 uses DefaultIPv6Prefix
+// and 128 keeps full ipv6 address
+func RemotePrefix(v4, v6 int) func(r *http.Request) string {
+	if v6 <= 0 {
+		v6 = DefaultIPv6Prefix
+	}
+	return func(r *http.Request) string {
+		return prefix(remoteHost(r), v4, v6)
+	}
+}
+
+func remoteHost(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// cut ip address to network of bits
+func prefix(ip string, v4, v6 int) string {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return ip
+	}
+	addr = addr.Unmap()
+
+	bits := v6
+	if addr.Is4() {
+		bits = v4
+	}
+	if bits <= 0 || bits >= addr.BitLen() {
+		return addr.String()
+	}
+
+	p, err := addr.Prefix(bits)
+	if err != nil {
+		return addr.String()
+	}
+	return p.String()
+}
+
+// response writer that remembers status
+type statusWriter struct {
+	http.ResponseWriter
+	code int
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	if w.code == 0 {
+		w.code = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	if w.code == 0 {
+		w.code = http.StatusOK
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *statusWriter) status() int {
+	if w.code == 0 {
+		return http.StatusOK
+	}
+	return w.code
+}
+
+// for http.ResponseController
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,48 @@
+package httplimit
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// waits longer than it are sent as http date
+// like end of monthly quota or long ban
+const maxRetryAfter = 24 * time.Hour
+
+// limiter that knows when key can retry
+// every limiter.Limiter[T] implements it
+type Retrier[T any] interface {
+	RetryAfter(id T, n int) (time.Duration, bool)
+}
+
+// set Retry-After header for key that was denied n units
+// from limiter state, bans and cooldowns included
+// wait is in seconds and in http date if it is over a day
+//
+// returns false and sets nothing if l is not
+// Retrier or key can never spend n units
+func WriteRetryAfter[T any](w http.ResponseWriter, l Limiter[T], id T, n int) bool {
+	rt, ok := l.(Retrier[T])
+	if !ok {
+		return false
+	}
+	d, ok := rt.RetryAfter(id, n)
+	if !ok {
+		return false
+	}
+
+	if d > maxRetryAfter {
+		at := time.Now().Add(d)
+		w.Header().Set("Retry-After", at.UTC().Format(http.TimeFormat))
+		return true
+	}
+
+	secs := int64((d + time.Second - 1) / time.Second)
+	if secs < 1 {
+		// denied now, so at least next second
+		secs = 1
+	}
+	w.Header().Set("Retry-After", strconv.FormatInt(secs, 10))
+	return true
+}
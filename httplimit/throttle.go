@@ -0,0 +1,97 @@
+package httplimit
+
+import (
+	"context"
+	"net/http"
+)
+
+// limiter that waits for units of key
+// every limiter.Limiter[T] implements it
+type Waiter[T any] interface {
+	WaitN(ctx context.Context, id T, n int) error
+}
+
+// response writer that waits for limiter
+// before every chunk of body it writes
+type throttleWriter[T any] struct {
+	http.ResponseWriter
+	ctx   context.Context
+	l     Waiter[T]
+	id    T
+	unit  int
+	chunk int
+}
+
+// wrap w so body is written in chunks of chunk bytes
+// and each of them waits for its size in units of
+// unit bytes from l, so big downloads of one key
+// can't take whole egress
+//
+// chunk must fit limit of key, write fails with
+// wait error when ctx is done or key can't get chunk
+// unit < 1 means 1 byte and chunk < 1 means unit
+func ThrottleWriter[T any](
+	ctx context.Context,
+	w http.ResponseWriter,
+	l Waiter[T],
+	id T,
+	unit,
+	chunk int,
+) http.ResponseWriter {
+	if unit < 1 {
+		unit = 1
+	}
+	if chunk < 1 {
+		chunk = unit
+	}
+	return &throttleWriter[T]{
+		ResponseWriter: w,
+		ctx:            ctx,
+		l:              l,
+		id:             id,
+		unit:           unit,
+		chunk:          chunk,
+	}
+}
+
+func (w *throttleWriter[T]) Write(b []byte) (int, error) {
+	var written int
+	for len(b) > 0 {
+		part := b
+		if len(part) > w.chunk {
+			part = part[:w.chunk]
+		}
+		units := (len(part) + w.unit - 1) / w.unit
+		if err := w.l.WaitN(w.ctx, w.id, units); err != nil {
+			return written, err
+		}
+		n, err := w.ResponseWriter.Write(part)
+		written += n
+		if err != nil {
+			return written, err
+		}
+		b = b[len(part):]
+	}
+	return written, nil
+}
+
+// for http.ResponseController
+func (w *throttleWriter[T]) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// middleware that throttles response bodies by key
+// see ThrottleWriter()
+func Throttle[T any](
+	l Waiter[T],
+	key func(r *http.Request) T,
+	unit,
+	chunk int,
+) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			tw := ThrottleWriter(r.Context(), w, l, key(r), unit, chunk)
+			next.ServeHTTP(tw, r)
+		})
+	}
+}
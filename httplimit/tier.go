@@ -0,0 +1,106 @@
+package httplimit
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/ssleert/limiter"
+)
+
+const defaultTierCache = 4096
+
+// maps key like api key to its policy
+// like plan of customer from billing service
+type TierResolver[T any] interface {
+	Tier(ctx context.Context, key T) (limiter.Policy, error)
+}
+
+// func that implements TierResolver
+type TierFunc[T any] func(ctx context.Context, key T) (limiter.Policy, error)
+
+func (f TierFunc[T]) Tier(ctx context.Context, key T) (limiter.Policy, error) {
+	return f(ctx, key)
+}
+
+// limiter that policies can be set on
+// every limiter.Limiter[T] implements it
+//
+// policy is kept only while key has entry
+// and setting same policy again is cheap
+type PolicySetter[T any] interface {
+	ResolvePolicy(id T, p limiter.Policy)
+}
+
+// TierResolver that caches results of other one for ttl
+// errors are cached too so failing backend
+// is not called on every request
+type TierCache[T comparable] struct {
+	r    TierResolver[T]
+	ttl  time.Duration
+	size int
+
+	mu sync.Mutex
+	m  map[T]tierEntry
+}
+
+type tierEntry struct {
+	p   limiter.Policy
+	err error
+	exp time.Time
+}
+
+// make new cache for r
+// if size <= 0 default is used
+func NewTierCache[T comparable](
+	r TierResolver[T],
+	ttl time.Duration,
+	size int,
+) *TierCache[T] {
+	if size <= 0 {
+		size = defaultTierCache
+	}
+	return &TierCache[T]{
+		r:    r,
+		ttl:  ttl,
+		size: size,
+		m:    make(map[T]tierEntry),
+	}
+}
+
+func (c *TierCache[T]) Tier(ctx context.Context, key T) (limiter.Policy, error) {
+	now := time.Now()
+
+	c.mu.Lock()
+	e, ok := c.m[key]
+	c.mu.Unlock()
+	if ok && now.Before(e.exp) {
+		return e.p, e.err
+	}
+
+	p, err := c.r.Tier(ctx, key)
+	if err == nil {
+		err = p.Validate()
+	}
+	e = tierEntry{
+		p:   p,
+		err: err,
+		exp: now.Add(c.ttl),
+	}
+
+	c.mu.Lock()
+	if len(c.m) >= c.size {
+		c.m = make(map[T]tierEntry, c.size)
+	}
+	c.m[key] = e
+	c.mu.Unlock()
+
+	return p, err
+}
+
+// remove cached key so it is resolved again
+func (c *TierCache[T]) Forget(key T) {
+	c.mu.Lock()
+	delete(c.m, key)
+	c.mu.Unlock()
+}
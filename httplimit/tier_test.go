@@ -0,0 +1,105 @@
+package httplimit_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ssleert/limiter"
+	"github.com/ssleert/limiter/httplimit"
+	"github.com/ssleert/limiter/limitertest"
+)
+
+var errBilling = errors.New("billing is down")
+
+func TestTier(t *testing.T) {
+	plans := map[string]limiter.Policy{
+		"free": {MaxCount: 2, Window: time.Minute},
+		"pro":  {MaxCount: 5, Window: time.Minute},
+	}
+	tests := []struct {
+		name string
+		// plan of key on each request
+		// unknown plan fails resolver
+		plans []string
+		// status of each request
+		want []int
+		// true if key has resolved policy after requests
+		known bool
+	}{
+		{
+			name:  "free",
+			plans: []string{"free", "free", "free"},
+			want:  []int{200, 200, 429},
+			known: true,
+		},
+		{
+			name:  "upgrade",
+			plans: []string{"free", "free", "pro", "pro", "pro", "pro"},
+			want:  []int{200, 200, 200, 200, 200, 429},
+			known: true,
+		},
+		{
+			name:  "error keeps policy",
+			plans: []string{"free", "down", "down"},
+			want:  []int{200, 200, 429},
+			known: true,
+		},
+		{
+			name:  "error without policy",
+			plans: []string{"down", "down", "down"},
+			want:  []int{200, 200, 200},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := limiter.New[string](10, 60, 16, 1024, 16)
+			limitertest.Use(l)
+
+			var plan string
+			var errs, wantErrs int
+			m := httplimit.New[string](l, func(r *http.Request) string { return "k" })
+			m.Tiers = httplimit.TierFunc[string](func(ctx context.Context, key string) (limiter.Policy, error) {
+				p, ok := plans[plan]
+				if !ok {
+					return limiter.Policy{}, errBilling
+				}
+				return p, nil
+			})
+			m.TierError = func(r *http.Request, err error) {
+				if !errors.Is(err, errBilling) {
+					t.Errorf("TierError() err = %v, want %v", err, errBilling)
+				}
+				errs++
+			}
+			h := m.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+			for i, p := range tt.plans {
+				plan = p
+				if _, ok := plans[p]; !ok {
+					wantErrs++
+				}
+				w := httptest.NewRecorder()
+				h.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+				if w.Code != tt.want[i] {
+					t.Fatalf("request %d with plan %s: status = %d, want %d", i, p, w.Code, tt.want[i])
+				}
+			}
+			if errs != wantErrs {
+				t.Fatalf("TierError() calls = %d, want %d", errs, wantErrs)
+			}
+			if _, ok := l.KeyPolicy("k"); ok != tt.known {
+				t.Fatalf("KeyPolicy() known = %v, want %v", ok, tt.known)
+			}
+
+			// tier policy lives only as long as key entry
+			l.Reset("k")
+			if _, ok := l.KeyPolicy("k"); ok {
+				t.Fatal("KeyPolicy() known after Reset()")
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package limiter
+
+import (
+	"context"
+	"time"
+)
+
+// common methods of all limiters in package
+// so apps can inject limiter and swap it
+// with Noop in tests and local dev
+type Interface[T comparable] interface {
+	Try(id T) bool
+	TryN(id T, n int) bool
+	Wait(ctx context.Context, id T) error
+	Reset(id T) bool
+}
+
+var (
+	_ Interface[string] = (*Limiter[string])(nil)
+	_ Interface[string] = (*Sketch[string])(nil)
+	_ Interface[string] = (*PNCounter[string])(nil)
+	_ Interface[string] = (*Distributed[string])(nil)
+	_ Interface[string] = (*LocalBurst[string])(nil)
+	_ Interface[string] = (*Groups[string])(nil)
+	_ Interface[string] = Noop[string]{}
+)
+
+// limiter that allows every action
+type Noop[T comparable] struct{}
+
+func (Noop[T]) Try(id T) bool {
+	return true
+}
+
+func (Noop[T]) TryN(id T, n int) bool {
+	return true
+}
+
+func (Noop[T]) Wait(ctx context.Context, id T) error {
+	return ctx.Err()
+}
+
+// always false as nothing is tracked
+func (Noop[T]) Reset(id T) bool {
+	return false
+}
+
+// call try until it returns true at starts of
+// windows aligned to unix epoch or until ctx is done
+// windows are taken from clock now
+func waitWindows(
+	ctx context.Context,
+	window int64,
+	now func() time.Time,
+	try func() bool,
+) error {
+	for {
+		if try() {
+			return nil
+		}
+		timeNow := now()
+		next := timeNow.Unix() - timeNow.Unix()%window + window
+		t := time.NewTimer(time.Unix(next, 0).Sub(timeNow))
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package limiter
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ssleert/mu"
+)
+
+var ErrInvariant = errors.New("limiter: invariant violated")
+
+type invariants struct {
+	on        bool
+	tolerance int
+	// nil means panic
+	onViolation func(err error)
+}
+
+// check internal invariants on every Try() for debugging
+// key count is within limit plus tolerance, key window
+// never moves back and map is within max keys when
+// new keys are denied or evicted on full map
+//
+// violations are passed to onViolation as ErrInvariant
+// errors and nil onViolation panics with them
+// it is slow, so use it only in tests and staging
+func (l *Limiter[T]) SetInvariants(on bool, tolerance int, onViolation func(err error)) {
+	defer l.moveBack()
+	mu.ExecMutex(&l.mu, func() {
+		l.inv = invariants{
+			on:          on,
+			tolerance:   tolerance,
+			onViolation: onViolation,
+		}
+	})
+}
+
+// check entry of id after try() changed prev
+//
+// l.mu must be held
+func (l *Limiter[T]) checkInvariants(id T, prev action, had bool, o outcome[T]) error {
+	a, ok := l.get(id)
+	if !ok {
+		return nil
+	}
+	p := l.policyOf(id)
+	tol := l.inv.tolerance
+
+	if p.burst > 0 {
+		if a.tokens > float64(p.burst+tol) {
+			return fmt.Errorf("%w: key %v has %.2f tokens over burst %d",
+				ErrInvariant, id, a.tokens, p.burst)
+		}
+		if a.tokens < float64(-p.overdraft-tol) {
+			return fmt.Errorf("%w: key %v has %.2f tokens under overdraft %d",
+				ErrInvariant, id, a.tokens, p.overdraft)
+		}
+	} else if limit := p.maxCount + a.carry + p.overdraft; a.count > limit+tol {
+		return fmt.Errorf("%w: key %v count %d over limit %d",
+			ErrInvariant, id, a.count, limit)
+	}
+
+	if had && a.deltaTime < prev.deltaTime {
+		return fmt.Errorf("%w: key %v window moved back from %d to %d",
+			ErrInvariant, id, prev.deltaTime, a.deltaTime)
+	}
+	if a.lastTime < a.firstTime {
+		return fmt.Errorf("%w: key %v last seen %d before first seen %d",
+			ErrInvariant, id, a.lastTime, a.firstTime)
+	}
+
+	bounded := l.fullPolicy == FullDeny || o.evicted
+	if l.maxMapLen > 0 && bounded && len(l.m) > l.maxMapLen {
+		return fmt.Errorf("%w: %d keys over max %d",
+			ErrInvariant, len(l.m), l.maxMapLen)
+	}
+	return nil
+}
+
+// report invariant violation
+func (inv invariants) report(err error) {
+	if inv.onViolation == nil {
+		panic(err)
+	}
+	inv.onViolation(err)
+}
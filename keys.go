@@ -0,0 +1,94 @@
+package limiter
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/ssleert/mu"
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
+)
+
+// sorted copy of keys paged by Keys()
+type keyList[T any] struct {
+	mu   sync.Mutex
+	keys *[]T
+}
+
+// page of up to limit tracked keys sorted in
+// ascending order, starting after cursor key
+// nil cursor starts from smallest key
+//
+// returns cursor of next page or nil after last one
+// keys added or removed between calls may be missed
+// but no key is listed twice
+//
+// first page takes sorted copy of keys and next pages
+// find cursor in it, so listing sorts keys once
+// and lock is held only to copy keys and check page
+// copy is dropped after last page
+func (l *Limiter[T]) Keys(cursor *T, limit int) ([]T, *T) {
+	if limit <= 0 {
+		return nil, cursor
+	}
+
+	kl := &l.keyList
+	kl.mu.Lock()
+	if cursor == nil || kl.keys == nil {
+		keys := l.sortedKeys()
+		kl.keys = &keys
+	}
+	keys := kl.keys
+	kl.mu.Unlock()
+
+	page, more := l.page(*keys, cursor, limit)
+	if !more {
+		kl.mu.Lock()
+		if kl.keys == keys {
+			kl.keys = nil
+		}
+		kl.mu.Unlock()
+		return page, nil
+	}
+	next := page[len(page)-1]
+	return page, &next
+}
+
+// sorted copy of all tracked keys
+func (l *Limiter[T]) sortedKeys() []T {
+	var keys []T
+	mu.ExecRWMutex(&l.mu, func() {
+		keys = make([]T, 0, len(l.m)+len(l.hotKeys()))
+		keys = append(keys, maps.Keys(l.m)...)
+		for id := range l.hotKeys() {
+			keys = append(keys, id)
+		}
+	})
+	slices.Sort(keys)
+	return keys
+}
+
+// up to limit keys after cursor in sorted keys
+// that are still tracked and true if keys have more
+func (l *Limiter[T]) page(keys []T, cursor *T, limit int) ([]T, bool) {
+	i := 0
+	if cursor != nil {
+		i = sort.Search(len(keys), func(i int) bool {
+			return keys[i] > *cursor
+		})
+	}
+
+	if n := len(keys) - i; limit > n {
+		limit = n
+	}
+	page := make([]T, 0, limit)
+	mu.ExecRWMutex(&l.mu, func() {
+		for ; i < len(keys) && len(page) < limit; i++ {
+			id := keys[i]
+			if _, ok := l.m[id]; ok || l.hotOf(id) != nil {
+				page = append(page, id)
+			}
+		}
+	})
+	return page, i < len(keys)
+}
@@ -0,0 +1,67 @@
+package limiter_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/ssleert/limiter"
+	"golang.org/x/exp/slices"
+)
+
+func TestKeys(t *testing.T) {
+	tests := []struct {
+		name   string
+		keys   int
+		limit  int
+		remove []int
+		pages  int
+	}{
+		{"empty", 0, 3, nil, 1},
+		{"one page", 3, 10, nil, 1},
+		{"exact pages", 6, 3, nil, 2},
+		{"last short", 7, 3, nil, 3},
+		{"removed", 7, 3, []int{4, 5}, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := limiter.New[string](10, 60, 16, 1024, 16)
+			var want []string
+			for i := 0; i < tt.keys; i++ {
+				id := fmt.Sprintf("k%02d", i)
+				l.Try(id)
+				if !slices.Contains(tt.remove, i) {
+					want = append(want, id)
+				}
+			}
+
+			var (
+				got    []string
+				cursor *string
+				pages  int
+			)
+			for {
+				page, next := l.Keys(cursor, tt.limit)
+				pages++
+				if len(page) > tt.limit {
+					t.Fatalf("page %d has %d keys, limit %d", pages, len(page), tt.limit)
+				}
+				got = append(got, page...)
+				if pages == 1 {
+					for _, i := range tt.remove {
+						l.Reset(fmt.Sprintf("k%02d", i))
+					}
+				}
+				if next == nil {
+					break
+				}
+				cursor = next
+			}
+			if !slices.Equal(got, want) {
+				t.Fatalf("Keys() listed %v, want %v", got, want)
+			}
+			if pages != tt.pages {
+				t.Fatalf("Keys() took %d pages, want %d", pages, tt.pages)
+			}
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package limiter
+
+import (
+	"context"
+	"runtime/pprof"
+)
+
+// set name of limiter used in pprof labels
+// of its goroutines, so services with many limiters
+// can tell them apart in cpu and goroutine profiles
+//
+// goroutines get labels limiter=name and op like
+// clean, janitor, checkpoint, audit, govern or watch
+// PNCounter loops get labels region and op
+func (l *Limiter[T]) SetName(name string) {
+	l.name.Store(&name)
+}
+
+// get name of limiter set by SetName()
+func (l *Limiter[T]) Name() string {
+	if name := l.name.Load(); name != nil {
+		return *name
+	}
+	return ""
+}
+
+// run f with pprof labels of limiter operation
+func (l *Limiter[T]) do(ctx context.Context, op string, f func(ctx context.Context)) {
+	pprof.Do(ctx, pprof.Labels("limiter", l.Name(), "op", op), f)
+}
+
+// set pprof labels of limiter operation on current goroutine
+// returned func restores labels of ctx
+func (l *Limiter[T]) label(ctx context.Context, op string) (context.Context, func()) {
+	return setLabels(ctx, "limiter", l.Name(), "op", op)
+}
+
+func setLabels(ctx context.Context, args ...string) (context.Context, func()) {
+	labeled := pprof.WithLabels(ctx, pprof.Labels(args...))
+	pprof.SetGoroutineLabels(labeled)
+	return labeled, func() {
+		pprof.SetGoroutineLabels(ctx)
+	}
+}
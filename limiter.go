@@ -5,8 +5,10 @@ also can be used as action limiter
 package limiter
 
 import (
+	"context"
+	"errors"
 	"github.com/ssleert/mu"
-	"runtime"
+	"math/rand"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -22,8 +24,8 @@ const (
 	// before clean up
 	defaultMaxMapLen = 16384
 
-	// how many objects Cleanup()
-	// goroutine can clean at once
+	// how many objects Clean()
+	// can check at once
 	defaultCleanAtOnce = 20
 
 	// default time of actions
@@ -36,19 +38,253 @@ const (
 	Default = -1
 )
 
+// returned when FullDeny keeps new key out of full map
+var ErrMapFull = errors.New("limiter: map is full")
+
+// what Try() does with new key
+// when map already has maxMapLen entries
+type FullPolicy int
+
+const (
+	// admit new key and let map grow
+	FullAllow FullPolicy = iota
+
+	// refuse new keys until clean up frees space
+	FullDeny
+
+	// remove oldest of sampled entries
+	// to make space for new key
+	FullEvict
+)
+
 type action struct {
+	// start of current window
 	deltaTime int64
-	count     int
+	// time of last Try() for this key
+	lastTime int64
+	// time of first Try() for this key
+	firstTime int64
+	// time of last denied Try(), 0 if never
+	lastDenied int64
+	count      int
+	// denied Try() calls in current window
+	denies int
+	// denied Try() calls in a row
+	streak int
+
+	// denials since violationStart
+	violations     int
+	violationStart int64
+	bannedUntil    int64
+	// count of recent bans, see SetBanBackoff()
+	banLevel int
+	// every Try() before it is denied and extends it
+	cooldownUntil int64
+
+	// token bucket of burst policy
+	tokens   float64
+	refilled int64
+
+	// overdraft repaid from next window
+	debt int
+	// unused actions of previous window
+	carry int
+	// extra seconds added to every window of key
+	jitter int64
+	// idle ttl for this key
+	// 0 means limiter idle ttl
+	ttl int64
+	// fair share period key was last counted active in
+	fairPeriod int64
+	// unix nanoseconds before next paced action
+	pacedUntil int64
+
+	// spike detector interval and its calls
+	spikeStart int64
+	spikeCount int
+	// ewma of calls per interval
+	spikeAvg float64
+	// spike was reported in this interval
+	spiked bool
 }
 
 type Limiter[T constraints.Ordered] struct {
-	m           map[T]action
+	m           map[T]packed
 	mu          sync.RWMutex
 	maxTime     int64
 	maxCount    int
+	burst       int
+	schedule    Schedule
 	maxMapLen   int
 	cleanAtOnce int
 	cleaning    atomic.Bool
+
+	// if false Try() never spawns clean up goroutines
+	autoClean bool
+	// entries checked by every Try()
+	tryClean int
+	// goroutines of one clean up step
+	cleanWorkers int
+	// see SetShrink()
+	shrink shrink
+	// see Usage
+	usage usage
+	// see SetMeta()
+	meta map[T]any
+
+	// what to do with new keys when map is full
+	fullPolicy FullPolicy
+
+	// if true Try() always returns true
+	dryRun bool
+
+	// own policies of keys
+	keyPolicies map[T]policy
+	// limit multipliers of keys
+	keyScales map[T]float64
+
+	// policies from resolver for present keys
+	resolved map[T]policy
+
+	ban banPolicy
+
+	// seconds of cooldown after denial
+	cooldown int64
+
+	warmup warmup
+
+	// part of limit each priority can use
+	prio priorities
+
+	fair fairShare[T]
+
+	// actions key can take over its limit
+	overdraft int
+
+	// part of unused actions moved to next window
+	carryPart float64
+	carryMax  int
+
+	// max extra seconds added to key windows
+	jitter int64
+	// seeded rand of deterministic mode
+	// and its seed for Clone()
+	rng  *rand.Rand
+	seed int64
+
+	// spread actions evenly over window
+	pacing bool
+
+	allowlist map[T]struct{}
+	// copy on write, read without lock
+	// by fast paths, see denied()
+	denylist atomic.Pointer[map[T]struct{}]
+
+	// seconds without Try() before entry can be removed
+	// entries are never removed before their window ends
+	idleTTL int64
+
+	// see Keys()
+	keyList keyList[T]
+
+	// keys snapshot and scan position
+	// shared by Clean() and CleanContext()
+	cleanKeys []T
+	cleanPos  int
+	// while cleanLog is on next snapshot is made of keys
+	// that survived scan and keys added during it
+	// so map is copied only to start the log
+	cleanKept []T
+	cleanNew  []T
+	cleanLog  bool
+
+	// units booked by ReserveAt()
+	bookings map[T][]booking
+	bookSeq  uint64
+
+	// made by Namespace()
+	namespaces map[string]*Limiter[T]
+	// name and parent of namespace, set once
+	nsName string
+	parent *Limiter[T]
+	// default policy was set on namespace
+	// so it doesn't follow parent one
+	ownPolicy bool
+	// limiter that isolated key belongs to
+	owner *Limiter[T]
+
+	// duration of last Clean() or CleanContext() run in ns
+	cleanDuration atomic.Int64
+	cleanInfo     cleanInfo
+
+	stats  counters
+	unique hll
+	logs   loggers
+
+	// nil if event stream is disabled
+	events chan Event[T]
+
+	// nil if Audit() is not running
+	audit      chan AuditRecord[T]
+	auditStats auditCounters
+
+	// called after Try() denied action
+	onDeny func(id T, st KeyState)
+	// called after clean up removed key
+	// with keys waiting for it
+	onExpire    func(id T, st KeyState)
+	expiredKeys []expiredKey[T]
+
+	spike spikeDetector[T]
+
+	hooks    atomic.Pointer[hooks[T]]
+	exempt   atomic.Pointer[func(id T) bool]
+	resolver atomic.Pointer[PolicyResolver[T]]
+	queue    queue[T]
+	flights  flights[T]
+	dedup    dedup[T]
+	samples  reservoir[T]
+	clock    atomic.Pointer[func() time.Time]
+
+	shadow      atomic.Pointer[Limiter[T]]
+	shadowStats shadowCounters
+
+	// timings are measured on every lockEvery call
+	lockEvery atomic.Int64
+	lockCalls atomic.Uint64
+	// true while l.mu is held by sampled call
+	timing bool
+
+	// bits of 1 - load factor
+	// so zero value means full limits
+	loadFactor atomic.Uint64
+
+	// pprof label of limiter goroutines
+	name atomic.Pointer[string]
+	// bytes in unit of TryBytes()
+	byteUnit atomic.Int64
+	// PauseMode, 0 if not paused
+	paused atomic.Int32
+
+	deterministic atomic.Bool
+
+	// see SetInvariants()
+	inv invariants
+
+	// see SetDenyCache()
+	denyCache denials[T]
+
+	// see SetRemovedTTL()
+	tomb tombstones[T]
+
+	// see SetHotKeys()
+	hot hotKeys[T]
+
+	// see SetTrust()
+	trust trust[T]
+
+	// see SetErrorShedding()
+	health health[T]
 }
 
 // make new limiter for type T with maxCount for all actions
@@ -80,83 +316,657 @@ func New[T constraints.Ordered](
 	}
 
 	return &Limiter[T]{
-		m:           make(map[T]action, mapLen),
+		m:           make(map[T]packed, mapLen),
 		maxTime:     maxTime,
 		maxCount:    maxCount,
 		maxMapLen:   maxMapLen,
 		cleanAtOnce: cleanAtOnce,
+		autoClean:   true,
 	}
 }
 
-func (l *Limiter[T]) Try(id T) bool {
-	timeNow := time.Now().Unix()
+// change max count of actions for subsequent decisions
+// if maxCount <= 0 it sets to default
+func (l *Limiter[T]) SetMaxCount(maxCount int) {
+	defer l.moveBack()
+	if maxCount <= 0 {
+		maxCount = defaultMaxCount
+	}
+	mu.ExecMutex(&l.mu, func() {
+		l.maxCount = maxCount
+		l.ownPolicy = true
+	})
+	l.denyCache.clear()
+	l.inherit()
+}
 
-	var (
-		a  action
-		ok bool
+// change window of actions for subsequent decisions
+// resolution is one second
+// if d < time.Second it sets to default
+func (l *Limiter[T]) SetWindow(d time.Duration) {
+	defer l.moveBack()
+	maxTime := int64(d / time.Second)
+	if maxTime <= 0 {
+		maxTime = defaultMaxTime
+	}
+	mu.ExecMutex(&l.mu, func() {
+		l.maxTime = maxTime
+		l.ownPolicy = true
+	})
+	l.denyCache.clear()
+	l.inherit()
+}
 
-		maxMapLen int
-		mapLen    int
-		maxCount  int
-		maxTime   int64
-	)
-	mu.ExecRWMutex(&l.mu, func() {
-		a, ok = l.m[id]
-		maxMapLen = l.maxMapLen
-		maxCount = l.maxCount
-		maxTime = l.maxTime
+// change max map len before clean up
+// if maxMapLen < 0 it sets to default
+// 0 means unlimited map size
+func (l *Limiter[T]) SetMaxMapLen(maxMapLen int) {
+	if maxMapLen < 0 {
+		maxMapLen = defaultMaxMapLen
+	}
+	mu.ExecMutex(&l.mu, func() {
+		l.maxMapLen = maxMapLen
 	})
-	if !ok {
-		mu.ExecMutex(&l.mu, func() {
-			l.m[id] = action{
-				deltaTime: timeNow,
-				count:     1,
+}
+
+// enable or disable clean up goroutines spawned by Try()
+// when disabled clean up happens only on Clean(),
+// CleanContext() or Janitor() calls from your code
+func (l *Limiter[T]) SetAutoClean(on bool) {
+	mu.ExecMutex(&l.mu, func() {
+		l.autoClean = on
+	})
+}
+
+// set what Try() does with new keys when map is full
+// default is FullAllow
+func (l *Limiter[T]) SetFullPolicy(p FullPolicy) {
+	mu.ExecMutex(&l.mu, func() {
+		l.fullPolicy = p
+	})
+}
+
+// keep entries until they are idle for d
+// even when their window has already ended
+// entry is never removed while its window is active
+//
+// 0 means entry is removed right after window end
+// resolution is one second
+func (l *Limiter[T]) SetIdleTTL(d time.Duration) {
+	mu.ExecMutex(&l.mu, func() {
+		l.idleTTL = int64(d / time.Second)
+	})
+}
+
+// override idle ttl for existing key
+// e.g. to keep banned keys longer
+// d <= 0 resets it to limiter idle ttl
+//
+// returns false if key is not tracked
+func (l *Limiter[T]) SetKeyTTL(id T, d time.Duration) bool {
+	defer l.moveBack(id)
+	var ok bool
+	mu.ExecMutex(&l.mu, func() {
+		l.holder(id, func(hl *Limiter[T]) {
+			var a action
+			a, ok = hl.get(id)
+			if !ok {
+				return
 			}
+			a.ttl = 0
+			if d > 0 {
+				a.ttl = int64(d / time.Second)
+			}
+			hl.set(id, a)
 		})
+	})
+	return ok
+}
+
+// in dry run mode limiter makes and records decisions
+// as usual (stats, events, logs, OnDeny) but Try() always
+// returns true, use it to observe new limits before enforcing
+func (l *Limiter[T]) SetDryRun(on bool) {
+	defer l.moveBack()
+	mu.ExecMutex(&l.mu, func() {
+		l.dryRun = on
+	})
+}
+
+// let keys exceed their limit by up to n actions
+// overdraft is repaid from next window
+// or from bucket tokens for burst policies
+//
+// 0 disables overdraft
+func (l *Limiter[T]) SetOverdraft(n int) {
+	defer l.moveBack()
+	if n < 0 {
+		n = 0
+	}
+	mu.ExecMutex(&l.mu, func() {
+		l.overdraft = n
+	})
+}
+
+// move part of unused actions of key window to
+// its next window but no more than max actions
+// e.g. SetCarryOver(0.5, 100) for billing like quotas
+//
+// works only for policies without burst
+// part <= 0 disables carry over
+func (l *Limiter[T]) SetCarryOver(part float64, max int) {
+	defer l.moveBack()
+	if part > 1 {
+		part = 1
+	}
+	mu.ExecMutex(&l.mu, func() {
+		l.carryPart = part
+		l.carryMax = max
+	})
+}
+
+// make window of every new key longer by random
+// part of max so keys created at same moment
+// don't reset their windows all together
+//
+// 0 disables jitter
+// resolution is one second
+func (l *Limiter[T]) SetJitter(max time.Duration) {
+	defer l.moveBack()
+	mu.ExecMutex(&l.mu, func() {
+		l.jitter = int64(max / time.Second)
+	})
+}
+
+// spread actions of every key evenly over its window
+// so key can take one action every window / limit
+// instead of using whole limit at once
+// e.g. for partners that enforce per second smoothness
+//
+// denials by pacing don't count as violations
+// and it has nanosecond resolution
+func (l *Limiter[T]) SetPacing(on bool) {
+	defer l.moveBack()
+	mu.ExecMutex(&l.mu, func() {
+		l.pacing = on
+	})
+}
+
+// after denial key stays denied for d and every
+// Try() during that time is denied and restarts it
+// even if key window ends meanwhile
+//
+// 0 disables cooldown
+// resolution is one second
+func (l *Limiter[T]) SetCooldown(d time.Duration) {
+	defer l.moveBack()
+	mu.ExecMutex(&l.mu, func() {
+		l.cooldown = int64(d / time.Second)
+	})
+}
+
+// call f every time Try() denies action
+// f is called outside of limiter lock
+// so it can use limiter methods
+//
+// nil f removes callback
+func (l *Limiter[T]) OnDeny(f func(id T, st KeyState)) {
+	defer l.moveBack()
+	mu.ExecMutex(&l.mu, func() {
+		l.onDeny = f
+	})
+}
+
+func (l *Limiter[T]) Try(id T) bool {
+	return l.TryN(id, 1)
+}
+
+// like Try() but action costs n units
+// all of them are spent or none
+// n < 1 is counted as 1
+func (l *Limiter[T]) TryN(id T, n int) bool {
+	if n < 1 {
+		n = 1
+	}
+	now := l.now()
+	ok := l.run(id, n, noPriority, now)
+	if !ok {
+		l.sampleDenial(id, nil, now)
+	}
+	if s := l.shadow.Load(); s != nil {
+		l.compareShadow(ok, s.TryN(id, n))
+	}
+	return ok
+}
+
+// forget state of key so it starts from scratch
+// own policy of key stays
+// returns false if key is not tracked
+func (l *Limiter[T]) Reset(id T) bool {
+	if hk := l.hotOf(id); hk != nil {
+		hk.settle(id)
+		return hk.l.Reset(id)
+	}
+	var ok bool
+	mu.ExecMutex(&l.mu, func() {
+		_, ok = l.get(id)
+		l.remove(id)
+		delete(l.tomb.m, id)
+	})
+	l.denyCache.forget(id)
+	return ok
+}
+
+// give n units spent by TryN() back to key
+// units are added to current window of key
+// n < 1 is counted as 1
+//
+// returns false if key is not tracked
+func (l *Limiter[T]) Refund(id T, n int) bool {
+	if hk := l.hotOf(id); hk != nil {
+		hk.settle(id)
+		return hk.l.Refund(id, n)
+	}
+	if n < 1 {
+		n = 1
+	}
+	timeNow := l.now().Unix()
+
+	var ok bool
+	mu.ExecMutex(&l.mu, func() {
+		var a action
+		a, ok = l.get(id)
+		if !ok {
+			return
+		}
+		p := l.policyOf(id)
+		a = l.roll(a, p, timeNow)
+		p.refund(&a, n)
+		l.set(id, a)
+	})
+	l.denyCache.forget(id)
+	return ok
+}
+
+// make decision for id at now with hooks around it
+func (l *Limiter[T]) run(id T, n, prio int, now time.Time) bool {
+	h := l.hooks.Load()
+	if h == nil {
+		return l.decide(id, n, prio, now)
+	}
+
+	for _, f := range h.before {
+		if !f(id) {
+			l.stats.denied.Add(1)
+			return false
+		}
+	}
+	ok := l.decide(id, n, prio, now)
+	for _, f := range h.after {
+		ok = f(id, ok)
+	}
+	return ok
+}
+
+// make decision for id at now and handle its outcome
+func (l *Limiter[T]) decide(id T, n, prio int, now time.Time) bool {
+	sampled := l.sampled()
+	if sampled {
+		defer l.tryDone(l.wallNow())
+	}
+	if ok, paused := l.pausedDecision(); paused {
+		return ok
+	}
+	if l.exempted(id) {
+		l.stats.allowed.Add(1)
 		return true
 	}
-	if timeNow-a.deltaTime < maxTime &&
-		a.count >= maxCount {
+	timeNow := now.Unix()
+	if l.denyCache.get(id, timeNow) {
+		l.stats.denied.Add(1)
 		return false
 	}
+	if l.trust.on.Load() {
+		if c := l.trustedOf(id); c != nil && !l.deniedKey(id) {
+			c.Add(uint64(n))
+			l.stats.allowed.Add(1)
+			return true
+		}
+	}
+	if l.hot.on.Load() {
+		l.hot.calls.Add(1)
+		if hk := l.hotOf(id); hk != nil {
+			return l.decideHot(hk, id, n, prio, now)
+		}
+	}
+	rp, resolved := l.resolve(id)
 
-	mu.ExecMutex(&l.mu, func() {
-		l.m[id] = action{
-			deltaTime: timeNow,
-			count:     a.count + 1,
+	var (
+		o      outcome[T]
+		pd     pending[T]
+		inv    invariants
+		verr   error
+		until  int64
+		hk     *hotKey[T]
+		round  *hotRound[T]
+		due    bool
+		expiry bool
+	)
+	mu.ExecMutex(timedMutex[T]{l, sampled}, func() {
+		// key was isolated after check above
+		if hk = l.hotOf(id); hk != nil {
+			return
+		}
+		round = l.countHot(id, timeNow)
+		due = l.trustDue(timeNow)
+		if resolved {
+			l.setResolved(id, rp)
+		}
+		inv = l.inv
+		var (
+			prev action
+			had  bool
+		)
+		if inv.on {
+			prev, had = l.get(id)
+		}
+		o = l.commit(id, l.try(id, n, prio, now), timeNow)
+		l.expireSome(id, timeNow)
+		expiry = len(l.expiredKeys) > 0
+		if inv.on {
+			verr = l.checkInvariants(id, prev, had, o)
+		}
+		pd = l.pending(id, o, timeNow)
+		if !o.ok && !l.dryRun && l.denyCache.on.Load() {
+			until = l.readyAt(id, 1, timeNow)
 		}
-		mapLen = len(l.m)
 	})
+	if hk != nil {
+		return l.decideHot(hk, id, n, prio, now)
+	}
+	if round != nil {
+		if l.deterministic.Load() {
+			l.rebalance(round)
+		} else {
+			go l.do(context.Background(), "hot", func(context.Context) {
+				l.rebalance(round)
+			})
+		}
+	}
+	if due {
+		l.checkTrust(timeNow)
+	}
+	if expiry {
+		l.notifyExpired()
+	}
+	if verr != nil {
+		inv.report(verr)
+	}
+	if until > timeNow {
+		l.denyCache.put(id, until)
+	}
+	return l.finish(id, o, pd, timeNow)
+}
+
+// limiter state needed to handle outcome
+// after l.mu is released
+type pending[T any] struct {
+	logs   loggers
+	dryRun bool
+
+	onDeny func(id T, st KeyState)
+	st     KeyState
 
-	if mapLen >= maxMapLen {
-		go l.Clean()
+	onSpike func(id T, rate, avg float64)
+}
+
+// l.mu must be held
+func (l *Limiter[T]) pending(id T, o outcome[T], timeNow int64) pending[T] {
+	pd := pending[T]{
+		logs:   l.logs,
+		dryRun: l.dryRun,
+	}
+	if o.spike {
+		pd.onSpike = l.spike.f
+	}
+	if !o.ok {
+		l.auditDenied(id, o.a, timeNow)
 	}
+	if !o.ok && l.onDeny != nil {
+		pd.onDeny = l.onDeny
+		pd.st = l.keyState(o.a, l.policyOf(id), timeNow)
+		pd.st.Meta = l.meta[id]
+	}
+	return pd
+}
+
+// update stats, call logs and callbacks for outcome
+// returns result of Try()
+func (l *Limiter[T]) finish(id T, o outcome[T], pd pending[T], timeNow int64) bool {
+	if o.ok {
+		l.stats.allowed.Add(1)
+	} else {
+		l.stats.denied.Add(1)
+		if pd.logs.deny != nil {
+			pd.logs.deny(
+				"limiter: action denied",
+				"key", id,
+				"count", o.a.count,
+				"denies", o.a.denies,
+				"banned", o.a.banned(timeNow),
+			)
+		}
+		if pd.onDeny != nil {
+			pd.onDeny(id, pd.st)
+		}
+	}
+	if pd.onSpike != nil {
+		pd.onSpike(id, o.spikeRate, o.spikeAvg)
+	}
+	if o.evicted && pd.logs.evict != nil {
+		pd.logs.evict("limiter: key evicted", "key", o.evictedKey)
+	}
+	if o.clean {
+		go l.do(context.Background(), "clean", func(context.Context) {
+			l.Clean()
+		})
+	}
+
+	return o.ok || pd.dryRun
+}
+
+// result of try() handled after l.mu is released
+type outcome[T any] struct {
+	ok bool
+	// clean up must be started
+	clean bool
+
+	// key removed to make space for new key
+	evicted    bool
+	evictedKey T
+
+	// key is new, it is counted and
+	// oldest key is evicted by commit()
+	inserted bool
+	unique   bool
+	evict    bool
+	// key was counted active for fair share
+	fair bool
 
-	return true
+	// entry after decision
+	a action
+
+	// calls of key went over its average
+	spike     bool
+	spikeRate float64
+	spikeAvg  float64
 }
 
-func (l *Limiter[T]) Clean() {
-	if l.cleaning.Load() {
-		return
+// decide on action for id and update its entry
+//
+// l.mu must be held
+func (l *Limiter[T]) try(id T, n, prio int, now time.Time) (o outcome[T]) {
+	timeNow := now.Unix()
+	if ok, listed := l.listed(id); listed {
+		o.ok = ok
+		o.a, _ = l.get(id)
+		return o
 	}
-	l.cleaning.Store(true)
 
-	var i int
-	mu.ExecMutex(&l.mu, func() {
-		for key, val := range l.m {
-			if i == l.cleanAtOnce {
-				i = 0
-				l.mu.Unlock()
-				runtime.Gosched()
-				l.mu.Lock()
+	full := l.maxMapLen > 0 && len(l.m) >= l.maxMapLen
+	o.clean = full && l.autoClean && !l.deterministic.Load()
+
+	p := l.policyOf(id)
+	a, found := l.lookup(id)
+	if !found {
+		if full {
+			switch l.fullPolicy {
+			case FullDeny:
+				o.a = action{
+					deltaTime: timeNow,
+					lastTime:  timeNow,
+					firstTime: timeNow,
+					denies:    1,
+				}
+				return o
+			case FullEvict:
+				o.evict = true
 			}
+		}
+		o.inserted = true
+		if old, ok := l.buried(id, timeNow); ok {
+			a = old
+		} else {
+			o.unique = true
+			a = p.fresh(timeNow)
+			if l.jitter > 0 {
+				a.jitter = l.int63n(l.jitter + 1)
+			}
+		}
+	}
 
-			timeNow := time.Now().Unix()
-			if timeNow-val.deltaTime >= l.maxTime {
-				delete(l.m, key)
+	a = l.roll(a, p, timeNow)
+	a.lastTime = timeNow
+	l.detectSpike(&a, &o, timeNow)
+	p = l.warmup.scale(p, a, timeNow)
+	period := a.fairPeriod
+	p = l.fairScale(id, &a, p, timeNow)
+	o.fair = a.fairPeriod != period
+	p = l.loadScale(p)
+	p = l.healthScale(id, p)
+	p = l.prio.scale(p, prio)
+	if l.bookings != nil {
+		p.reserve += l.booked(id, a.deltaTime, p.end(a))
+	}
+	var nowNano int64
+	if l.pacing {
+		nowNano = now.UnixNano()
+	}
+	switch {
+	case a.banned(timeNow):
+		a.denies++
+		a.streak++
+	case l.pacing && a.pacedUntil > nowNano:
+		a.denies++
+		a.streak++
+	case a.cooldownUntil > timeNow || !p.admit(&a, n, timeNow):
+		a.denies++
+		a.streak++
+		if l.cooldown > 0 {
+			a.cooldownUntil = timeNow + l.cooldown
+		}
+		l.violate(&a, timeNow)
+	default:
+		o.ok = true
+		a.streak = 0
+		if l.pacing {
+			p.pace(&a, n, nowNano)
+		}
+	}
+	if !o.ok {
+		a.lastDenied = timeNow
+	}
+	l.set(id, a)
+	o.a = a
+	return o
+}
+
+// apply parts of outcome of try() that are
+// not rolled back by TryAll() and send event
+//
+// l.mu must be held
+func (l *Limiter[T]) commit(id T, o outcome[T], timeNow int64) outcome[T] {
+	if o.inserted {
+		l.stats.inserted.Add(1)
+		l.unbury(id)
+		if o.unique {
+			l.addUnique(id)
+		}
+		if o.evict {
+			o.evictedKey, o.evicted = l.evict(timeNow, id)
+		}
+		if len(l.m) > l.shrink.peak {
+			l.shrink.peak = len(l.m)
+		}
+	}
+	if o.ok {
+		l.emit(EventAllowed, id, timeNow)
+	} else {
+		l.emit(EventDenied, id, timeNow)
+	}
+	return o
+}
+
+// entry as it looks at timeNow
+// starts new window if current one ended
+//
+// l.mu must be held
+func (l *Limiter[T]) current(a action, p policy, timeNow int64) action {
+	if p.ended(a, timeNow) {
+		// overdraft and unused actions
+		// move only to next window
+		next := p.next(a, timeNow)
+		carry := 0
+		if next && p.burst <= 0 && p.carryPart > 0 {
+			unused := p.maxCount + a.carry - a.count
+			carry = int(float64(unused) * p.carryPart)
+			if carry > p.carryMax {
+				carry = p.carryMax
+			}
+			if carry < 0 {
+				carry = 0
 			}
-			i++
 		}
-	})
-	l.cleaning.Store(false)
+
+		a.count = 0
+		if next {
+			a.count = a.debt
+		}
+		a.carry = carry
+		a.deltaTime = p.start(timeNow)
+		a.debt = 0
+		a.denies = 0
+	}
+	return a
+}
+
+// true if entry can be removed
+//
+// l.mu must be held
+func (l *Limiter[T]) expired(a action, p policy, timeNow int64) bool {
+	if a.banned(timeNow) || a.cooldownUntil > timeNow ||
+		l.ban.decay > 0 && l.banLevel(a, timeNow) > 0 {
+		return false
+	}
+
+	if p.burst > 0 && p.tokens(a, timeNow) < float64(p.burst) {
+		return false
+	}
+
+	ttl := l.idleTTL
+	if a.ttl > 0 {
+		ttl = a.ttl
+	}
+	return p.ended(a, timeNow) &&
+		timeNow-a.lastTime >= ttl
 }
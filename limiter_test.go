@@ -0,0 +1,30 @@
+package limiter_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ssleert/limiter"
+)
+
+func TestSetWindow(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want time.Duration
+	}{
+		{d: 90 * time.Second, want: 90 * time.Second},
+		{d: 1500 * time.Millisecond, want: time.Second},
+		{d: 500 * time.Millisecond, want: time.Hour},
+		{d: 0, want: time.Hour},
+		{d: -time.Minute, want: time.Hour},
+	}
+	for _, tt := range tests {
+		t.Run(tt.d.String(), func(t *testing.T) {
+			l := limiter.New[string](10, 60, 16, 1024, 16)
+			l.SetWindow(tt.d)
+			if got := l.Policy().Window; got != tt.want {
+				t.Fatalf("Policy().Window = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
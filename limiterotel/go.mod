@@ -0,0 +1,17 @@
+module github.com/ssleert/limiter/limiterotel
+
+go 1.20
+
+require (
+	github.com/ssleert/limiter v0.0.0
+	go.opentelemetry.io/otel v1.19.0
+	go.opentelemetry.io/otel/metric v1.19.0
+	go.opentelemetry.io/otel/trace v1.19.0
+)
+
+require (
+	github.com/ssleert/mu v0.0.0-20231020083341-2150862745b3 // indirect
+	golang.org/x/exp v0.0.0-20231006140011-7918f672742d // indirect
+)
+
+replace github.com/ssleert/limiter => ../
@@ -0,0 +1,223 @@
+/*
+opentelemetry instrumentation for limiter
+*/
+package limiterotel
+
+import (
+	"context"
+	"time"
+
+	"github.com/ssleert/limiter"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// attribute with limiter name on all metrics
+const NameKey = attribute.Key("limiter.name")
+
+// attribute with namespace name on metrics of namespaces
+const NamespaceKey = attribute.Key("limiter.namespace")
+
+// anything with limiter stats
+// every limiter.Limiter[T] implements it
+type Source interface {
+	Stats() limiter.Stats
+	LastCleanDuration() time.Duration
+}
+
+// source with namespaces
+// every limiter.Limiter[T] implements it
+type NamespaceSource interface {
+	NamespaceStats() map[string]limiter.Stats
+}
+
+// register observable instruments for src on meter
+// values are read from src.Stats() on every collection
+// if src is NamespaceSource every namespace is observed
+// too with NamespaceKey attribute
+//
+// call Unregister() on result to stop collection
+func RegisterMetrics(
+	meter metric.Meter,
+	src Source,
+	name string,
+) (metric.Registration, error) {
+	allowed, err := meter.Int64ObservableCounter(
+		"limiter.allowed",
+		metric.WithDescription("Actions allowed by limiter."),
+		metric.WithUnit("{action}"),
+	)
+	if err != nil {
+		return nil, err
+	}
+	denied, err := meter.Int64ObservableCounter(
+		"limiter.denied",
+		metric.WithDescription("Actions denied by limiter."),
+		metric.WithUnit("{action}"),
+	)
+	if err != nil {
+		return nil, err
+	}
+	evicted, err := meter.Int64ObservableCounter(
+		"limiter.evicted",
+		metric.WithDescription("Keys evicted to make space for new keys."),
+		metric.WithUnit("{key}"),
+	)
+	if err != nil {
+		return nil, err
+	}
+	cleaned, err := meter.Int64ObservableCounter(
+		"limiter.cleaned",
+		metric.WithDescription("Expired keys removed by clean up."),
+		metric.WithUnit("{key}"),
+	)
+	if err != nil {
+		return nil, err
+	}
+	keys, err := meter.Int64ObservableUpDownCounter(
+		"limiter.keys",
+		metric.WithDescription("Keys tracked by limiter."),
+		metric.WithUnit("{key}"),
+	)
+	if err != nil {
+		return nil, err
+	}
+	cleanDuration, err := meter.Float64ObservableGauge(
+		"limiter.clean.duration",
+		metric.WithDescription("Duration of last clean up run."),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	attrs := metric.WithAttributes(NameKey.String(name))
+	return meter.RegisterCallback(
+		func(_ context.Context, o metric.Observer) error {
+			observe := func(st limiter.Stats, attrs metric.ObserveOption) {
+				o.ObserveInt64(allowed, int64(st.Allowed), attrs)
+				o.ObserveInt64(denied, int64(st.Denied), attrs)
+				o.ObserveInt64(evicted, int64(st.Evicted), attrs)
+				o.ObserveInt64(cleaned, int64(st.Cleaned), attrs)
+				o.ObserveInt64(keys, int64(st.Keys), attrs)
+			}
+			observe(src.Stats(), attrs)
+			o.ObserveFloat64(
+				cleanDuration,
+				src.LastCleanDuration().Seconds(),
+				attrs,
+			)
+
+			nss, ok := src.(NamespaceSource)
+			if !ok {
+				return nil
+			}
+			for ns, st := range nss.NamespaceStats() {
+				observe(st, metric.WithAttributes(
+					NameKey.String(name),
+					NamespaceKey.String(ns),
+				))
+			}
+			return nil
+		},
+		allowed, denied, evicted, cleaned, keys, cleanDuration,
+	)
+}
+
+// attribute with decision of limiter
+// "allowed" or "denied"
+const DecisionKey = attribute.Key("limiter.decision")
+
+// limiter that calls can be timed
+// every limiter.Limiter[T] implements it
+type Timed[T any] interface {
+	TryN(id T, n int) bool
+	WaitN(ctx context.Context, id T, n int) error
+}
+
+// wrapper of limiter that records durations
+// of its decisions and waits in histograms
+// with NameKey and DecisionKey attributes
+//
+// use it instead of limiter where calls
+// should be measured, calls of limiter
+// itself are not recorded
+type Histograms[T any] struct {
+	l Timed[T]
+
+	try  metric.Float64Histogram
+	wait metric.Float64Histogram
+
+	allowed metric.MeasurementOption
+	denied  metric.MeasurementOption
+}
+
+// make histograms for calls of l on meter
+func NewHistograms[T any](
+	meter metric.Meter,
+	l Timed[T],
+	name string,
+) (*Histograms[T], error) {
+	try, err := meter.Float64Histogram(
+		"limiter.try.duration",
+		metric.WithDescription("Duration of limiter decisions."),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		return nil, err
+	}
+	wait, err := meter.Float64Histogram(
+		"limiter.wait.duration",
+		metric.WithDescription("Time spent waiting for limiter budget."),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Histograms[T]{
+		l:    l,
+		try:  try,
+		wait: wait,
+		allowed: metric.WithAttributeSet(attribute.NewSet(
+			NameKey.String(name),
+			DecisionKey.String("allowed"),
+		)),
+		denied: metric.WithAttributeSet(attribute.NewSet(
+			NameKey.String(name),
+			DecisionKey.String("denied"),
+		)),
+	}, nil
+}
+
+func (h *Histograms[T]) Try(id T) bool {
+	return h.TryN(id, 1)
+}
+
+// l.TryN() with its duration recorded
+func (h *Histograms[T]) TryN(id T, n int) bool {
+	start := time.Now()
+	ok := h.l.TryN(id, n)
+	h.try.Record(context.Background(), time.Since(start).Seconds(), h.decision(ok))
+	return ok
+}
+
+func (h *Histograms[T]) Wait(ctx context.Context, id T) error {
+	return h.WaitN(ctx, id, 1)
+}
+
+// l.WaitN() with its duration recorded
+// failed waits are recorded as denied
+func (h *Histograms[T]) WaitN(ctx context.Context, id T, n int) error {
+	start := time.Now()
+	err := h.l.WaitN(ctx, id, n)
+	h.wait.Record(ctx, time.Since(start).Seconds(), h.decision(err == nil))
+	return err
+}
+
+func (h *Histograms[T]) decision(ok bool) metric.MeasurementOption {
+	if ok {
+		return h.allowed
+	}
+	return h.denied
+}
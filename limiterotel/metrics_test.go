@@ -0,0 +1,91 @@
+package limiterotel_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ssleert/limiter"
+	"github.com/ssleert/limiter/limiterotel"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/noop"
+)
+
+// meter with histograms that keep their records
+type meter struct {
+	noop.Meter
+	hists map[string]*histogram
+}
+
+func (m *meter) Float64Histogram(
+	name string,
+	_ ...metric.Float64HistogramOption,
+) (metric.Float64Histogram, error) {
+	h := &histogram{}
+	m.hists[name] = h
+	return h, nil
+}
+
+type histogram struct {
+	noop.Float64Histogram
+	// decisions of records
+	decisions []string
+}
+
+func (h *histogram) Record(_ context.Context, _ float64, opts ...metric.RecordOption) {
+	set := metric.NewRecordConfig(opts).Attributes()
+	d, _ := set.Value(limiterotel.DecisionKey)
+	h.decisions = append(h.decisions, d.AsString())
+}
+
+func TestHistograms(t *testing.T) {
+	tests := []struct {
+		name string
+		call func(h *limiterotel.Histograms[string]) bool
+		// histogram with record and its decisions
+		hist string
+		want []string
+	}{
+		{
+			name: "try",
+			call: func(h *limiterotel.Histograms[string]) bool { return h.Try("a") },
+			hist: "limiter.try.duration",
+			want: []string{"allowed", "allowed", "denied"},
+		},
+		{
+			name: "wait",
+			call: func(h *limiterotel.Histograms[string]) bool {
+				ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+				defer cancel()
+				return h.Wait(ctx, "a") == nil
+			},
+			hist: "limiter.wait.duration",
+			want: []string{"allowed", "allowed", "denied"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &meter{hists: make(map[string]*histogram)}
+			l := limiter.New[string](2, 60, 16, 1024, 16)
+			h, err := limiterotel.NewHistograms[string](m, l, "test")
+			if err != nil {
+				t.Fatalf("NewHistograms() err = %v", err)
+			}
+
+			for i, want := range tt.want {
+				if got := tt.call(h); got != (want == "allowed") {
+					t.Fatalf("call %d = %v, want %s", i, got, want)
+				}
+			}
+			got := m.hists[tt.hist].decisions
+			if len(got) != len(tt.want) {
+				t.Fatalf("records = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("records = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
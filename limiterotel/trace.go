@@ -0,0 +1,94 @@
+package limiterotel
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/ssleert/limiter"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// name of span event added on denied action
+const DeniedEvent = "limiter.denied"
+
+// name of span event added when Wait() blocks or fails
+const WaitEvent = "limiter.wait"
+
+// attribute with seconds until key can spend units
+const RetryAfterKey = attribute.Key("limiter.retry_after")
+
+// attribute with seconds spent in Wait()
+const WaitedKey = attribute.Key("limiter.waited")
+
+// attribute with error of Wait()
+const ErrorKey = attribute.Key("limiter.error")
+
+// limiter that can be traced
+// every limiter.Limiter[T] implements it
+type Traced[T any] interface {
+	TryN(id T, n int) bool
+	WaitN(ctx context.Context, id T, n int) error
+	RetryAfter(id T, n int) (time.Duration, bool)
+}
+
+// call l.TryN(id, 1) and if action is denied add
+// DeniedEvent to span from ctx with retry after
+// for key and limiter name
+func Try[T any](ctx context.Context, l Traced[T], name string, id T) bool {
+	if l.TryN(id, 1) {
+		return true
+	}
+
+	span := trace.SpanFromContext(ctx)
+	if !span.IsRecording() {
+		return false
+	}
+
+	attrs := []attribute.KeyValue{NameKey.String(name)}
+	if d, ok := l.RetryAfter(id, 1); ok {
+		attrs = append(attrs, RetryAfterKey.Float64(d.Seconds()))
+	}
+	span.AddEvent(DeniedEvent, trace.WithAttributes(attrs...))
+
+	return false
+}
+
+// call l.WaitN(ctx, id, 1) and if key had no budget
+// or wait failed add WaitEvent to span from ctx
+// with time waited, expected wait and error
+//
+// waits that are admitted right away
+// leave span as is like Try() does
+func Wait[T any](ctx context.Context, l Traced[T], name string, id T) error {
+	span := trace.SpanFromContext(ctx)
+	if !span.IsRecording() {
+		return l.WaitN(ctx, id, 1)
+	}
+
+	after, ok := l.RetryAfter(id, 1)
+	start := time.Now()
+	err := l.WaitN(ctx, id, 1)
+	if ok && after == 0 && err == nil {
+		return nil
+	}
+
+	attrs := []attribute.KeyValue{
+		NameKey.String(name),
+		WaitedKey.Float64(time.Since(start).Seconds()),
+	}
+	var re *limiter.RetryError
+	if errors.As(err, &re) {
+		after, ok = re.RetryAfter(), true
+	}
+	if ok {
+		attrs = append(attrs, RetryAfterKey.Float64(after.Seconds()))
+	}
+	if err != nil {
+		attrs = append(attrs, ErrorKey.String(err.Error()))
+	}
+	span.AddEvent(WaitEvent, trace.WithAttributes(attrs...))
+
+	return err
+}
@@ -0,0 +1,120 @@
+package limiterotel_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ssleert/limiter"
+	"github.com/ssleert/limiter/limiterotel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// span that records its events
+type span struct {
+	trace.Span
+	events []event
+}
+
+type event struct {
+	name  string
+	attrs map[attribute.Key]attribute.Value
+}
+
+func (s *span) IsRecording() bool { return true }
+
+func (s *span) AddEvent(name string, opts ...trace.EventOption) {
+	e := event{name: name, attrs: make(map[attribute.Key]attribute.Value)}
+	cfg := trace.NewEventConfig(opts...)
+	for _, kv := range cfg.Attributes() {
+		e.attrs[kv.Key] = kv.Value
+	}
+	s.events = append(s.events, e)
+}
+
+func TestTrace(t *testing.T) {
+	tests := []struct {
+		name string
+		// spent units of key before call
+		spent int
+		call  func(ctx context.Context, l *limiter.Limiter[string]) error
+		// name of event or empty if none
+		event string
+		// attributes event must have
+		keys []attribute.Key
+	}{
+		{
+			name:  "try allowed",
+			call:  try,
+			event: "",
+		},
+		{
+			name:  "try denied",
+			spent: 1,
+			call:  try,
+			event: limiterotel.DeniedEvent,
+			keys:  []attribute.Key{limiterotel.NameKey, limiterotel.RetryAfterKey},
+		},
+		{
+			name:  "wait admitted",
+			call:  wait,
+			event: "",
+		},
+		{
+			name:  "wait failed",
+			spent: 1,
+			call:  wait,
+			event: limiterotel.WaitEvent,
+			keys: []attribute.Key{
+				limiterotel.NameKey,
+				limiterotel.WaitedKey,
+				limiterotel.RetryAfterKey,
+				limiterotel.ErrorKey,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := limiter.New[string](1, 60, 16, 1024, 16)
+			for i := 0; i < tt.spent; i++ {
+				l.Try("a")
+			}
+
+			s := &span{Span: trace.SpanFromContext(context.Background())}
+			ctx, cancel := context.WithTimeout(
+				trace.ContextWithSpan(context.Background(), s),
+				10*time.Millisecond,
+			)
+			defer cancel()
+			tt.call(ctx, l)
+
+			if tt.event == "" {
+				if len(s.events) != 0 {
+					t.Fatalf("events = %+v, want none", s.events)
+				}
+				return
+			}
+			if len(s.events) != 1 || s.events[0].name != tt.event {
+				t.Fatalf("events = %+v, want one %s", s.events, tt.event)
+			}
+			for _, k := range tt.keys {
+				if _, ok := s.events[0].attrs[k]; !ok {
+					t.Fatalf("event %+v has no %s", s.events[0], k)
+				}
+			}
+			if v, ok := s.events[0].attrs[limiterotel.RetryAfterKey]; ok && v.AsFloat64() <= 0 {
+				t.Fatalf("retry after = %v, want > 0", v.AsFloat64())
+			}
+		})
+	}
+}
+
+func try(ctx context.Context, l *limiter.Limiter[string]) error {
+	limiterotel.Try[string](ctx, l, "test", "a")
+	return nil
+}
+
+func wait(ctx context.Context, l *limiter.Limiter[string]) error {
+	return limiterotel.Wait[string](ctx, l, "test", "a")
+}
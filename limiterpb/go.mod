@@ -0,0 +1,13 @@
+module github.com/ssleert/limiter/limiterpb
+
+go 1.20
+
+require (
+	github.com/ssleert/limiter v0.0.0
+	golang.org/x/exp v0.0.0-20231006140011-7918f672742d
+	google.golang.org/protobuf v1.31.0
+)
+
+require github.com/ssleert/mu v0.0.0-20231020083341-2150862745b3 // indirect
+
+replace github.com/ssleert/limiter => ../
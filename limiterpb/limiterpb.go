@@ -0,0 +1,297 @@
+/*
+protobuf encoding of limiter state
+schema is in state.proto
+*/
+package limiterpb
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"time"
+
+	"github.com/ssleert/limiter"
+	"golang.org/x/exp/constraints"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// schema version written by Marshal()
+// it follows limiter.SnapshotVersion
+const Version = limiter.SnapshotVersion
+
+var ErrInvalid = errors.New("limiterpb: invalid message")
+
+// Snapshot message
+type Snapshot[T constraints.Ordered] struct {
+	Version uint32
+	// clock of writer in unix seconds
+	Time    int64
+	Entries []limiter.Entry[T]
+}
+
+// encode s as Snapshot message
+// Entry.Meta has no schema and is not encoded
+func Marshal[T constraints.Ordered](s Snapshot[T]) ([]byte, error) {
+	var b []byte
+	b = appendVarint(b, 1, uint64(s.Version))
+	b = appendVarint(b, 2, uint64(s.Time))
+	for _, e := range s.Entries {
+		eb, err := marshalEntry(e)
+		if err != nil {
+			return nil, err
+		}
+		b = protowire.AppendTag(b, 3, protowire.BytesType)
+		b = protowire.AppendBytes(b, eb)
+	}
+	return b, nil
+}
+
+// decode Snapshot message from b
+// unknown fields are skipped and entries
+// of older versions are migrated
+func Unmarshal[T constraints.Ordered](b []byte) (Snapshot[T], error) {
+	var s Snapshot[T]
+	err := fields(b, func(num protowire.Number, typ protowire.Type, v uint64, data []byte) error {
+		switch num {
+		case 1:
+			s.Version = uint32(v)
+		case 2:
+			s.Time = int64(v)
+		case 3:
+			if typ != protowire.BytesType {
+				return fmt.Errorf("%w: entry is not message", ErrInvalid)
+			}
+			e, err := unmarshalEntry[T](data)
+			if err != nil {
+				return err
+			}
+			s.Entries = append(s.Entries, e)
+		}
+		return nil
+	})
+	if err != nil {
+		return Snapshot[T]{}, err
+	}
+	if s.Version > Version {
+		return Snapshot[T]{}, fmt.Errorf("%w: unknown version %d", ErrInvalid, s.Version)
+	}
+	if err := limiter.Migrate(int(s.Version), s.Entries); err != nil {
+		return Snapshot[T]{}, err
+	}
+	s.Version = Version
+	return s, nil
+}
+
+// write state of l to w as Snapshot message
+func Save[T constraints.Ordered](w io.Writer, l *limiter.Limiter[T]) error {
+	b, err := Marshal(Snapshot[T]{
+		Version: Version,
+		Time:    time.Now().Unix(),
+		Entries: l.Snapshot(),
+	})
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(b)
+	return err
+}
+
+// read Snapshot message from r and restore it to l
+func Load[T constraints.Ordered](r io.Reader, l *limiter.Limiter[T]) error {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	s, err := Unmarshal[T](b)
+	if err != nil {
+		return err
+	}
+	l.Restore(s.Entries)
+	return nil
+}
+
+func marshalEntry[T constraints.Ordered](e limiter.Entry[T]) ([]byte, error) {
+	kb, err := marshalKey(e.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	var b []byte
+	b = protowire.AppendTag(b, 1, protowire.BytesType)
+	b = protowire.AppendBytes(b, kb)
+	b = appendVarint(b, 2, uint64(e.WindowStart))
+	b = appendVarint(b, 3, uint64(e.LastSeen))
+	b = appendVarint(b, 4, uint64(e.FirstSeen))
+	b = appendVarint(b, 5, uint64(e.Count))
+	b = appendVarint(b, 6, uint64(e.Denies))
+	b = appendVarint(b, 7, uint64(e.Violations))
+	b = appendVarint(b, 8, uint64(e.ViolationStart))
+	b = appendVarint(b, 9, uint64(e.BannedUntil))
+	b = appendVarint(b, 10, uint64(e.BanLevel))
+	b = appendVarint(b, 11, uint64(e.CooldownUntil))
+	if e.Tokens != 0 {
+		b = protowire.AppendTag(b, 12, protowire.Fixed64Type)
+		b = protowire.AppendFixed64(b, math.Float64bits(e.Tokens))
+	}
+	b = appendVarint(b, 13, uint64(e.Refilled))
+	b = appendVarint(b, 14, uint64(e.Debt))
+	b = appendVarint(b, 15, uint64(e.Carry))
+	b = appendVarint(b, 16, uint64(e.Jitter))
+	b = appendVarint(b, 17, uint64(e.TTL))
+	b = appendVarint(b, 18, uint64(e.LastDenied))
+	b = appendVarint(b, 19, uint64(e.DenyStreak))
+	return b, nil
+}
+
+func unmarshalEntry[T constraints.Ordered](b []byte) (limiter.Entry[T], error) {
+	var e limiter.Entry[T]
+	err := fields(b, func(num protowire.Number, typ protowire.Type, v uint64, data []byte) error {
+		n := int64(v)
+		switch num {
+		case 1:
+			k, err := unmarshalKey[T](data)
+			if err != nil {
+				return err
+			}
+			e.Key = k
+		case 2:
+			e.WindowStart = n
+		case 3:
+			e.LastSeen = n
+		case 4:
+			e.FirstSeen = n
+		case 5:
+			e.Count = int(n)
+		case 6:
+			e.Denies = int(n)
+		case 7:
+			e.Violations = int(n)
+		case 8:
+			e.ViolationStart = n
+		case 9:
+			e.BannedUntil = n
+		case 10:
+			e.BanLevel = int(n)
+		case 11:
+			e.CooldownUntil = n
+		case 12:
+			e.Tokens = math.Float64frombits(v)
+		case 13:
+			e.Refilled = n
+		case 14:
+			e.Debt = int(n)
+		case 15:
+			e.Carry = int(n)
+		case 16:
+			e.Jitter = n
+		case 17:
+			e.TTL = n
+		case 18:
+			e.LastDenied = n
+		case 19:
+			e.DenyStreak = int(n)
+		}
+		return nil
+	})
+	return e, err
+}
+
+// encode key as Key message by its kind
+func marshalKey[T constraints.Ordered](k T) ([]byte, error) {
+	var b []byte
+	v := reflect.ValueOf(k)
+	switch v.Kind() {
+	case reflect.String:
+		b = protowire.AppendTag(b, 1, protowire.BytesType)
+		b = protowire.AppendString(b, v.String())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		b = protowire.AppendTag(b, 2, protowire.VarintType)
+		b = protowire.AppendVarint(b, protowire.EncodeZigZag(v.Int()))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32,
+		reflect.Uint64, reflect.Uintptr:
+		b = protowire.AppendTag(b, 3, protowire.VarintType)
+		b = protowire.AppendVarint(b, v.Uint())
+	case reflect.Float32, reflect.Float64:
+		b = protowire.AppendTag(b, 4, protowire.Fixed64Type)
+		b = protowire.AppendFixed64(b, math.Float64bits(v.Float()))
+	default:
+		return nil, fmt.Errorf("%w: key kind %s", ErrInvalid, v.Kind())
+	}
+	return b, nil
+}
+
+func unmarshalKey[T constraints.Ordered](b []byte) (T, error) {
+	var k T
+	v := reflect.ValueOf(&k).Elem()
+	err := fields(b, func(num protowire.Number, typ protowire.Type, n uint64, data []byte) error {
+		switch {
+		case num == 1 && v.Kind() == reflect.String:
+			v.SetString(string(data))
+		case num == 2 && v.CanInt():
+			v.SetInt(protowire.DecodeZigZag(n))
+		case num == 3 && v.CanUint():
+			v.SetUint(n)
+		case num == 4 && v.CanFloat():
+			v.SetFloat(math.Float64frombits(n))
+		default:
+			return fmt.Errorf("%w: key field %d for %s key", ErrInvalid, num, v.Kind())
+		}
+		return nil
+	})
+	return k, err
+}
+
+// append varint field if v is not zero
+func appendVarint(b []byte, num protowire.Number, v uint64) []byte {
+	if v == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, v)
+}
+
+// call f for every field of message b
+// v is value of varint and fixed fields
+// data is value of bytes fields
+func fields(
+	b []byte,
+	f func(num protowire.Number, typ protowire.Type, v uint64, data []byte) error,
+) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return fmt.Errorf("%w: %v", ErrInvalid, protowire.ParseError(n))
+		}
+		b = b[n:]
+
+		var (
+			v    uint64
+			data []byte
+		)
+		switch typ {
+		case protowire.VarintType:
+			v, n = protowire.ConsumeVarint(b)
+		case protowire.Fixed64Type:
+			v, n = protowire.ConsumeFixed64(b)
+		case protowire.Fixed32Type:
+			var v32 uint32
+			v32, n = protowire.ConsumeFixed32(b)
+			v = uint64(v32)
+		case protowire.BytesType:
+			data, n = protowire.ConsumeBytes(b)
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, b)
+		}
+		if n < 0 {
+			return fmt.Errorf("%w: %v", ErrInvalid, protowire.ParseError(n))
+		}
+		b = b[n:]
+
+		if err := f(num, typ, v, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
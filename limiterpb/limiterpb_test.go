@@ -0,0 +1,155 @@
+package limiterpb
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/ssleert/limiter"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func full[T any](k T) limiter.Entry[T] {
+	return limiter.Entry[T]{
+		Key:            k,
+		WindowStart:    100,
+		LastSeen:       105,
+		FirstSeen:      50,
+		Count:          7,
+		Denies:         2,
+		LastDenied:     104,
+		DenyStreak:     2,
+		Violations:     3,
+		ViolationStart: 90,
+		BannedUntil:    200,
+		BanLevel:       1,
+		CooldownUntil:  150,
+		Tokens:         2.5,
+		Refilled:       103,
+		Debt:           4,
+		Carry:          1,
+		Jitter:         -3,
+		TTL:            600,
+	}
+}
+
+func roundTrip[T string | int | uint64 | float64](t *testing.T, entries []limiter.Entry[T]) {
+	t.Helper()
+	b, err := Marshal(Snapshot[T]{Version: Version, Time: 42, Entries: entries})
+	if err != nil {
+		t.Fatalf("Marshal() = %v", err)
+	}
+	s, err := Unmarshal[T](b)
+	if err != nil {
+		t.Fatalf("Unmarshal() = %v", err)
+	}
+	if s.Version != Version || s.Time != 42 {
+		t.Fatalf("Unmarshal() header = %d %d, want %d 42", s.Version, s.Time, Version)
+	}
+	if !reflect.DeepEqual(s.Entries, entries) {
+		t.Fatalf("Unmarshal() entries = %+v, want %+v", s.Entries, entries)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		run  func(t *testing.T)
+	}{
+		{"string", func(t *testing.T) {
+			roundTrip(t, []limiter.Entry[string]{full("a"), {Key: "", Count: 1}})
+		}},
+		{"int", func(t *testing.T) {
+			roundTrip(t, []limiter.Entry[int]{full(-5), full(0), {Key: 1 << 40}})
+		}},
+		{"uint", func(t *testing.T) {
+			roundTrip(t, []limiter.Entry[uint64]{full(uint64(1) << 63)})
+		}},
+		{"float", func(t *testing.T) {
+			roundTrip(t, []limiter.Entry[float64]{full(-1.5)})
+		}},
+		{"empty", func(t *testing.T) {
+			roundTrip[string](t, nil)
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, tt.run)
+	}
+}
+
+func TestUnmarshal(t *testing.T) {
+	entry := func(key []byte) []byte {
+		var b []byte
+		b = protowire.AppendTag(b, 1, protowire.BytesType)
+		b = protowire.AppendBytes(b, key)
+		b = protowire.AppendTag(b, 5, protowire.VarintType)
+		b = protowire.AppendVarint(b, 3)
+		return b
+	}
+	snapshot := func(version uint64, entries ...[]byte) []byte {
+		var b []byte
+		b = protowire.AppendTag(b, 1, protowire.VarintType)
+		b = protowire.AppendVarint(b, version)
+		for _, e := range entries {
+			b = protowire.AppendTag(b, 3, protowire.BytesType)
+			b = protowire.AppendBytes(b, e)
+		}
+		return b
+	}
+	strKey := protowire.AppendString(protowire.AppendTag(nil, 1, protowire.BytesType), "a")
+	intKey := protowire.AppendVarint(protowire.AppendTag(nil, 2, protowire.VarintType), 2)
+	unknown := protowire.AppendVarint(protowire.AppendTag(nil, 99, protowire.VarintType), 1)
+
+	tests := []struct {
+		name  string
+		in    []byte
+		err   error
+		count int
+	}{
+		{"unversioned", snapshot(0, entry(strKey)), nil, 3},
+		{"current", snapshot(Version, entry(strKey)), nil, 3},
+		{"unknown field", append(snapshot(Version, entry(strKey)), unknown...), nil, 3},
+		{"newer", snapshot(Version+1, entry(strKey)), ErrInvalid, 0},
+		{"wrong key kind", snapshot(Version, entry(intKey)), ErrInvalid, 0},
+		{"truncated", snapshot(Version, entry(strKey))[:5], ErrInvalid, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := Unmarshal[string](tt.in)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Unmarshal() = %v, want %v", err, tt.err)
+			}
+			if err != nil {
+				return
+			}
+			if len(s.Entries) != 1 || s.Entries[0].Key != "a" || s.Entries[0].Count != tt.count {
+				t.Fatalf("Unmarshal() entries = %+v, want a with count %d", s.Entries, tt.count)
+			}
+			if s.Version != Version {
+				t.Fatalf("Unmarshal() version = %d, want %d", s.Version, Version)
+			}
+		})
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+	l := limiter.New[string](10, 60, 16, 1024, 16)
+	for _, id := range []string{"a", "b", "b"} {
+		l.Try(id)
+	}
+
+	var buf bytes.Buffer
+	if err := Save(&buf, l); err != nil {
+		t.Fatalf("Save() = %v", err)
+	}
+	m := limiter.New[string](10, 60, 16, 1024, 16)
+	if err := Load(&buf, m); err != nil {
+		t.Fatalf("Load() = %v", err)
+	}
+	for id, want := range map[string]int{"a": 1, "b": 2} {
+		if s, ok := m.KeyStats(id); !ok || s.Count != want {
+			t.Fatalf("KeyStats(%q) = %+v, %v, want count %d", id, s, ok, want)
+		}
+	}
+}
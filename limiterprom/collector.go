@@ -0,0 +1,178 @@
+/*
+prometheus collector for limiter stats
+*/
+package limiterprom
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/ssleert/limiter"
+)
+
+// anything with limiter stats
+// every limiter.Limiter[T] implements it
+type Source interface {
+	Stats() limiter.Stats
+	LastCleanDuration() time.Duration
+}
+
+// source with namespaces
+// every limiter.Limiter[T] implements it
+type NamespaceSource interface {
+	NamespaceStats() map[string]limiter.Stats
+}
+
+// variable labels of all metrics
+var nsLabels = []string{"namespace"}
+
+type Collector struct {
+	src Source
+
+	allowed       *prometheus.Desc
+	denied        *prometheus.Desc
+	keys          *prometheus.Desc
+	fillRatio     *prometheus.Desc
+	cleanDuration *prometheus.Desc
+	usage         *prometheus.Desc
+	lockWait      *prometheus.Desc
+	lookup        *prometheus.Desc
+	try           *prometheus.Desc
+}
+
+// make new collector for src
+// all metrics have label with name as value
+// if label is empty "limiter" is used
+//
+// if src is NamespaceSource metrics of every namespace
+// have namespace label with its name, src own
+// metrics have empty namespace
+func New(src Source, label, name string) *Collector {
+	if label == "" {
+		label = "limiter"
+	}
+	labels := prometheus.Labels{label: name}
+
+	return &Collector{
+		src: src,
+		allowed: prometheus.NewDesc(
+			"limiter_allowed_total",
+			"Actions allowed by limiter.",
+			nsLabels, labels,
+		),
+		denied: prometheus.NewDesc(
+			"limiter_denied_total",
+			"Actions denied by limiter.",
+			nsLabels, labels,
+		),
+		keys: prometheus.NewDesc(
+			"limiter_keys",
+			"Keys tracked by limiter.",
+			nsLabels, labels,
+		),
+		fillRatio: prometheus.NewDesc(
+			"limiter_fill_ratio",
+			"Tracked keys divided by max keys before clean up.",
+			nsLabels, labels,
+		),
+		cleanDuration: prometheus.NewDesc(
+			"limiter_clean_duration_seconds",
+			"Duration of last clean up run.",
+			nsLabels, labels,
+		),
+		usage: prometheus.NewDesc(
+			"limiter_window_usage_ratio",
+			"Count of ended key windows divided by their limit.",
+			nsLabels, labels,
+		),
+		lockWait: prometheus.NewDesc(
+			"limiter_lock_wait_seconds",
+			"Lock wait of sampled calls.",
+			nsLabels, labels,
+		),
+		lookup: prometheus.NewDesc(
+			"limiter_lookup_seconds",
+			"Key map lookup of sampled calls.",
+			nsLabels, labels,
+		),
+		try: prometheus.NewDesc(
+			"limiter_try_seconds",
+			"Try() latency of sampled calls.",
+			nsLabels, labels,
+		),
+	}
+}
+
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.allowed
+	ch <- c.denied
+	ch <- c.keys
+	ch <- c.fillRatio
+	ch <- c.cleanDuration
+	ch <- c.usage
+	ch <- c.lockWait
+	ch <- c.lookup
+	ch <- c.try
+}
+
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	c.collect(ch, c.src.Stats(), "")
+	ch <- prometheus.MustNewConstMetric(
+		c.cleanDuration, prometheus.GaugeValue,
+		c.src.LastCleanDuration().Seconds(), "",
+	)
+
+	nss, ok := c.src.(NamespaceSource)
+	if !ok {
+		return
+	}
+	for ns, st := range nss.NamespaceStats() {
+		c.collect(ch, st, ns)
+	}
+}
+
+func (c *Collector) collect(ch chan<- prometheus.Metric, st limiter.Stats, ns string) {
+	var fill float64
+	if st.MaxKeys > 0 {
+		fill = float64(st.Keys) / float64(st.MaxKeys)
+	}
+
+	ch <- prometheus.MustNewConstMetric(
+		c.allowed, prometheus.CounterValue, float64(st.Allowed), ns,
+	)
+	ch <- prometheus.MustNewConstMetric(
+		c.denied, prometheus.CounterValue, float64(st.Denied), ns,
+	)
+	ch <- prometheus.MustNewConstMetric(
+		c.keys, prometheus.GaugeValue, float64(st.Keys), ns,
+	)
+	ch <- prometheus.MustNewConstMetric(
+		c.fillRatio, prometheus.GaugeValue, fill, ns,
+	)
+	ch <- prometheus.MustNewConstSummary(
+		c.usage, st.Usage.Windows, st.Usage.Sum,
+		map[float64]float64{
+			0.5:  st.Usage.P50,
+			0.95: st.Usage.P95,
+			0.99: st.Usage.P99,
+		},
+		ns,
+	)
+	c.timing(ch, c.lockWait, st.LockSamples, st.LockWait, ns)
+	c.timing(ch, c.lookup, st.LookupSamples, st.LookupTime, ns)
+	c.timing(ch, c.try, st.TrySamples, st.TryTime, ns)
+}
+
+// send sampled timing as summary without quantiles
+// so average is sum divided by count
+func (c *Collector) timing(
+	ch chan<- prometheus.Metric,
+	desc *prometheus.Desc,
+	samples uint64,
+	total time.Duration,
+	ns string,
+) {
+	ch <- prometheus.MustNewConstSummary(
+		desc, samples, total.Seconds(), nil, ns,
+	)
+}
@@ -0,0 +1,224 @@
+/*
+golang.org/x/time/rate like api for keys of limiter
+so code written for one *rate.Limiter per key
+can use one self cleaning limiter instead
+*/
+package limiterrate
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/ssleert/limiter"
+	"golang.org/x/exp/constraints"
+)
+
+// max events per second like rate.Limit
+type Limit float64
+
+// delay of reservation that is not OK
+const InfDuration = time.Duration(math.MaxInt64)
+
+// limit of one event every interval
+func Every(interval time.Duration) Limit {
+	if interval <= 0 {
+		return Limit(math.MaxInt32)
+	}
+	return Limit(float64(time.Second) / float64(interval))
+}
+
+// token bucket policy with rate r and burst b
+// like rate.NewLimiter(r, b) has
+//
+// limiter resolution is one second so rates under
+// one per second are rounded to whole seconds per event
+// and bigger ones to whole events per second
+// b < 1 is counted as 1
+func Policy(r Limit, b int) limiter.Policy {
+	if b < 1 {
+		b = 1
+	}
+	if r >= 1 {
+		return limiter.Policy{
+			MaxCount: int(math.Min(math.Round(float64(r)), math.MaxInt32)),
+			Window:   time.Second,
+			Burst:    b,
+		}
+	}
+	secs := 1.0
+	if r > 0 {
+		secs = math.Max(math.Round(1/float64(r)), 1)
+	}
+	return limiter.Policy{
+		MaxCount: 1,
+		Window:   time.Duration(secs) * time.Second,
+		Burst:    b,
+	}
+}
+
+// make limiter with Policy(r, b) for all keys
+func NewLimiter[T constraints.Ordered](r Limit, b int) *limiter.Limiter[T] {
+	p := Policy(r, b)
+	return limiter.Build[T]().
+		Count(p.MaxCount).
+		Window(p.Window).
+		Burst(p.Burst).
+		New()
+}
+
+// one key of limiter with api of *rate.Limiter
+// it's cheap, so make it right where it's used
+type Limiter[T constraints.Ordered] struct {
+	l  *limiter.Limiter[T]
+	id T
+}
+
+// get key id of l
+func Key[T constraints.Ordered](l *limiter.Limiter[T], id T) *Limiter[T] {
+	return &Limiter[T]{l: l, id: id}
+}
+
+// like AllowN(time.Now(), 1)
+func (k *Limiter[T]) Allow() bool {
+	return k.AllowN(time.Now(), 1)
+}
+
+// true if n events may happen at t
+func (k *Limiter[T]) AllowN(t time.Time, n int) bool {
+	return k.l.TryNAt(k.id, n, t)
+}
+
+// like WaitN(ctx, 1)
+func (k *Limiter[T]) Wait(ctx context.Context) error {
+	return k.WaitN(ctx, 1)
+}
+
+// block until n events may happen
+// see limiter.Limiter.WaitN() for errors
+func (k *Limiter[T]) WaitN(ctx context.Context, n int) error {
+	return k.l.WaitN(ctx, k.id, n)
+}
+
+// like ReserveN(time.Now(), 1)
+func (k *Limiter[T]) Reserve() *Reservation {
+	return k.ReserveN(time.Now(), 1)
+}
+
+// reserve n events at t or when key has budget
+// for them, act after Delay() or Cancel() it
+//
+// budget that frees up later is booked in window
+// it frees up in, token buckets have no windows
+// to book, so their reservations are OK only
+// when tokens are there at t
+func (k *Limiter[T]) ReserveN(t time.Time, n int) *Reservation {
+	if k.l.TryNAt(k.id, n, t) {
+		return &Reservation{
+			ok: true,
+			at: t,
+			cancel: func() {
+				k.l.Refund(k.id, n)
+			},
+		}
+	}
+
+	d, ok := k.l.RetryAfter(k.id, n)
+	if !ok {
+		return &Reservation{}
+	}
+	// windows end at whole seconds
+	at := t.Add(d)
+	if sec := at.Truncate(time.Second); sec.Before(at) {
+		at = sec.Add(time.Second)
+	}
+	b, err := k.l.ReserveNAt(k.id, n, at)
+	if err != nil {
+		return &Reservation{}
+	}
+	return &Reservation{
+		ok: true,
+		at: at,
+		cancel: func() {
+			b.Cancel()
+		},
+	}
+}
+
+// events per second of key policy
+func (k *Limiter[T]) Limit() Limit {
+	p, _ := k.l.KeyPolicy(k.id)
+	return Limit(float64(p.MaxCount) / p.Window.Seconds())
+}
+
+// burst of key policy
+// max count for keys without burst
+func (k *Limiter[T]) Burst() int {
+	p, _ := k.l.KeyPolicy(k.id)
+	if p.Burst > 0 {
+		return p.Burst
+	}
+	return p.MaxCount
+}
+
+// events key can make right now
+func (k *Limiter[T]) Tokens() float64 {
+	st, ok := k.l.KeyStats(k.id)
+	if !ok {
+		return float64(k.Burst())
+	}
+	return float64(st.Remaining)
+}
+
+// set own policy of key with rate r
+// and its current burst, see Policy()
+func (k *Limiter[T]) SetLimit(r Limit) {
+	k.l.SetKeyPolicy(k.id, Policy(r, k.Burst()))
+}
+
+// set own policy of key with burst b
+// and its current rate, see Policy()
+func (k *Limiter[T]) SetBurst(b int) {
+	k.l.SetKeyPolicy(k.id, Policy(k.Limit(), b))
+}
+
+// events reserved by ReserveN()
+type Reservation struct {
+	ok     bool
+	at     time.Time
+	cancel func()
+	once   sync.Once
+}
+
+// false if events can never be reserved
+func (r *Reservation) OK() bool {
+	return r.ok
+}
+
+// like DelayFrom(time.Now())
+func (r *Reservation) Delay() time.Duration {
+	return r.DelayFrom(time.Now())
+}
+
+// time to wait from t before acting
+// InfDuration if reservation is not OK
+func (r *Reservation) DelayFrom(t time.Time) time.Duration {
+	if !r.ok {
+		return InfDuration
+	}
+	if d := r.at.Sub(t); d > 0 {
+		return d
+	}
+	return 0
+}
+
+// give reserved events back to key
+// so other calls can use them
+// next calls do nothing
+func (r *Reservation) Cancel() {
+	if !r.ok {
+		return
+	}
+	r.once.Do(r.cancel)
+}
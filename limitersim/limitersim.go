@@ -0,0 +1,229 @@
+/*
+replay of recorded request traces through limiter
+so limits can be tuned offline before shipping them
+*/
+package limitersim
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/ssleert/limiter"
+	"golang.org/x/exp/constraints"
+	"golang.org/x/exp/slices"
+)
+
+// one recorded request
+type Event[T any] struct {
+	Key  T
+	Time time.Time
+	// cost of request, <= 0 means 1
+	N int
+}
+
+// stream of events in time order
+// Next() returns io.EOF after last event
+type Source[T any] interface {
+	Next() (Event[T], error)
+}
+
+type sliceSource[T any] struct {
+	evs []Event[T]
+}
+
+// Source of events from slice
+func Slice[T any](evs []Event[T]) Source[T] {
+	return &sliceSource[T]{evs: evs}
+}
+
+func (s *sliceSource[T]) Next() (Event[T], error) {
+	if len(s.evs) == 0 {
+		return Event[T]{}, io.EOF
+	}
+	ev := s.evs[0]
+	s.evs = s.evs[1:]
+	return ev, nil
+}
+
+type csvSource[T any] struct {
+	r     *csv.Reader
+	parse func(s string) (T, error)
+}
+
+// Source of events from csv with lines like
+// key,unix_time[,n] where unix_time is in
+// seconds and can have fraction like 1700000000.25
+func CSV[T any](r io.Reader, parse func(s string) (T, error)) Source[T] {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.ReuseRecord = true
+	return &csvSource[T]{r: cr, parse: parse}
+}
+
+func (s *csvSource[T]) Next() (Event[T], error) {
+	rec, err := s.r.Read()
+	if err != nil {
+		return Event[T]{}, err
+	}
+	line, _ := s.r.FieldPos(0)
+	if len(rec) < 2 {
+		return Event[T]{}, fmt.Errorf("limitersim: line %d: want key and time", line)
+	}
+
+	key, err := s.parse(rec[0])
+	if err != nil {
+		return Event[T]{}, fmt.Errorf("limitersim: line %d: %w", line, err)
+	}
+	sec, err := strconv.ParseFloat(rec[1], 64)
+	if err != nil {
+		return Event[T]{}, fmt.Errorf("limitersim: line %d: %w", line, err)
+	}
+	ev := Event[T]{
+		Key:  key,
+		Time: time.Unix(0, int64(sec*float64(time.Second))),
+	}
+	if len(rec) > 2 {
+		ev.N, err = strconv.Atoi(rec[2])
+		if err != nil {
+			return Event[T]{}, fmt.Errorf("limitersim: line %d: %w", line, err)
+		}
+	}
+	return ev, nil
+}
+
+// simulation settings
+type Options struct {
+	// seed of limiter rand
+	Seed int64
+	// run full clean up every CleanEvery of trace time
+	// like janitor would, 0 means never so
+	// PeakKeys counts every key seen
+	CleanEvery time.Duration
+}
+
+// result of simulation
+type Report[T constraints.Ordered] struct {
+	// replayed events
+	Events uint64
+	// events with true and false decision
+	Allowed uint64
+	Denied  uint64
+
+	// max keys in limiter map at once
+	PeakKeys int
+	// count of distinct keys in trace
+	UniqueKeys int
+
+	// times of first and last event
+	Start time.Time
+	End   time.Time
+
+	denied map[T]uint64
+}
+
+// part of events that were allowed
+func (r Report[T]) AdmitRate() float64 {
+	if r.Events == 0 {
+		return 0
+	}
+	return float64(r.Allowed) / float64(r.Events)
+}
+
+// part of events that were denied
+func (r Report[T]) DenyRate() float64 {
+	if r.Events == 0 {
+		return 0
+	}
+	return float64(r.Denied) / float64(r.Events)
+}
+
+// n keys with most denied events in whole trace
+// sorted from biggest to smallest
+func (r Report[T]) TopDenied(n int) []limiter.KeyCount[T] {
+	res := make([]limiter.KeyCount[T], 0, len(r.denied))
+	for key, c := range r.denied {
+		res = append(res, limiter.KeyCount[T]{Key: key, Count: int(c)})
+	}
+	slices.SortFunc(res, func(a, b limiter.KeyCount[T]) int {
+		if a.Count != b.Count {
+			return b.Count - a.Count
+		}
+		if a.Key < b.Key {
+			return -1
+		}
+		if a.Key > b.Key {
+			return 1
+		}
+		return 0
+	})
+	if n < len(res) {
+		res = res[:n]
+	}
+	return res
+}
+
+// replay events of src through l
+// l is switched to deterministic mode with clock
+// at event times, so use fresh limiter for every run
+// events out of order are replayed at latest time seen
+//
+// returns report of events read before error
+// and error of src other than io.EOF
+func Run[T constraints.Ordered](
+	l *limiter.Limiter[T],
+	src Source[T],
+	opt Options,
+) (Report[T], error) {
+	var now time.Time
+	l.SetDeterministic(func() time.Time {
+		return now
+	}, opt.Seed)
+
+	r := Report[T]{denied: make(map[T]uint64)}
+	seen := make(map[T]struct{})
+	var lastClean time.Time
+	for {
+		ev, err := src.Next()
+		if errors.Is(err, io.EOF) {
+			return r, nil
+		}
+		if err != nil {
+			return r, err
+		}
+
+		if ev.Time.After(now) {
+			now = ev.Time
+		}
+		if r.Events == 0 {
+			r.Start = now
+			lastClean = now
+		}
+		if opt.CleanEvery > 0 && now.Sub(lastClean) >= opt.CleanEvery {
+			l.CleanContext(context.Background(), 0)
+			lastClean = now
+		}
+
+		n := ev.N
+		if n <= 0 {
+			n = 1
+		}
+		r.Events++
+		if l.TryN(ev.Key, n) {
+			r.Allowed++
+		} else {
+			r.Denied++
+			r.denied[ev.Key]++
+		}
+		seen[ev.Key] = struct{}{}
+		if k := l.Len(); k > r.PeakKeys {
+			r.PeakKeys = k
+		}
+		r.UniqueKeys = len(seen)
+		r.End = now
+	}
+}
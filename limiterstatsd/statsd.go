@@ -0,0 +1,246 @@
+/*
+statsd and dogstatsd emitter for limiter stats
+*/
+package limiterstatsd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math/rand"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ssleert/limiter"
+)
+
+// anything with limiter stats
+// every limiter.Limiter[T] implements it
+type Source interface {
+	Stats() limiter.Stats
+}
+
+// source with namespaces
+// every limiter.Limiter[T] implements it
+type NamespaceSource interface {
+	NamespaceStats() map[string]limiter.Stats
+}
+
+// max udp payload that fits in ethernet mtu
+// with ip and udp headers and some options
+const DefaultMaxPacket = 1432
+
+// interval of Run() if given one is not positive
+const defaultInterval = 10 * time.Second
+
+// pushes limiter stats to statsd over udp
+// counters are sent as deltas since previous Flush()
+// so one flush covers all actions and
+// no per call sampling is needed
+type Emitter struct {
+	conn   net.Conn
+	src    Source
+	prefix string
+	tags   string
+	last   limiter.Stats
+	lastNS map[string]limiter.Stats
+
+	mu        sync.Mutex
+	rate      float64
+	rnd       *rand.Rand
+	maxPacket int
+
+	// current packet and full ones
+	buf     bytes.Buffer
+	packets [][]byte
+}
+
+// make new emitter that sends src stats to addr
+// every metric name starts with prefix
+//
+// tags are dogstatsd tags like "limiter:login"
+// leave them empty for plain statsd
+func New(
+	addr,
+	prefix string,
+	src Source,
+	tags ...string,
+) (*Emitter, error) {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	var t string
+	if len(tags) > 0 {
+		t = "|#" + strings.Join(tags, ",")
+	}
+	if prefix != "" && !strings.HasSuffix(prefix, ".") {
+		prefix += "."
+	}
+
+	return &Emitter{
+		conn:      conn,
+		src:       src,
+		prefix:    prefix,
+		tags:      t,
+		rate:      1,
+		rnd:       rand.New(rand.NewSource(time.Now().UnixNano())),
+		maxPacket: DefaultMaxPacket,
+	}, nil
+}
+
+// send only rate part of flushes, like 0.1 for
+// every tenth one on average, counters of sent ones
+// have |@rate so statsd scales them back up
+// deltas of skipped flushes are dropped
+//
+// rate <= 0 or >= 1 sends every flush
+func (e *Emitter) SetSampleRate(rate float64) {
+	if rate <= 0 || rate > 1 {
+		rate = 1
+	}
+	e.mu.Lock()
+	e.rate = rate
+	e.mu.Unlock()
+}
+
+// split flush into packets of at most n bytes
+// metric longer than n is sent in own packet
+//
+// n <= 0 sets it to DefaultMaxPacket
+func (e *Emitter) SetMaxPacket(n int) {
+	if n <= 0 {
+		n = DefaultMaxPacket
+	}
+	e.mu.Lock()
+	e.maxPacket = n
+	e.mu.Unlock()
+}
+
+// send current stats in packets of max packet size
+// or skip them if flush is not sampled
+//
+// if src is NamespaceSource stats of every namespace
+// are sent too with namespace:name tag for dogstatsd
+// or with prefix.name. prefix for plain statsd
+func (e *Emitter) Flush() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	st := e.src.Stats()
+	sampled := e.rate >= 1 || e.rnd.Float64() < e.rate
+
+	e.buf.Reset()
+	e.packets = e.packets[:0]
+	e.stats(st, e.last, e.prefix, e.tags)
+	e.last = st
+
+	if nss, ok := e.src.(NamespaceSource); ok {
+		cur := nss.NamespaceStats()
+		for ns, st := range cur {
+			prefix, tags := e.prefix, e.tags
+			if tags != "" {
+				tags += ",namespace:" + ns
+			} else {
+				prefix += ns + "."
+			}
+			e.stats(st, e.lastNS[ns], prefix, tags)
+		}
+		e.lastNS = cur
+	}
+	if !sampled {
+		return nil
+	}
+	e.cut()
+
+	var errs []error
+	for _, p := range e.packets {
+		if _, err := e.conn.Write(p); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (e *Emitter) stats(st, last limiter.Stats, prefix, tags string) {
+	e.counter(prefix, tags, "allowed", st.Allowed, last.Allowed)
+	e.counter(prefix, tags, "denied", st.Denied, last.Denied)
+	e.counter(prefix, tags, "inserted", st.Inserted, last.Inserted)
+	e.counter(prefix, tags, "evicted", st.Evicted, last.Evicted)
+	e.counter(prefix, tags, "cleaned", st.Cleaned, last.Cleaned)
+	e.metric(prefix, tags, "keys", strconv.Itoa(st.Keys), "g")
+}
+
+// call Flush() every interval until ctx is done
+// it blocks so run it in your own goroutine
+//
+// flush errors go to onErr, nil onErr ignores them
+// interval <= 0 flushes every 10 seconds
+// returns ctx.Err()
+func (e *Emitter) Run(
+	ctx context.Context,
+	interval time.Duration,
+	onErr func(err error),
+) error {
+	if interval <= 0 {
+		interval = defaultInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			if err := e.Flush(); err != nil && onErr != nil {
+				onErr(err)
+			}
+		}
+	}
+}
+
+// close udp connection
+func (e *Emitter) Close() error {
+	return e.conn.Close()
+}
+
+func (e *Emitter) counter(prefix, tags, name string, cur, last uint64) {
+	typ := "c"
+	if e.rate < 1 {
+		typ += "|@" + strconv.FormatFloat(e.rate, 'g', -1, 64)
+	}
+	e.metric(prefix, tags, name, strconv.FormatUint(cur-last, 10), typ)
+}
+
+// add metric line to current packet
+// full packet is cut before line that doesn't fit
+func (e *Emitter) metric(prefix, tags, name, val, typ string) {
+	n := len(prefix) + len(name) + 1 + len(val) + 1 + len(typ) + len(tags)
+	if e.buf.Len() > 0 && e.buf.Len()+1+n > e.maxPacket {
+		e.cut()
+	}
+	if e.buf.Len() > 0 {
+		e.buf.WriteByte('\n')
+	}
+	e.buf.WriteString(prefix)
+	e.buf.WriteString(name)
+	e.buf.WriteByte(':')
+	e.buf.WriteString(val)
+	e.buf.WriteByte('|')
+	e.buf.WriteString(typ)
+	e.buf.WriteString(tags)
+}
+
+// move current packet to full ones
+func (e *Emitter) cut() {
+	if e.buf.Len() == 0 {
+		return
+	}
+	e.packets = append(e.packets, bytes.Clone(e.buf.Bytes()))
+	e.buf.Reset()
+}
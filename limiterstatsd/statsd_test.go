@@ -0,0 +1,144 @@
+package limiterstatsd_test
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ssleert/limiter"
+	"github.com/ssleert/limiter/limiterstatsd"
+)
+
+// udp server and emitter of limiter that allowed 2 and denied 1
+func emitter(t *testing.T, tags ...string) (*limiterstatsd.Emitter, net.PacketConn) {
+	t.Helper()
+	srv, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Skip("no udp:", err)
+	}
+	t.Cleanup(func() { srv.Close() })
+
+	l := limiter.New[string](2, 60, 16, 1024, 16)
+	for i := 0; i < 3; i++ {
+		l.Try("a")
+	}
+	e, err := limiterstatsd.New(srv.LocalAddr().String(), "app", l, tags...)
+	if err != nil {
+		t.Fatalf("New() err = %v", err)
+	}
+	t.Cleanup(func() { e.Close() })
+	return e, srv
+}
+
+// packets waiting in srv
+func packets(t *testing.T, srv net.PacketConn) []string {
+	t.Helper()
+	var res []string
+	buf := make([]byte, 64<<10)
+	for {
+		srv.SetReadDeadline(time.Now().Add(50 * time.Millisecond))
+		n, _, err := srv.ReadFrom(buf)
+		if err != nil {
+			return res
+		}
+		res = append(res, string(buf[:n]))
+	}
+}
+
+func TestFlush(t *testing.T) {
+	tests := []struct {
+		name      string
+		tags      []string
+		maxPacket int
+		// lines of all packets
+		want []string
+		// min packets
+		packets int
+	}{
+		{
+			name: "statsd",
+			want: []string{
+				"app.allowed:2|c", "app.denied:1|c", "app.inserted:1|c",
+				"app.evicted:0|c", "app.cleaned:0|c", "app.keys:1|g",
+			},
+			packets: 1,
+		},
+		{
+			name:      "split",
+			tags:      []string{"limiter:login"},
+			maxPacket: 60,
+			want: []string{
+				"app.allowed:2|c|#limiter:login", "app.denied:1|c|#limiter:login",
+				"app.inserted:1|c|#limiter:login", "app.evicted:0|c|#limiter:login",
+				"app.cleaned:0|c|#limiter:login", "app.keys:1|g|#limiter:login",
+			},
+			packets: 3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, srv := emitter(t, tt.tags...)
+			e.SetMaxPacket(tt.maxPacket)
+			if err := e.Flush(); err != nil {
+				t.Fatalf("Flush() err = %v", err)
+			}
+
+			ps := packets(t, srv)
+			if len(ps) < tt.packets {
+				t.Fatalf("packets = %q, want at least %d", ps, tt.packets)
+			}
+			var lines []string
+			for _, p := range ps {
+				if tt.maxPacket > 0 && len(p) > tt.maxPacket {
+					t.Fatalf("packet %q is longer than %d", p, tt.maxPacket)
+				}
+				lines = append(lines, strings.Split(p, "\n")...)
+			}
+			if strings.Join(lines, " ") != strings.Join(tt.want, " ") {
+				t.Fatalf("lines = %q, want %q", lines, tt.want)
+			}
+		})
+	}
+}
+
+func TestSampleRate(t *testing.T) {
+	e, srv := emitter(t)
+	e.SetSampleRate(0.5)
+
+	const flushes = 200
+	for i := 0; i < flushes; i++ {
+		if err := e.Flush(); err != nil {
+			t.Fatalf("Flush() err = %v", err)
+		}
+	}
+	ps := packets(t, srv)
+	if len(ps) < flushes/4 || len(ps) > flushes*3/4 {
+		t.Fatalf("sent %d of %d flushes, want about half", len(ps), flushes)
+	}
+	for _, p := range ps {
+		if !strings.Contains(p, "allowed:") || !strings.Contains(p, "|c|@0.5") {
+			t.Fatalf("packet %q has no sampled counters", p)
+		}
+		if strings.Contains(p, "|g|@") {
+			t.Fatalf("packet %q has sampled gauge", p)
+		}
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	e, _ := emitter(t)
+	e.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var got error
+	e.Run(ctx, time.Millisecond, func(err error) {
+		got = err
+		cancel()
+	})
+	if got == nil {
+		t.Fatal("Run() reported no error of closed connection")
+	}
+}
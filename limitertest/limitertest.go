@@ -0,0 +1,144 @@
+/*
+fake clock and assertions for tests
+of code that uses limiter, so they don't
+sleep real seconds
+*/
+package limitertest
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ssleert/limiter"
+)
+
+// default start time of Clock
+var Epoch = time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// anything with injectable clock
+// every limiter.Limiter[T], limiter.Breaker[T]
+// and limiter.Debouncer[T] implements it
+type Clocked interface {
+	SetClock(now func() time.Time)
+}
+
+// clock that moves only when told to
+// safe for concurrent use
+type Clock struct {
+	mu sync.Mutex
+	t  time.Time
+}
+
+// make new clock at start
+// zero start means Epoch
+func NewClock(start time.Time) *Clock {
+	if start.IsZero() {
+		start = Epoch
+	}
+	return &Clock{t: start}
+}
+
+// make new clock at Epoch and attach it to ls
+func Use(ls ...Clocked) *Clock {
+	c := NewClock(time.Time{})
+	c.Attach(ls...)
+	return c
+}
+
+// make ls use clock for time
+func (c *Clock) Attach(ls ...Clocked) {
+	for _, l := range ls {
+		l.SetClock(c.Now)
+	}
+}
+
+// current time of clock
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.t
+}
+
+// move clock forward by d
+// and return new time
+func (c *Clock) Advance(d time.Duration) time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.t = c.t.Add(d)
+	return c.t
+}
+
+// set clock to t, even backwards
+func (c *Clock) Set(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.t = t
+}
+
+// move clock forward by d n times
+// and call f after every step
+func (c *Clock) Step(d time.Duration, n int, f func(now time.Time)) {
+	for i := 0; i < n; i++ {
+		now := c.Advance(d)
+		if f != nil {
+			f(now)
+		}
+	}
+}
+
+// anything that decides on keys
+// every limiter.Limiter[T], limiter.Breaker[T]
+// and limiter.Debouncer[T] implements it
+type Tryer[T comparable] interface {
+	Try(id T) bool
+}
+
+// anything with state of keys
+// every limiter.Limiter[T], limiter.Breaker[T]
+// and limiter.Debouncer[T] implements it
+type Stater[T comparable] interface {
+	KeyStats(id T) (limiter.KeyState, bool)
+}
+
+// fail t unless next n actions of key are allowed
+// actions are taken, so they count towards limit
+func AssertAllowed[T comparable](t testing.TB, l Tryer[T], id T, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		if !l.Try(id) {
+			t.Fatalf("limitertest: action %d of %v denied, want %d allowed", i+1, id, n)
+		}
+	}
+}
+
+// fail t unless next action of key is denied
+func AssertDenied[T comparable](t testing.TB, l Tryer[T], id T) {
+	t.Helper()
+	if l.Try(id) {
+		t.Fatalf("limitertest: action of %v allowed, want denied", id)
+	}
+}
+
+// fail t unless key gets exactly n actions
+// and the next one is denied
+func AssertDeniedAfter[T comparable](t testing.TB, l Tryer[T], id T, n int) {
+	t.Helper()
+	AssertAllowed(t, l, id, n)
+	if l.Try(id) {
+		t.Fatalf("limitertest: action %d of %v allowed, want denied after %d", n+1, id, n)
+	}
+}
+
+// fail t unless key has want actions left
+// untracked key fails too
+func AssertRemaining[T comparable](t testing.TB, l Stater[T], id T, want int) {
+	t.Helper()
+	st, ok := l.KeyStats(id)
+	if !ok {
+		t.Fatalf("limitertest: key %v is not tracked", id)
+	}
+	if st.Remaining != want {
+		t.Fatalf("limitertest: key %v has %d remaining, want %d", id, st.Remaining, want)
+	}
+}
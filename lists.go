@@ -0,0 +1,114 @@
+package limiter
+
+import (
+	"github.com/ssleert/mu"
+)
+
+// let keys always pass Try() without tracking them
+func (l *Limiter[T]) AllowKeys(ids ...T) {
+	defer l.moveBack(ids...)
+	mu.ExecMutex(&l.mu, func() {
+		if l.allowlist == nil {
+			l.allowlist = make(map[T]struct{}, len(ids))
+		}
+		for _, id := range ids {
+			l.allowlist[id] = struct{}{}
+			l.remove(id)
+		}
+	})
+}
+
+// remove keys from allowlist
+func (l *Limiter[T]) RemoveAllowed(ids ...T) {
+	defer l.moveBack(ids...)
+	mu.ExecMutex(&l.mu, func() {
+		for _, id := range ids {
+			delete(l.allowlist, id)
+		}
+	})
+}
+
+// make keys always fail Try()
+// denylist is checked before allowlist
+// denied keys lose trust, see SetTrust()
+func (l *Limiter[T]) DenyKeys(ids ...T) {
+	defer l.moveBack(ids...)
+	mu.ExecMutex(&l.mu, func() {
+		d := make(map[T]struct{}, len(l.denied())+len(ids))
+		for id := range l.denied() {
+			d[id] = struct{}{}
+		}
+		for _, id := range ids {
+			d[id] = struct{}{}
+		}
+		l.denylist.Store(&d)
+		l.distrust(ids...)
+	})
+}
+
+// remove keys from denylist
+func (l *Limiter[T]) RemoveDenied(ids ...T) {
+	defer l.moveBack(ids...)
+	mu.ExecMutex(&l.mu, func() {
+		d := make(map[T]struct{}, len(l.denied()))
+		for id := range l.denied() {
+			d[id] = struct{}{}
+		}
+		for _, id := range ids {
+			delete(d, id)
+		}
+		l.denylist.Store(&d)
+	})
+}
+
+// true if key is in denylist
+// it doesn't need l.mu
+func (l *Limiter[T]) deniedKey(id T) bool {
+	_, ok := l.denied()[id]
+	return ok
+}
+
+// denied keys, must not be changed
+func (l *Limiter[T]) denied() map[T]struct{} {
+	if p := l.denylist.Load(); p != nil {
+		return *p
+	}
+	return nil
+}
+
+// let keys for which f returns true always pass Try()
+// f is called before limiter lock and key state
+// so exempt keys never get into map
+//
+// nil f removes predicate
+func (l *Limiter[T]) SetExempt(f func(id T) bool) {
+	if f == nil {
+		l.exempt.Store(nil)
+		return
+	}
+	l.exempt.Store(&f)
+}
+
+// true if key is exempt
+func (l *Limiter[T]) exempted(id T) bool {
+	f := l.exempt.Load()
+	return f != nil && (*f)(id)
+}
+
+// decision for listed key
+// returns false if key is not listed
+//
+// l.mu must be held
+func (l *Limiter[T]) listed(id T) (ok bool, listed bool) {
+	if d := l.denied(); len(d) > 0 {
+		if _, found := d[id]; found {
+			return false, true
+		}
+	}
+	if len(l.allowlist) > 0 {
+		if _, found := l.allowlist[id]; found {
+			return true, true
+		}
+	}
+	return false, false
+}
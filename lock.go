@@ -0,0 +1,79 @@
+package limiter
+
+import (
+	"time"
+
+	"golang.org/x/exp/constraints"
+)
+
+// l.mu that measures time spent
+// waiting for lock on sampled calls
+// and map lookups made under it
+type timedMutex[T constraints.Ordered] struct {
+	l       *Limiter[T]
+	sampled bool
+}
+
+func (m timedMutex[T]) Lock() {
+	if !m.sampled {
+		m.l.mu.Lock()
+		return
+	}
+
+	start := m.l.wallNow()
+	m.l.mu.Lock()
+	m.l.stats.lockWait.Add(int64(m.l.wallNow().Sub(start)))
+	m.l.stats.lockSamples.Add(1)
+	m.l.timing = true
+}
+
+func (m timedMutex[T]) Unlock() {
+	if m.sampled {
+		m.l.timing = false
+	}
+	m.l.mu.Unlock()
+}
+
+// measure lock wait, map lookup and whole Try()
+// time on every nth Try() call, results are in
+// LockWait, LookupTime and TryTime of Stats()
+//
+// n <= 0 disables measuring
+func (l *Limiter[T]) SetTimingSampling(n int) {
+	l.lockEvery.Store(int64(n))
+}
+
+// Deprecated: use SetTimingSampling(), it measures
+// lock wait with other timings
+func (l *Limiter[T]) SetLockSampling(n int) {
+	l.SetTimingSampling(n)
+}
+
+// true if timings of this call must be measured
+func (l *Limiter[T]) sampled() bool {
+	every := l.lockEvery.Load()
+	return every > 0 && l.lockCalls.Add(1)%uint64(every) == 0
+}
+
+// record time of sampled Try() started at start
+func (l *Limiter[T]) tryDone(start time.Time) {
+	l.stats.tryTime.Add(int64(l.wallNow().Sub(start)))
+	l.stats.trySamples.Add(1)
+}
+
+// get() that is timed on sampled calls
+//
+// l.mu must be held
+func (l *Limiter[T]) lookup(id T) (action, bool) {
+	if !l.timing {
+		return l.get(id)
+	}
+	start := l.wallNow()
+	p, ok := l.m[id]
+	l.stats.lookupTime.Add(int64(l.wallNow().Sub(start)))
+	l.stats.lookupSamples.Add(1)
+	if !ok {
+		return action{}, false
+	}
+	return p.unpack(), true
+}
@@ -0,0 +1,33 @@
+package limiter
+
+import (
+	"github.com/ssleert/mu"
+)
+
+// func used to log limiter events
+// with key value pairs in args
+//
+// methods of *slog.Logger like logger.Info fit it
+type LogFunc func(msg string, args ...any)
+
+type loggers struct {
+	deny  LogFunc
+	evict LogFunc
+	clean LogFunc
+}
+
+// log denied actions, evicted keys and clean up runs
+// nil func disables its events
+//
+// pick level for each event with slog like this
+// l.SetLogger(logger.Warn, logger.Info, logger.Debug)
+func (l *Limiter[T]) SetLogger(deny, evict, clean LogFunc) {
+	defer l.moveBack()
+	mu.ExecMutex(&l.mu, func() {
+		l.logs = loggers{
+			deny:  deny,
+			evict: evict,
+			clean: clean,
+		}
+	})
+}
@@ -0,0 +1,63 @@
+package limiter
+
+import (
+	"unsafe"
+
+	"github.com/ssleert/mu"
+)
+
+// go map buckets hold 8 entries with
+// 1 byte hash each and overflow pointer
+// and are 6.5/8 full on average
+const (
+	bucketEntries = 8
+	bucketLoad    = 6.5
+)
+
+// approximate bytes held by limiter entries
+// map buckets are counted for max entries since
+// map was made, as go maps don't shrink, plus
+// data of string keys
+//
+// it's an estimate for capacity dashboards
+// not an exact heap size
+func (l *Limiter[T]) EstimatedBytes() int {
+	var n float64
+	mu.ExecRWMutex(&l.mu, func() {
+		var (
+			key     T
+			entry   = unsafe.Sizeof(packed{})
+			pol     = unsafe.Sizeof(policy{})
+			keySz   = unsafe.Sizeof(key)
+			buckets = func(entries int, size uintptr) float64 {
+				per := uintptr(1) + keySz + size
+				overhead := float64(unsafe.Sizeof(uintptr(0))) / bucketEntries
+				return float64(entries) * (float64(per) + overhead) *
+					bucketEntries / bucketLoad
+			}
+		)
+
+		peak := l.shrink.peak
+		if len(l.m) > peak {
+			peak = len(l.m)
+		}
+		n += buckets(peak, entry)
+		n += buckets(len(l.resolved), pol)
+		n += buckets(len(l.keyPolicies), pol)
+		n += buckets(len(l.tomb.m), unsafe.Sizeof(tomb{}))
+		for _, bs := range l.bookings {
+			n += float64(uintptr(len(bs)) * unsafe.Sizeof(booking{}))
+		}
+		n += buckets(len(l.bookings), unsafe.Sizeof([]booking(nil)))
+
+		if _, ok := any(key).(string); ok {
+			for id := range l.m {
+				n += float64(len(any(id).(string)))
+			}
+			for id := range l.keyPolicies {
+				n += float64(len(any(id).(string)))
+			}
+		}
+	})
+	return int(n)
+}
@@ -0,0 +1,96 @@
+package limiter
+
+import (
+	"github.com/ssleert/mu"
+	"golang.org/x/exp/maps"
+)
+
+// fold entries of other limiter into l
+// e.g. when draining one instance into other
+//
+// counts, denials and debt are summed
+// earliest window start and first time are kept
+// and latest ban, cooldown and last time
+// bucket tokens spent by both are spent in l
+//
+// returns count of merged entries
+func (l *Limiter[T]) Merge(other *Limiter[T]) int {
+	if other == l {
+		return 0
+	}
+
+	other.moveBack()
+	var m map[T]packed
+	mu.ExecRWMutex(&other.mu, func() {
+		m = maps.Clone(other.m)
+	})
+	l.moveBackOf(maps.Keys(m))
+
+	timeNow := l.now().Unix()
+	mu.ExecMutex(&l.mu, func() {
+		for id, pb := range m {
+			b := pb.unpack()
+			a, ok := l.get(id)
+			if !ok {
+				l.set(id, b)
+				continue
+			}
+			l.set(id, l.policyOf(id).merge(a, b, timeNow))
+		}
+	})
+	return len(m)
+}
+
+// entry with actions of both a and b
+func (p policy) merge(a, b action, timeNow int64) action {
+	if p.burst > 0 {
+		ta := p.tokens(a, timeNow)
+		tb := p.tokens(b, timeNow)
+		a.tokens = ta + tb - float64(p.burst)
+		a.refilled = timeNow
+	}
+
+	a.deltaTime = min64(a.deltaTime, b.deltaTime)
+	a.firstTime = min64(a.firstTime, b.firstTime)
+	a.lastTime = max64(a.lastTime, b.lastTime)
+	a.lastDenied = max64(a.lastDenied, b.lastDenied)
+	a.count += b.count
+	a.denies += b.denies
+	if b.streak > a.streak {
+		a.streak = b.streak
+	}
+	a.debt += b.debt
+	if b.carry > a.carry {
+		a.carry = b.carry
+	}
+
+	switch {
+	case a.violations == 0:
+		a.violationStart = b.violationStart
+	case b.violations > 0:
+		a.violationStart = min64(a.violationStart, b.violationStart)
+	}
+	a.violations += b.violations
+	a.bannedUntil = max64(a.bannedUntil, b.bannedUntil)
+	if b.banLevel > a.banLevel {
+		a.banLevel = b.banLevel
+	}
+	a.cooldownUntil = max64(a.cooldownUntil, b.cooldownUntil)
+	a.ttl = max64(a.ttl, b.ttl)
+	a.pacedUntil = max64(a.pacedUntil, b.pacedUntil)
+	return a
+}
+
+func min64(a, b int64) int64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func max64(a, b int64) int64 {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,63 @@
+package limiter
+
+import (
+	"github.com/ssleert/mu"
+)
+
+// attach v to tracked key, e.g. user or session of it
+// v lives while key entry lives, so it's removed
+// with entry on clean up, eviction and Reset()
+//
+// v is passed in KeyState.Meta of callbacks
+// and KeyStats(), Event.Meta and Entry.Meta
+// so keep it small, nil v removes it
+//
+// returns false if key is not tracked
+func (l *Limiter[T]) SetMeta(id T, v any) bool {
+	if hk := l.hotOf(id); hk != nil {
+		return hk.l.SetMeta(id, v)
+	}
+	var ok bool
+	mu.ExecMutex(&l.mu, func() {
+		if _, ok = l.m[id]; ok {
+			l.setMeta(id, v)
+		}
+	})
+	return ok
+}
+
+// get value attached to key by SetMeta()
+func (l *Limiter[T]) Meta(id T) (any, bool) {
+	if hk := l.hotOf(id); hk != nil {
+		return hk.l.Meta(id)
+	}
+	var (
+		v  any
+		ok bool
+	)
+	mu.ExecRWMutex(&l.mu, func() {
+		v, ok = l.meta[id]
+	})
+	return v, ok
+}
+
+// l.mu must be held
+func (l *Limiter[T]) setMeta(id T, v any) {
+	if v == nil {
+		delete(l.meta, id)
+		return
+	}
+	if l.meta == nil {
+		l.meta = make(map[T]any)
+	}
+	l.meta[id] = v
+}
+
+// Entry of key with its value
+//
+// l.mu must be held
+func (l *Limiter[T]) export(id T, a action) Entry[T] {
+	e := entryOf(id, a)
+	e.Meta = l.meta[id]
+	return e
+}
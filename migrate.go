@@ -0,0 +1,40 @@
+package limiter
+
+import (
+	"errors"
+	"fmt"
+)
+
+// version of snapshot format written by Save()
+// snapshots without version are read as 1
+const SnapshotVersion = 1
+
+var ErrSnapshotVersion = errors.New("limiter: unknown snapshot version")
+
+// migrations[i] turns entry of version i+1 into version i+2
+// format changes that new fields with zero values can't
+// express bump SnapshotVersion and append migration here
+var migrations = [SnapshotVersion - 1]func(a *action){}
+
+// bring entries written in version to SnapshotVersion in place
+// version 0 means snapshot without version and is read as 1
+//
+// returns ErrSnapshotVersion if version is newer
+// than format known by this package
+func Migrate[T any](version int, entries []Entry[T]) error {
+	if version == 0 {
+		version = 1
+	}
+	if version < 1 || version > SnapshotVersion {
+		return fmt.Errorf("%w: %d", ErrSnapshotVersion, version)
+	}
+	for v := version; v < SnapshotVersion; v++ {
+		for i, e := range entries {
+			a := e.action()
+			migrations[v-1](&a)
+			entries[i] = entryOf(e.Key, a)
+			entries[i].Meta = e.Meta
+		}
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package limiter_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ssleert/limiter"
+)
+
+func TestMigrate(t *testing.T) {
+	tests := []struct {
+		name    string
+		version int
+		err     error
+	}{
+		{"unversioned", 0, nil},
+		{"first", 1, nil},
+		{"current", limiter.SnapshotVersion, nil},
+		{"negative", -1, limiter.ErrSnapshotVersion},
+		{"newer", limiter.SnapshotVersion + 1, limiter.ErrSnapshotVersion},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries := []limiter.Entry[string]{
+				{Key: "a", WindowStart: 10, LastSeen: 12, Count: 3, Denies: 1, Meta: "m"},
+			}
+			want := entries[0]
+
+			err := limiter.Migrate(tt.version, entries)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Migrate(%d) = %v, want %v", tt.version, err, tt.err)
+			}
+			if err == nil && entries[0] != want {
+				t.Fatalf("Migrate(%d) changed entry to %+v, want %+v", tt.version, entries[0], want)
+			}
+		})
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		err  error
+		keys map[string]int
+	}{
+		{
+			name: "unversioned",
+			in:   `{"entries":[{"key":"a","window_start":1,"last_seen":1,"count":2}]}`,
+			keys: map[string]int{"a": 2},
+		},
+		{
+			name: "current",
+			in:   `{"version":1,"entries":[{"key":"a","window_start":1,"last_seen":1,"count":1},{"key":"b","window_start":1,"last_seen":1,"count":4}]}`,
+			keys: map[string]int{"a": 1, "b": 4},
+		},
+		{
+			name: "newer",
+			in:   `{"version":99,"entries":[{"key":"a","count":1}]}`,
+			err:  limiter.ErrSnapshotVersion,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := func() time.Time { return time.Unix(2, 0) }
+			l := limiter.New[string](10, 60, 16, 1024, 16)
+			l.SetClock(now)
+			err := l.Load(strings.NewReader(tt.in))
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Load() = %v, want %v", err, tt.err)
+			}
+			for id, count := range tt.keys {
+				s, ok := l.KeyStats(id)
+				if !ok || s.Count != count {
+					t.Fatalf("KeyStats(%q) = %+v, %v, want count %d", id, s, ok, count)
+				}
+			}
+
+			var buf bytes.Buffer
+			if err := l.Save(&buf); err != nil {
+				t.Fatalf("Save() = %v", err)
+			}
+			m := limiter.New[string](10, 60, 16, 1024, 16)
+			if err := m.Load(&buf); err != nil {
+				t.Fatalf("Load(Save()) = %v", err)
+			}
+			if got, want := len(m.Snapshot()), len(tt.keys); got != want {
+				t.Fatalf("round trip has %d keys, want %d", got, want)
+			}
+		})
+	}
+}
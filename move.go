@@ -0,0 +1,91 @@
+package limiter
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ssleert/mu"
+)
+
+var ErrNoQuota = errors.New("limiter: not enough quota to move")
+
+// move n unused units of current window from key
+// to other key, e.g. for temporary boost of tenant
+// from is charged like it spent them and to
+// can spend them over its limit until its
+// window ends, bucket keys can't go over burst
+//
+// move is sent to event stream as EventMovedOut
+// of from and EventMovedIn of to
+// n < 1 is counted as 1
+//
+// new keys are let in map by full policy like Try()
+// does, returns ErrMapFull if FullDeny keeps them out
+// and ErrNoQuota if from has less than n units
+func (l *Limiter[T]) MoveQuota(from, to T, n int) error {
+	if n < 1 {
+		n = 1
+	}
+	if from == to {
+		return nil
+	}
+	l.moveBack(from, to)
+	timeNow := l.now().Unix()
+
+	var err error
+	mu.ExecMutex(&l.mu, func() {
+		pf := l.policyOf(from)
+		a, foundFrom := l.get(from)
+		if !foundFrom {
+			a = pf.fresh(timeNow)
+		}
+		if left := l.keyState(a, pf, timeNow).Remaining; left < n {
+			err = fmt.Errorf("%w: %d of %d", ErrNoQuota, left, n)
+			return
+		}
+
+		pt := l.policyOf(to)
+		b, foundTo := l.get(to)
+		if !foundTo {
+			b = pt.fresh(timeNow)
+		}
+		added := 0
+		if !foundFrom {
+			added++
+		}
+		if !foundTo {
+			added++
+		}
+		if !l.makeSpace(added, timeNow, from, to) {
+			err = ErrMapFull
+			return
+		}
+		a = l.roll(a, pf, timeNow)
+		b = l.roll(b, pt, timeNow)
+
+		if pf.burst > 0 {
+			a.tokens = pf.tokens(a, timeNow) - float64(n)
+			a.refilled = timeNow
+		}
+		a.count += n
+		if pt.burst > 0 {
+			b.tokens = pt.tokens(b, timeNow) + float64(n)
+			b.refilled = timeNow
+		} else {
+			b.carry += n
+		}
+		l.set(from, a)
+		l.set(to, b)
+
+		if l.events != nil {
+			at := time.Unix(timeNow, 0)
+			l.send(Event[T]{Kind: EventMovedOut, Key: from, Peer: to, N: n, Time: at})
+			l.send(Event[T]{Kind: EventMovedIn, Key: to, Peer: from, N: n, Time: at})
+		}
+	})
+	if err == nil {
+		l.denyCache.forget(to)
+	}
+	return err
+}
@@ -0,0 +1,81 @@
+package limiter_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ssleert/limiter"
+	"github.com/ssleert/limiter/limitertest"
+)
+
+func TestMoveQuota(t *testing.T) {
+	tests := []struct {
+		name string
+		n    int
+		want error
+		// actions of a and b allowed after move
+		a, b int
+	}{
+		{name: "moves", n: 2, a: 1, b: 5},
+		{name: "not enough", n: 4, want: limiter.ErrNoQuota, a: 3, b: 3},
+		{name: "zero is one", n: 0, a: 2, b: 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := limiter.New[string](3, 60, 16, 1024, 16)
+			limitertest.Use(l)
+
+			if err := l.MoveQuota("a", "b", tt.n); !errors.Is(err, tt.want) {
+				t.Fatalf("MoveQuota(a, b, %d) err = %v, want %v", tt.n, err, tt.want)
+			}
+			limitertest.AssertAllowed[string](t, l, "a", tt.a)
+			limitertest.AssertDenied[string](t, l, "a")
+			limitertest.AssertAllowed[string](t, l, "b", tt.b)
+			limitertest.AssertDenied[string](t, l, "b")
+		})
+	}
+}
+
+func TestMoveQuotaFullMap(t *testing.T) {
+	tests := []struct {
+		name     string
+		full     limiter.FullPolicy
+		from, to string
+		want     error
+		// keys in map after move
+		keys []string
+	}{
+		{"deny new", limiter.FullDeny, "a", "b", limiter.ErrMapFull, []string{"a", "c"}},
+		{"deny both new", limiter.FullDeny, "x", "y", limiter.ErrMapFull, []string{"a", "c"}},
+		{"deny known", limiter.FullDeny, "a", "c", nil, []string{"a", "c"}},
+		{"allow new", limiter.FullAllow, "a", "b", nil, []string{"a", "b", "c"}},
+		{"evict other", limiter.FullEvict, "a", "b", nil, []string{"a", "b"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := limitertest.NewClock(time.Unix(1000, 0))
+			l := limiter.New[string](3, 60, 2, 2, 16)
+			l.SetDeterministic(c.Now, 1)
+			l.SetFullPolicy(tt.full)
+			limitertest.AssertAllowed[string](t, l, "a", 1)
+			limitertest.AssertAllowed[string](t, l, "c", 1)
+
+			if err := l.MoveQuota(tt.from, tt.to, 1); !errors.Is(err, tt.want) {
+				t.Fatalf("MoveQuota(%s, %s) err = %v, want %v", tt.from, tt.to, err, tt.want)
+			}
+			got := make(map[string]bool)
+			for _, e := range l.Snapshot() {
+				got[e.Key] = true
+			}
+			if len(got) != len(tt.keys) {
+				t.Fatalf("keys = %v, want %v", got, tt.keys)
+			}
+			for _, k := range tt.keys {
+				if !got[k] {
+					t.Fatalf("keys = %v, want %v", got, tt.keys)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,300 @@
+package limiter
+
+import (
+	"sort"
+	"time"
+	"unsafe"
+
+	"golang.org/x/exp/constraints"
+)
+
+// try id of a and id of b as one action
+// it is allowed only if both keys have budget
+// and then units are spent from both or none
+//
+// a and b are locked together in fixed order
+// so no other Try() sees only one of them spent
+// hooks and shadow limiters are not run
+func TryBoth[A, B constraints.Ordered](
+	a *Limiter[A], idA A,
+	b *Limiter[B], idB B,
+) bool {
+	return TryAll(a.Check(idA, 1), b.Check(idB, 1))
+}
+
+// try all ids as one action
+// it is allowed only if every key has budget
+// and then units are spent from all or none
+//
+// same id can be given many times
+// and its units are summed
+// hooks and shadow limiters are not run
+func (l *Limiter[T]) TryMany(ids ...T) bool {
+	cs := make([]Check, len(ids))
+	for i, id := range ids {
+		cs[i] = l.Check(id, 1)
+	}
+	return TryAll(cs...)
+}
+
+// part of action over many keys and limiters
+// made by Limiter.Check()
+type Check interface {
+	// part done before locks are held
+	prepare()
+
+	// address of locked limiter
+	addr() uintptr
+	// address of limiter key belongs to
+	// limiters are locked before own
+	// limiters of their isolated keys
+	root() uintptr
+	lock()
+	unlock()
+
+	// check of units of both if o is
+	// for same key of same limiter
+	join(o Check) (Check, bool)
+
+	// spend units and return true if key has budget
+	// lock must be held
+	reserve() bool
+	// keep reserved units if action is allowed
+	// or give them back, lock must be held
+	end(ok bool)
+
+	// handle outcome after all locks are released
+	// reserved is false if key was not tried
+	finish(reserved, ok bool)
+}
+
+// try all checks as one action, e.g. per user
+// and global limiters, it is allowed only if
+// every check passes and then units are spent
+// from all limiters or none
+//
+// limiters are locked together in order of their
+// addresses, so concurrent TryAll() calls with
+// same limiters in any order never deadlock
+// isolated keys are tried under their own lock
+// checks of same key of same limiter are summed
+// hooks and shadow limiters are not run
+func TryAll(cs ...Check) bool {
+	cs = joined(cs)
+	for _, c := range cs {
+		c.prepare()
+	}
+
+	locks := make([]Check, len(cs))
+	copy(locks, cs)
+	sort.Slice(locks, func(i, j int) bool {
+		a, b := locks[i], locks[j]
+		if a.root() != b.root() {
+			return a.root() < b.root()
+		}
+		// same root, its own lock goes first
+		ra, rb := a.addr() == a.root(), b.addr() == b.root()
+		if ra != rb {
+			return ra
+		}
+		return a.addr() < b.addr()
+	})
+	for i, c := range locks {
+		if i == 0 || c.addr() != locks[i-1].addr() {
+			c.lock()
+		}
+	}
+
+	tried := len(cs)
+	ok := true
+	for i, c := range cs {
+		if !c.reserve() {
+			tried = i + 1
+			ok = false
+			break
+		}
+	}
+	for _, c := range cs[:tried] {
+		c.end(ok)
+	}
+
+	for i := len(locks) - 1; i >= 0; i-- {
+		if i == 0 || locks[i].addr() != locks[i-1].addr() {
+			locks[i].unlock()
+		}
+	}
+
+	for i, c := range cs {
+		c.finish(i < tried, ok)
+	}
+	return ok
+}
+
+// checks with units of same key
+// of same limiter summed into one
+func joined(cs []Check) []Check {
+	res := make([]Check, 0, len(cs))
+next:
+	for _, c := range cs {
+		for i, r := range res {
+			if j, ok := r.join(c); ok {
+				res[i] = j
+				continue next
+			}
+		}
+		res = append(res, c)
+	}
+	return res
+}
+
+// check of n units for id of l
+type check[T constraints.Ordered] struct {
+	l  *Limiter[T]
+	id T
+	n  int
+	// own state of isolated key
+	// its limiter is locked instead of l
+	hk *hotKey[T]
+	// key was isolated or moved back
+	// after prepare() and is not tried
+	moved bool
+
+	pause    PauseMode
+	exempt   bool
+	rp       policy
+	resolved bool
+	now      time.Time
+	timeNow  int64
+	sampled  bool
+
+	prev    packed
+	existed bool
+	o       outcome[T]
+	pd      pending[T]
+}
+
+// make check of n units for id to use with TryAll()
+// check holds state of TryAll() call, so don't
+// use it in many concurrent calls
+// n < 1 is counted as 1
+func (l *Limiter[T]) Check(id T, n int) Check {
+	if n < 1 {
+		n = 1
+	}
+	return &check[T]{
+		l:  l,
+		id: id,
+		n:  n,
+	}
+}
+
+func (c *check[T]) prepare() {
+	l := c.l
+	c.pause, _ = l.Paused()
+	c.exempt = c.pause == PauseAllow || l.exempted(c.id)
+	if !c.exempt {
+		c.rp, c.resolved = l.resolve(c.id)
+	}
+	c.now = l.now()
+	c.timeNow = c.now.Unix()
+	c.hk = l.hotOf(c.id)
+}
+
+// limiter that has state of key
+func (c *check[T]) owner() *Limiter[T] {
+	if c.hk != nil {
+		return c.hk.l
+	}
+	return c.l
+}
+
+func (c *check[T]) join(o Check) (Check, bool) {
+	d, ok := o.(*check[T])
+	if !ok || d.l != c.l || d.id != c.id {
+		return nil, false
+	}
+	return c.l.Check(c.id, c.n+d.n), true
+}
+
+func (c *check[T]) addr() uintptr {
+	return uintptr(unsafe.Pointer(c.owner()))
+}
+
+func (c *check[T]) root() uintptr {
+	return uintptr(unsafe.Pointer(c.l))
+}
+
+func (c *check[T]) lock() {
+	l := c.owner()
+	c.sampled = l.sampled()
+	timedMutex[T]{l, c.sampled}.Lock()
+}
+
+func (c *check[T]) unlock() {
+	timedMutex[T]{c.owner(), c.sampled}.Unlock()
+}
+
+func (c *check[T]) reserve() bool {
+	if c.pause == PauseDeny {
+		return false
+	}
+	if c.exempt {
+		return true
+	}
+
+	if c.moved = c.l.hotOf(c.id) != c.hk; c.moved {
+		return false
+	}
+	l := c.owner()
+	if c.hk != nil {
+		c.hk.calls.Add(1)
+		c.hk.settleLocked(c.id)
+	}
+	if c.resolved {
+		l.setResolved(c.id, c.rp)
+	}
+	c.prev, c.existed = l.m[c.id]
+	c.o = l.try(c.id, c.n, noPriority, c.now)
+	return c.o.ok || l.dryRun
+}
+
+func (c *check[T]) end(ok bool) {
+	if c.pause == PauseDeny || c.exempt || c.moved {
+		return
+	}
+	l := c.owner()
+	if c.o.ok && !ok {
+		if c.existed {
+			l.m[c.id] = c.prev
+		} else {
+			delete(l.m, c.id)
+		}
+		if c.o.fair {
+			l.unfair(c.id, c.o.a.fairPeriod)
+		}
+		// action was denied because of other key
+		l.emit(EventDenied, c.id, c.timeNow)
+		return
+	}
+	// last tried one failed and keeps its denial
+	c.o = l.commit(c.id, c.o, c.timeNow)
+	c.pd = l.pending(c.id, c.o, c.timeNow)
+}
+
+func (c *check[T]) finish(reserved, ok bool) {
+	switch {
+	case c.exempt && ok:
+		c.l.stats.allowed.Add(1)
+	case !reserved || c.exempt || c.moved || (c.o.ok && !ok):
+		// action was denied because of other key
+		c.l.stats.denied.Add(1)
+	case c.hk != nil:
+		if c.hk.l.finish(c.id, c.o, c.pd, c.timeNow) {
+			c.l.stats.allowed.Add(1)
+		} else {
+			c.l.stats.denied.Add(1)
+		}
+	default:
+		c.l.finish(c.id, c.o, c.pd, c.timeNow)
+	}
+}
@@ -0,0 +1,139 @@
+package limiter_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ssleert/limiter"
+	"github.com/ssleert/limiter/limitertest"
+)
+
+func TestTryMany(t *testing.T) {
+	tests := []struct {
+		name  string
+		calls [][]string
+		want  []bool
+		// allowed single actions of a after calls
+		left int
+	}{
+		{
+			name:  "spends all",
+			calls: [][]string{{"a", "b"}},
+			want:  []bool{true},
+			left:  2,
+		},
+		{
+			name:  "same id is summed",
+			calls: [][]string{{"a", "a"}, {"a", "a"}},
+			want:  []bool{true, false},
+			left:  1,
+		},
+		{
+			name:  "same id over limit",
+			calls: [][]string{{"a", "a", "a", "a"}},
+			want:  []bool{false},
+			left:  3,
+		},
+		{
+			name:  "other key spent",
+			calls: [][]string{{"b", "b", "b"}, {"a", "b"}},
+			want:  []bool{true, false},
+			left:  3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := limiter.New[string](3, 60, 16, 1024, 16)
+			limitertest.Use(l)
+			for i, ids := range tt.calls {
+				if got := l.TryMany(ids...); got != tt.want[i] {
+					t.Fatalf("TryMany(%v) = %v, want %v", ids, got, tt.want[i])
+				}
+			}
+			limitertest.AssertAllowed[string](t, l, "a", tt.left)
+			limitertest.AssertDenied[string](t, l, "a")
+		})
+	}
+}
+
+func TestTryManyRollback(t *testing.T) {
+	l := limiter.New[string](1, 60, 16, 1024, 16)
+	c := limitertest.NewClock(time.Unix(1000, 0))
+	l.SetDeterministic(c.Now, 1)
+	limitertest.AssertAllowed[string](t, l, "b", 1)
+	before := l.Stats()
+	events := l.Events(16)
+
+	if l.TryMany("a", "b") {
+		t.Fatal("TryMany(a, b) = true with spent b")
+	}
+	after := l.Stats()
+	if after.Inserted != before.Inserted || after.UniqueKeys != before.UniqueKeys {
+		t.Fatalf("inserted %d, unique %d, want %d, %d",
+			after.Inserted, after.UniqueKeys, before.Inserted, before.UniqueKeys)
+	}
+	if n := l.Len(); n != 1 {
+		t.Fatalf("Len() = %d, want 1", n)
+	}
+	for _, id := range []string{"a", "b"} {
+		select {
+		case e := <-events:
+			if e.Kind != limiter.EventDenied || e.Key != id {
+				t.Fatalf("event = %+v, want denied %s", e, id)
+			}
+		default:
+			t.Fatalf("no event of %s", id)
+		}
+	}
+	limitertest.AssertAllowed[string](t, l, "a", 1)
+}
+
+func TestTryAll(t *testing.T) {
+	user := limiter.New[string](2, 60, 16, 1024, 16)
+	global := limiter.New[string](3, 60, 16, 1024, 16)
+	limitertest.Use(user, global)
+
+	want := []bool{true, true, false}
+	for i, w := range want {
+		ok := limiter.TryAll(user.Check("a", 1), global.Check("all", 1))
+		if ok != w {
+			t.Fatalf("TryAll() %d = %v, want %v", i, ok, w)
+		}
+	}
+	// denial of user key gave global unit back
+	limitertest.AssertAllowed[string](t, global, "all", 1)
+	limitertest.AssertDenied[string](t, global, "all")
+}
+
+func TestTryManyFairRollback(t *testing.T) {
+	l := limiter.New[string](100, 60, 16, 1024, 16)
+	c := limitertest.NewClock(time.Unix(1200, 0))
+	l.SetDeterministic(c.Now, 1)
+	l.SetFairShare(4, time.Minute)
+	l.DenyKeys("b")
+
+	limitertest.AssertAllowed[string](t, l, "a", 1)
+	// x is not active after rollback so a keeps whole share
+	if l.TryMany("x", "b") {
+		t.Fatal("TryMany(x, b) = true with denied b")
+	}
+	limitertest.AssertAllowed[string](t, l, "a", 3)
+	limitertest.AssertDenied[string](t, l, "a")
+}
+
+func TestTryManyEvictRollback(t *testing.T) {
+	l := limiter.New[string](1, 60, 1, 1, 16)
+	c := limitertest.NewClock(time.Unix(1000, 0))
+	l.SetDeterministic(c.Now, 1)
+	l.SetFullPolicy(limiter.FullEvict)
+	limitertest.AssertAllowed[string](t, l, "b", 1)
+
+	// a is not added so b is not evicted for it
+	if l.TryMany("a", "b") {
+		t.Fatal("TryMany(a, b) = true with spent b")
+	}
+	if got := l.Stats().Evicted; got != 0 {
+		t.Fatalf("Evicted = %d, want 0", got)
+	}
+	limitertest.AssertDenied[string](t, l, "b")
+}
@@ -0,0 +1,157 @@
+package limiter
+
+import (
+	"sort"
+
+	"github.com/ssleert/mu"
+)
+
+// get namespace of limiter with name
+// it is made on first call with config of l
+// but without key policies
+//
+// namespace has own keys, policies and stats
+// and is cleaned up by Clean(), CleanContext()
+// and Janitor() of l, so one limiter can serve
+// many limit purposes like login and search
+//
+// key policy of namespace wins over its default
+// policy and it follows default policy of l until
+// it is set on namespace by SetPolicy(), Apply() or
+// others, see InheritPolicy()
+func (l *Limiter[T]) Namespace(name string) *Limiter[T] {
+	var ns *Limiter[T]
+	mu.ExecRWMutex(&l.mu, func() {
+		ns = l.namespaces[name]
+	})
+	if ns != nil {
+		return ns
+	}
+
+	c := l.Clone(false)
+	c.keyPolicies = nil
+	c.nsName = name
+	c.parent = l
+	if parent := l.Name(); parent != "" {
+		c.SetName(parent + "/" + name)
+	} else {
+		c.SetName(name)
+	}
+
+	mu.ExecMutex(&l.mu, func() {
+		ns = l.namespaces[name]
+		if ns != nil {
+			return
+		}
+		if l.namespaces == nil {
+			l.namespaces = make(map[string]*Limiter[T])
+		}
+		// default policy could change since Clone()
+		p := l.defaultPolicy()
+		c.maxCount = p.maxCount
+		c.maxTime = p.maxTime
+		c.burst = p.burst
+		c.schedule = p.schedule
+		l.namespaces[name] = c
+		ns = c
+	})
+	return ns
+}
+
+// sorted names of namespaces of l
+func (l *Limiter[T]) Namespaces() []string {
+	var names []string
+	mu.ExecRWMutex(&l.mu, func() {
+		names = make([]string, 0, len(l.namespaces))
+		for name := range l.namespaces {
+			names = append(names, name)
+		}
+	})
+	sort.Strings(names)
+	return names
+}
+
+// name of namespace, empty for
+// limiters not made by Namespace()
+func (l *Limiter[T]) NamespaceName() string {
+	return l.nsName
+}
+
+// namespaces of l at the moment
+func (l *Limiter[T]) children() []*Limiter[T] {
+	var res []*Limiter[T]
+	mu.ExecRWMutex(&l.mu, func() {
+		if len(l.namespaces) == 0 {
+			return
+		}
+		res = make([]*Limiter[T], 0, len(l.namespaces))
+		for _, ns := range l.namespaces {
+			res = append(res, ns)
+		}
+	})
+	return res
+}
+
+// get stats of every namespace of l by its name
+// see Stats()
+func (l *Limiter[T]) NamespaceStats() map[string]Stats {
+	res := make(map[string]Stats)
+	for _, ns := range l.children() {
+		res[ns.nsName] = ns.Stats()
+	}
+	return res
+}
+
+// make namespace follow default policy of its
+// parent again after SetPolicy() on it
+// does nothing for limiters that are not namespaces
+func (l *Limiter[T]) InheritPolicy() {
+	if l.parent == nil {
+		return
+	}
+	var p policy
+	mu.ExecRWMutex(&l.parent.mu, func() {
+		p = l.parent.defaultPolicy()
+	})
+	mu.ExecMutex(&l.mu, func() {
+		l.ownPolicy = false
+	})
+	l.inheritFrom(p)
+}
+
+// push default policy of l to namespaces
+// that don't have own one
+func (l *Limiter[T]) inherit() {
+	children := l.children()
+	if len(children) == 0 {
+		return
+	}
+	var p policy
+	mu.ExecRWMutex(&l.mu, func() {
+		p = l.defaultPolicy()
+	})
+	for _, ns := range children {
+		ns.inheritFrom(p)
+	}
+}
+
+// take p as default policy unless l has own one
+func (l *Limiter[T]) inheritFrom(p policy) {
+	defer l.moveBack()
+	var own bool
+	mu.ExecMutex(&l.mu, func() {
+		own = l.ownPolicy
+		if own {
+			return
+		}
+		l.maxCount = p.maxCount
+		l.maxTime = p.maxTime
+		l.burst = p.burst
+		l.schedule = p.schedule
+		l.distrust()
+	})
+	if !own {
+		l.denyCache.clear()
+		l.inherit()
+	}
+}
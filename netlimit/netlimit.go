@@ -0,0 +1,140 @@
+/*
+net.Listener wrapper for limiter
+*/
+package netlimit
+
+import (
+	"net"
+	"sync"
+)
+
+// limiter of new connections
+// every limiter.Limiter[string] implements it
+type Limiter interface {
+	Try(id string) bool
+}
+
+// listener that limits connections of every
+// client ip both by count of open ones
+// and by rate of new ones
+//
+// rejected connections are closed right after
+// accept and Accept() waits for next one
+type Listener struct {
+	net.Listener
+
+	max  int
+	rate Limiter
+
+	mu sync.Mutex
+	// open connections of keys
+	// keys without them are removed
+	conns map[string]int
+
+	// key of connection
+	// if nil ip of remote address is used
+	Key func(addr net.Addr) string
+
+	// called with rejected connection before it is closed
+	// e.g. to write error to it or count it
+	Rejected func(c net.Conn)
+}
+
+// wrap l so every key has at most max open connections
+// and new ones pass rate, e.g. limiter with 60 per minute
+//
+// max <= 0 means no count limit and nil rate no rate limit
+func NewListener(l net.Listener, max int, rate Limiter) *Listener {
+	return &Listener{
+		Listener: l,
+		max:      max,
+		rate:     rate,
+		conns:    make(map[string]int),
+	}
+}
+
+// wait for next connection that passes limits
+func (l *Listener) Accept() (net.Conn, error) {
+	for {
+		c, err := l.Listener.Accept()
+		if err != nil {
+			return nil, err
+		}
+
+		key := l.key(c.RemoteAddr())
+		if l.admit(key) {
+			return &conn{Conn: c, l: l, key: key}, nil
+		}
+		if l.Rejected != nil {
+			l.Rejected(c)
+		}
+		c.Close()
+	}
+}
+
+// open connections of key
+func (l *Listener) Open(key string) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.conns[key]
+}
+
+// count of keys with open connections
+func (l *Listener) Keys() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.conns)
+}
+
+func (l *Listener) key(addr net.Addr) string {
+	if l.Key != nil {
+		return l.Key(addr)
+	}
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return addr.String()
+	}
+	return host
+}
+
+// take slot of key if both limits pass
+// count is checked first so rate isn't
+// spent by rejected connections
+func (l *Listener) admit(key string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.max > 0 && l.conns[key] >= l.max {
+		return false
+	}
+	if l.rate != nil && !l.rate.Try(key) {
+		return false
+	}
+	l.conns[key]++
+	return true
+}
+
+func (l *Listener) release(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.conns[key]--
+	if l.conns[key] <= 0 {
+		delete(l.conns, key)
+	}
+}
+
+// connection that gives its slot back on close
+type conn struct {
+	net.Conn
+	l    *Listener
+	key  string
+	once sync.Once
+}
+
+func (c *conn) Close() error {
+	c.once.Do(func() {
+		c.l.release(c.key)
+	})
+	return c.Conn.Close()
+}
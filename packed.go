@@ -0,0 +1,143 @@
+package limiter
+
+import "math"
+
+// compact form of action kept in limiter map
+// unix seconds are kept in 32 bits so they are
+// good until 2106, counts and seconds are clamped
+// to 32 bit range
+type packed struct {
+	tokens     float64
+	spikeAvg   float64
+	pacedUntil int64
+
+	deltaTime      uint32
+	lastTime       uint32
+	firstTime      uint32
+	lastDenied     uint32
+	violationStart uint32
+	bannedUntil    uint32
+	cooldownUntil  uint32
+	refilled       uint32
+	fairPeriod     uint32
+	spikeStart     uint32
+
+	count      int32
+	denies     int32
+	streak     int32
+	violations int32
+	banLevel   int32
+	debt       int32
+	carry      int32
+	spikeCount int32
+	jitter     int32
+	ttl        int32
+
+	spiked bool
+}
+
+func (a action) pack() packed {
+	return packed{
+		tokens:         a.tokens,
+		spikeAvg:       a.spikeAvg,
+		pacedUntil:     a.pacedUntil,
+		deltaTime:      unix32(a.deltaTime),
+		lastTime:       unix32(a.lastTime),
+		firstTime:      unix32(a.firstTime),
+		lastDenied:     unix32(a.lastDenied),
+		violationStart: unix32(a.violationStart),
+		bannedUntil:    unix32(a.bannedUntil),
+		cooldownUntil:  unix32(a.cooldownUntil),
+		refilled:       unix32(a.refilled),
+		fairPeriod:     unix32(a.fairPeriod),
+		spikeStart:     unix32(a.spikeStart),
+		count:          int32Of(int64(a.count)),
+		denies:         int32Of(int64(a.denies)),
+		streak:         int32Of(int64(a.streak)),
+		violations:     int32Of(int64(a.violations)),
+		banLevel:       int32Of(int64(a.banLevel)),
+		debt:           int32Of(int64(a.debt)),
+		carry:          int32Of(int64(a.carry)),
+		spikeCount:     int32Of(int64(a.spikeCount)),
+		jitter:         int32Of(a.jitter),
+		ttl:            int32Of(a.ttl),
+		spiked:         a.spiked,
+	}
+}
+
+func (p packed) unpack() action {
+	return action{
+		tokens:         p.tokens,
+		spikeAvg:       p.spikeAvg,
+		pacedUntil:     p.pacedUntil,
+		deltaTime:      int64(p.deltaTime),
+		lastTime:       int64(p.lastTime),
+		firstTime:      int64(p.firstTime),
+		lastDenied:     int64(p.lastDenied),
+		violationStart: int64(p.violationStart),
+		bannedUntil:    int64(p.bannedUntil),
+		cooldownUntil:  int64(p.cooldownUntil),
+		refilled:       int64(p.refilled),
+		fairPeriod:     int64(p.fairPeriod),
+		spikeStart:     int64(p.spikeStart),
+		count:          int(p.count),
+		denies:         int(p.denies),
+		streak:         int(p.streak),
+		violations:     int(p.violations),
+		banLevel:       int(p.banLevel),
+		debt:           int(p.debt),
+		carry:          int(p.carry),
+		spikeCount:     int(p.spikeCount),
+		jitter:         int64(p.jitter),
+		ttl:            int64(p.ttl),
+		spiked:         p.spiked,
+	}
+}
+
+func unix32(t int64) uint32 {
+	switch {
+	case t < 0:
+		return 0
+	case t > math.MaxUint32:
+		return math.MaxUint32
+	}
+	return uint32(t)
+}
+
+func int32Of(n int64) int32 {
+	switch {
+	case n < math.MinInt32:
+		return math.MinInt32
+	case n > math.MaxInt32:
+		return math.MaxInt32
+	}
+	return int32(n)
+}
+
+// entry of key
+//
+// l.mu must be held
+func (l *Limiter[T]) get(id T) (action, bool) {
+	p, ok := l.m[id]
+	if !ok {
+		return action{}, false
+	}
+	return p.unpack(), true
+}
+
+// l.mu must be held
+func (l *Limiter[T]) set(id T, a action) {
+	l.put(id, a.pack())
+}
+
+// store entry and queue new key
+// for next clean up snapshot
+//
+// l.mu must be held
+func (l *Limiter[T]) put(id T, p packed) {
+	n := len(l.m)
+	l.m[id] = p
+	if len(l.m) > n && l.cleanLog {
+		l.logNew(id)
+	}
+}
@@ -0,0 +1,47 @@
+package limiter
+
+// decision of paused limiter
+type PauseMode int32
+
+const (
+	// allow every action
+	PauseAllow PauseMode = iota + 1
+
+	// deny every action
+	PauseDeny
+)
+
+// make every decision mode without checking keys
+// for maintenance windows and incidents
+// key state is kept as is and nothing is counted
+// except Allowed and Denied stats
+func (l *Limiter[T]) Pause(mode PauseMode) {
+	l.paused.Store(int32(mode))
+}
+
+// go back to normal decisions after Pause()
+func (l *Limiter[T]) Resume() {
+	l.paused.Store(0)
+}
+
+// get mode set by Pause()
+// returns false if limiter is not paused
+func (l *Limiter[T]) Paused() (PauseMode, bool) {
+	mode := PauseMode(l.paused.Load())
+	return mode, mode != 0
+}
+
+// decision of paused limiter
+// returns false if limiter is not paused
+func (l *Limiter[T]) pausedDecision() (ok bool, paused bool) {
+	mode, paused := l.Paused()
+	if !paused {
+		return false, false
+	}
+	if mode == PauseAllow {
+		l.stats.allowed.Add(1)
+		return true, true
+	}
+	l.stats.denied.Add(1)
+	return false, true
+}
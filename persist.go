@@ -0,0 +1,261 @@
+package limiter
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	"github.com/ssleert/mu"
+	"golang.org/x/exp/maps"
+)
+
+// exported state of one key
+// all times are unix seconds
+type Entry[T any] struct {
+	Key T `json:"key"`
+
+	WindowStart int64 `json:"window_start"`
+	LastSeen    int64 `json:"last_seen"`
+	FirstSeen   int64 `json:"first_seen"`
+	Count       int   `json:"count"`
+	Denies      int   `json:"denies,omitempty"`
+	LastDenied  int64 `json:"last_denied,omitempty"`
+	DenyStreak  int   `json:"deny_streak,omitempty"`
+
+	Violations     int   `json:"violations,omitempty"`
+	ViolationStart int64 `json:"violation_start,omitempty"`
+	BannedUntil    int64 `json:"banned_until,omitempty"`
+	BanLevel       int   `json:"ban_level,omitempty"`
+	CooldownUntil  int64 `json:"cooldown_until,omitempty"`
+
+	Tokens   float64 `json:"tokens,omitempty"`
+	Refilled int64   `json:"refilled,omitempty"`
+
+	Debt   int   `json:"debt,omitempty"`
+	Carry  int   `json:"carry,omitempty"`
+	Jitter int64 `json:"jitter,omitempty"`
+	TTL    int64 `json:"ttl,omitempty"`
+
+	// value of key, see SetMeta()
+	// json snapshots decode it as json value
+	// like map[string]any
+	Meta any `json:"meta,omitempty"`
+}
+
+func entryOf[T any](id T, a action) Entry[T] {
+	return Entry[T]{
+		Key:            id,
+		WindowStart:    a.deltaTime,
+		LastSeen:       a.lastTime,
+		FirstSeen:      a.firstTime,
+		Count:          a.count,
+		Denies:         a.denies,
+		LastDenied:     a.lastDenied,
+		DenyStreak:     a.streak,
+		Violations:     a.violations,
+		ViolationStart: a.violationStart,
+		BannedUntil:    a.bannedUntil,
+		BanLevel:       a.banLevel,
+		CooldownUntil:  a.cooldownUntil,
+		Tokens:         a.tokens,
+		Refilled:       a.refilled,
+		Debt:           a.debt,
+		Carry:          a.carry,
+		Jitter:         a.jitter,
+		TTL:            a.ttl,
+	}
+}
+
+func (e Entry[T]) action() action {
+	return action{
+		deltaTime:      e.WindowStart,
+		lastTime:       e.LastSeen,
+		firstTime:      e.FirstSeen,
+		count:          e.Count,
+		denies:         e.Denies,
+		lastDenied:     e.LastDenied,
+		streak:         e.DenyStreak,
+		violations:     e.Violations,
+		violationStart: e.ViolationStart,
+		bannedUntil:    e.BannedUntil,
+		banLevel:       e.BanLevel,
+		cooldownUntil:  e.CooldownUntil,
+		tokens:         e.Tokens,
+		refilled:       e.Refilled,
+		debt:           e.Debt,
+		carry:          e.Carry,
+		jitter:         e.Jitter,
+		ttl:            e.TTL,
+	}
+}
+
+// entries copied under one lock by Snapshot()
+const snapshotChunk = 1024
+
+// copy state of all keys
+//
+// keys are copied first and their entries are then
+// copied in chunks with lock released between them,
+// so big limiters don't stall Try() for whole copy,
+// every entry is consistent but entries are taken at
+// slightly different times, keys added after start
+// are not in it and removed ones are skipped
+// isolated keys are copied last, see SetHotKeys()
+func (l *Limiter[T]) Snapshot() []Entry[T] {
+	var keys []T
+	mu.ExecRWMutex(&l.mu, func() {
+		keys = maps.Keys(l.m)
+	})
+
+	res := make([]Entry[T], 0, len(keys))
+	for len(keys) > 0 {
+		chunk := keys
+		if len(chunk) > snapshotChunk {
+			chunk = chunk[:snapshotChunk]
+		}
+		keys = keys[len(chunk):]
+
+		mu.ExecRWMutex(&l.mu, func() {
+			for _, id := range chunk {
+				if a, ok := l.get(id); ok {
+					res = append(res, l.export(id, a))
+				}
+			}
+		})
+		runtime.Gosched()
+	}
+	mu.ExecRWMutex(&l.mu, func() {
+		l.eachHot(func(id T, hl *Limiter[T], a action) {
+			res = append(res, hl.export(id, a))
+		})
+	})
+	return res
+}
+
+// set state of keys from entries
+// keys not in entries stay as is
+func (l *Limiter[T]) Restore(entries []Entry[T]) {
+	ids := make([]T, len(entries))
+	for i, e := range entries {
+		ids[i] = e.Key
+	}
+	l.moveBackOf(ids)
+	mu.ExecMutex(&l.mu, func() {
+		for _, e := range entries {
+			l.set(e.Key, e.action())
+			l.setMeta(e.Key, e.Meta)
+		}
+	})
+}
+
+type snapshot[T any] struct {
+	Version int        `json:"version"`
+	Entries []Entry[T] `json:"entries"`
+}
+
+// write state of all keys to w as json
+func (l *Limiter[T]) Save(w io.Writer) error {
+	return json.NewEncoder(w).Encode(snapshot[T]{
+		Version: SnapshotVersion,
+		Entries: l.Snapshot(),
+	})
+}
+
+// read state written by Save() from r
+// and restore it, see Restore()
+// state of older versions is migrated, see Migrate()
+func (l *Limiter[T]) Load(r io.Reader) error {
+	var s snapshot[T]
+	if err := json.NewDecoder(r).Decode(&s); err != nil {
+		return err
+	}
+	if err := Migrate(s.Version, s.Entries); err != nil {
+		return err
+	}
+	l.Restore(s.Entries)
+	return nil
+}
+
+// Save() to file at path
+// file is replaced atomically so crash
+// never leaves half written state
+func (l *Limiter[T]) SaveFile(path string) error {
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if err := l.Save(f); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// Load() from file at path
+func (l *Limiter[T]) LoadFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return l.Load(f)
+}
+
+// interval of Checkpoint() if given one is not positive
+const defaultCheckpointInterval = time.Minute
+
+// SaveFile() every interval until ctx is done
+// and once more before return, so long windows
+// like Daily() or Monthly() survive crashes
+// restore state on start with LoadFile()
+//
+// it blocks so run it in your own goroutine
+// save errors are passed to onErr
+// nil onErr ignores them
+// interval <= 0 saves it every minute
+//
+// returns ctx.Err()
+func (l *Limiter[T]) Checkpoint(
+	ctx context.Context,
+	path string,
+	interval time.Duration,
+	onErr func(err error),
+) error {
+	ctx, unlabel := l.label(ctx, "checkpoint")
+	defer unlabel()
+
+	if interval <= 0 {
+		interval = defaultCheckpointInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	save := func() {
+		if err := l.SaveFile(path); err != nil && onErr != nil {
+			onErr(err)
+		}
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			save()
+			return ctx.Err()
+		case <-ticker.C:
+			save()
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package limiter_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ssleert/limiter"
+	"github.com/ssleert/limiter/limitertest"
+)
+
+func TestCheckpoint(t *testing.T) {
+	tests := []struct {
+		name     string
+		interval time.Duration
+	}{
+		{"interval", time.Hour},
+		{"zero interval", 0},
+		{"negative interval", -time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "state.json")
+			l := limiter.New[string](3, 60, 16, 1024, 16)
+			limitertest.Use(l)
+			limitertest.AssertAllowed[string](t, l, "a", 3)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			err := l.Checkpoint(ctx, path, tt.interval, func(err error) {
+				t.Errorf("Checkpoint() save err = %v", err)
+			})
+			if !errors.Is(err, context.Canceled) {
+				t.Fatalf("Checkpoint() err = %v, want %v", err, context.Canceled)
+			}
+
+			// state is saved before return
+			r := limiter.New[string](3, 60, 16, 1024, 16)
+			limitertest.Use(r)
+			if err := r.LoadFile(path); err != nil {
+				t.Fatalf("LoadFile() err = %v", err)
+			}
+			limitertest.AssertDenied[string](t, r, "a")
+		})
+	}
+}
@@ -0,0 +1,331 @@
+package limiter
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ssleert/mu"
+)
+
+// limit of actions for key
+type Policy struct {
+	// max actions in one window
+	// if <= 0 default is used
+	MaxCount int
+	// window length, resolution is one second
+	// if <= 0 default is used
+	Window time.Duration
+
+	// if > 0 key can spend up to Burst actions at once
+	// and gets MaxCount actions back every Window
+	// so MaxCount per Window is sustained rate
+	Burst int
+
+	// if not nil windows follow schedule like
+	// Aligned() or Daily() instead of starting at key
+	// first action, Window and Burst are ignored
+	Schedule Schedule
+}
+
+// check that policy values are in range
+// zero values are valid and mean defaults
+func (p Policy) Validate() error {
+	if p.MaxCount < 0 {
+		return fmt.Errorf("%w: negative max count", ErrInvalidPolicy)
+	}
+	if p.Window < 0 {
+		return fmt.Errorf("%w: negative window", ErrInvalidPolicy)
+	}
+	if p.Window > 0 && p.Window < time.Second {
+		return fmt.Errorf("%w: window less than second", ErrInvalidPolicy)
+	}
+	if p.Burst < 0 {
+		return fmt.Errorf("%w: negative burst", ErrInvalidPolicy)
+	}
+	return nil
+}
+
+type jsonPolicy struct {
+	MaxCount int    `json:"max_count"`
+	Window   string `json:"window"`
+	Burst    int    `json:"burst,omitempty"`
+	Schedule string `json:"schedule,omitempty"`
+}
+
+// fields of json policy that are set
+type jsonPolicyPatch struct {
+	MaxCount *int    `json:"max_count"`
+	Window   *string `json:"window"`
+	Burst    *int    `json:"burst"`
+	Schedule *string `json:"schedule"`
+}
+
+var ErrScheduleText = errors.New("limiter: schedule has no text form")
+
+// encode window as duration string like "1m"
+// and schedule in ParseSchedule() form, schedule
+// must implement fmt.Stringer like ones of this package
+func (p Policy) MarshalJSON() ([]byte, error) {
+	jp := jsonPolicy{
+		MaxCount: p.MaxCount,
+		Window:   p.Window.String(),
+		Burst:    p.Burst,
+	}
+	if p.Schedule != nil {
+		s, ok := p.Schedule.(fmt.Stringer)
+		if !ok {
+			return nil, ErrScheduleText
+		}
+		jp.Schedule = s.String()
+	}
+	return json.Marshal(jp)
+}
+
+// decode window from duration string like "1m"
+// and schedule with ParseSchedule()
+//
+// only fields present in data are changed
+// so decoding into current policy updates it
+// empty schedule removes schedule
+func (p *Policy) UnmarshalJSON(data []byte) error {
+	var jp jsonPolicyPatch
+	if err := json.Unmarshal(data, &jp); err != nil {
+		return err
+	}
+
+	v := *p
+	if jp.MaxCount != nil {
+		v.MaxCount = *jp.MaxCount
+	}
+	if jp.Window != nil {
+		v.Window = 0
+		if *jp.Window != "" {
+			var err error
+			v.Window, err = time.ParseDuration(*jp.Window)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	if jp.Burst != nil {
+		v.Burst = *jp.Burst
+	}
+	if jp.Schedule != nil {
+		v.Schedule = nil
+		if *jp.Schedule != "" {
+			var err error
+			v.Schedule, err = ParseSchedule(*jp.Schedule)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	*p = v
+	return nil
+}
+
+// policy in limiter units
+type policy struct {
+	maxCount int
+	maxTime  int64
+	burst    int
+	schedule Schedule
+
+	// limiter wide settings
+	// set by policyOf()
+	overdraft int
+	carryPart float64
+	carryMax  int
+
+	// units that must stay unused
+	// set for low priorities by try()
+	reserve int
+}
+
+// true if p and o give same limits
+func (p policy) same(o policy) bool {
+	return p.maxCount == o.maxCount &&
+		p.maxTime == o.maxTime &&
+		p.burst == o.burst &&
+		sameSchedule(p.schedule, o.schedule)
+}
+
+func (p Policy) internal() policy {
+	if p.MaxCount <= 0 {
+		p.MaxCount = defaultMaxCount
+	}
+	maxTime := int64(p.Window / time.Second)
+	if maxTime <= 0 {
+		maxTime = defaultMaxTime
+	}
+	if p.Schedule != nil {
+		p.Burst = 0
+	}
+	return policy{
+		maxCount: p.MaxCount,
+		maxTime:  maxTime,
+		burst:    p.Burst,
+		schedule: p.Schedule,
+	}
+}
+
+func (p policy) external() Policy {
+	return Policy{
+		MaxCount: p.maxCount,
+		Window:   time.Duration(p.maxTime) * time.Second,
+		Burst:    p.burst,
+		Schedule: p.schedule,
+	}
+}
+
+// get default policy of limiter
+func (l *Limiter[T]) Policy() Policy {
+	var p policy
+	mu.ExecRWMutex(&l.mu, func() {
+		p = l.defaultPolicy()
+	})
+	return p.external()
+}
+
+// set default policy for keys without own one
+func (l *Limiter[T]) SetPolicy(p Policy) {
+	defer l.moveBack()
+	ip := p.internal()
+	mu.ExecMutex(&l.mu, func() {
+		l.maxCount = ip.maxCount
+		l.maxTime = ip.maxTime
+		l.burst = ip.burst
+		l.schedule = ip.schedule
+		l.ownPolicy = true
+		l.distrust()
+	})
+	l.denyCache.clear()
+	l.inherit()
+}
+
+// set own policy for key
+// it stays even when key entry is removed
+func (l *Limiter[T]) SetKeyPolicy(id T, p Policy) {
+	defer l.moveBack(id)
+	ip := p.internal()
+	mu.ExecMutex(&l.mu, func() {
+		if l.keyPolicies == nil {
+			l.keyPolicies = make(map[T]policy)
+		}
+		l.keyPolicies[id] = ip
+		l.distrust(id)
+	})
+	l.denyCache.forget(id)
+}
+
+// remove own policy of key
+// so default one is used
+func (l *Limiter[T]) RemoveKeyPolicy(id T) {
+	defer l.moveBack(id)
+	mu.ExecMutex(&l.mu, func() {
+		delete(l.keyPolicies, id)
+		l.distrust(id)
+	})
+	l.denyCache.forget(id)
+}
+
+// multiply limit of key by k without own policy for it
+// e.g. 2 for paying clients or 0.5 for noisy ones
+// it scales max count and burst of any policy
+// key gets, own or default, and stays like key policy
+// even when key entry is removed
+//
+// k <= 0 or k == 1 removes it
+// not to be confused with fair share SetWeight()
+func (l *Limiter[T]) SetKeyScale(id T, k float64) {
+	defer l.moveBack(id)
+	mu.ExecMutex(&l.mu, func() {
+		l.distrust(id)
+		if k <= 0 || k == 1 {
+			delete(l.keyScales, id)
+			return
+		}
+		if l.keyScales == nil {
+			l.keyScales = make(map[T]float64)
+		}
+		l.keyScales[id] = k
+	})
+	l.denyCache.forget(id)
+}
+
+// get limit multiplier of key
+// 1 if key has none
+func (l *Limiter[T]) KeyScale(id T) float64 {
+	k := 1.0
+	mu.ExecRWMutex(&l.mu, func() {
+		if v, ok := l.keyScales[id]; ok {
+			k = v
+		}
+	})
+	return k
+}
+
+// get policy used for key with its scale
+// and true if it is key own or resolved policy
+func (l *Limiter[T]) KeyPolicy(id T) (Policy, bool) {
+	var (
+		p  policy
+		ok bool
+	)
+	mu.ExecRWMutex(&l.mu, func() {
+		p, ok = l.keyPolicies[id]
+		if !ok {
+			p, ok = l.resolvedOf(id)
+		}
+		if !ok {
+			p = l.defaultPolicy()
+		}
+		p = l.keyScale(id, p)
+	})
+	return p.external(), ok
+}
+
+// l.mu must be held
+func (l *Limiter[T]) defaultPolicy() policy {
+	return policy{
+		maxCount: l.maxCount,
+		maxTime:  l.maxTime,
+		burst:    l.burst,
+		schedule: l.schedule,
+	}
+}
+
+// policy used for key
+//
+// l.mu must be held
+func (l *Limiter[T]) policyOf(id T) policy {
+	p, ok := l.keyPolicies[id]
+	if !ok {
+		p, ok = l.resolvedOf(id)
+	}
+	if !ok {
+		p = l.defaultPolicy()
+	}
+	p = l.keyScale(id, p)
+	p.overdraft = l.overdraft
+	p.carryPart = l.carryPart
+	p.carryMax = l.carryMax
+	return p
+}
+
+// p scaled by multiplier of key
+//
+// l.mu must be held
+func (l *Limiter[T]) keyScale(id T, p policy) policy {
+	k, ok := l.keyScales[id]
+	if !ok {
+		return p
+	}
+	p.maxCount = scaled(p.maxCount, k)
+	if p.burst > 0 {
+		p.burst = scaled(p.burst, k)
+	}
+	return p
+}
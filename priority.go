@@ -0,0 +1,74 @@
+package limiter
+
+import (
+	"github.com/ssleert/mu"
+)
+
+// Try() and TryN() use whole limit
+const noPriority = -1
+
+// max part of limit every priority can use
+// index is priority
+type priorities []float64
+
+// let priority i use only shares[i] part of key limit
+// so when budget is scarce low priorities are denied
+// first and high ones still get through, e.g.
+//
+//	l.SetPriorities(0.5, 0.8, 1)
+//
+// lets priority 0 spend first half of limit
+// priority 1 first 80% and priority 2 all of it
+//
+// priorities over last share use whole limit
+// no shares disables priorities
+func (l *Limiter[T]) SetPriorities(shares ...float64) {
+	defer l.moveBack()
+	ps := make(priorities, len(shares))
+	for i, s := range shares {
+		switch {
+		case s < 0:
+			s = 0
+		case s > 1:
+			s = 1
+		}
+		ps[i] = s
+	}
+	mu.ExecMutex(&l.mu, func() {
+		l.prio = ps
+	})
+}
+
+// like Try() but action has priority prio
+// set by SetPriorities()
+// prio < 0 is counted as 0
+func (l *Limiter[T]) TryPriority(id T, prio int) bool {
+	if prio < 0 {
+		prio = 0
+	}
+	now := l.now()
+	ok := l.run(id, 1, prio, now)
+	if !ok {
+		l.sampleDenial(id, nil, now)
+	}
+	if s := l.shadow.Load(); s != nil {
+		l.compareShadow(ok, s.TryPriority(id, prio))
+	}
+	return ok
+}
+
+// policy p with units reserved for priorities over prio
+// overdraft is left only for full share
+func (ps priorities) scale(p policy, prio int) policy {
+	if prio < 0 || prio >= len(ps) || ps[prio] >= 1 {
+		return p
+	}
+
+	limit := p.maxCount
+	if p.burst > 0 {
+		limit = p.burst
+	}
+	p.reserve = limit - int(float64(limit)*ps[prio])
+	p.overdraft = 0
+	return p
+}
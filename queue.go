@@ -0,0 +1,500 @@
+package limiter
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/ssleert/mu"
+)
+
+var (
+	ErrQueueFull   = errors.New("limiter: wait queue is full")
+	ErrWaitTooLong = errors.New("limiter: wait would exceed max wait")
+	ErrDenied      = errors.New("limiter: action can never be allowed")
+	ErrShed        = errors.New("limiter: waiter was shed from queue")
+)
+
+// order of waiters in key queue
+type QueueOrder int
+
+const (
+	// oldest waiter goes first
+	QueueFIFO QueueOrder = iota
+	// newest waiter goes first and when queue is full
+	// oldest one is shed to make space for new one
+	QueueLIFO
+	// QueueFIFO until oldest waiter waits for half
+	// of max wait then QueueLIFO, so under overload
+	// waiters that will time out anyway don't block others
+	QueueAdaptive
+)
+
+type queue[T comparable] struct {
+	mu sync.Mutex
+
+	// max waiters of one key
+	depth   int
+	maxWait time.Duration
+	order   QueueOrder
+	codel   codel
+	waiting map[T]*waiters
+}
+
+// codel settings
+// target <= 0 disables it
+type codel struct {
+	target   time.Duration
+	interval time.Duration
+}
+
+// waiters of one key
+// only active one tries key, others wait for their turn
+type waiters struct {
+	list   []*waiter
+	active *waiter
+
+	// min wait of waiters that got turn in interval
+	minDelay time.Duration
+	// end of current codel interval
+	intervalEnd time.Time
+	// min delay was over target in last interval
+	overloaded bool
+}
+
+type waiter struct {
+	// closed when it is waiter turn
+	turn chan struct{}
+	// closed when waiter is shed
+	shed  chan struct{}
+	since time.Time
+}
+
+// bound Wait() calls to depth waiters per key
+// each waiting no more than maxWait
+//
+// depth <= 0 means no limit on waiters
+// maxWait <= 0 means wait is bounded only by ctx
+func (l *Limiter[T]) SetQueue(depth int, maxWait time.Duration) {
+	l.queue.mu.Lock()
+	defer l.queue.mu.Unlock()
+
+	l.queue.depth = depth
+	l.queue.maxWait = maxWait
+}
+
+// set order of waiters in key queues
+// default is QueueFIFO
+func (l *Limiter[T]) SetQueueOrder(o QueueOrder) {
+	l.queue.mu.Lock()
+	defer l.queue.mu.Unlock()
+
+	l.queue.order = o
+}
+
+// shed waiters of key that waited over target
+// when its waiters waited over target for
+// whole interval, like codel does for packets
+//
+// so key queue in sustained overload stays near
+// target delay instead of growing to max wait
+// queue without waiters is never overloaded
+//
+// target <= 0 disables it
+// if interval <= 0 it sets to 100ms
+func (l *Limiter[T]) SetCoDel(target, interval time.Duration) {
+	if interval <= 0 {
+		interval = 100 * time.Millisecond
+	}
+	l.queue.mu.Lock()
+	defer l.queue.mu.Unlock()
+
+	l.queue.codel = codel{
+		target:   target,
+		interval: interval,
+	}
+}
+
+// like WaitN() with one unit
+func (l *Limiter[T]) Wait(ctx context.Context, id T) error {
+	return l.WaitN(ctx, id, 1)
+}
+
+// like TryN() but over limit action waits in
+// key queue until key has budget for it
+// instead of being denied
+//
+// waiters of key are admitted in order set by
+// SetQueueOrder() and by default in order they came
+//
+// returns ErrQueueFull if key has too many waiters
+// ErrShed if newer waiter took its place in queue
+// or it was shed by SetCoDel()
+// ErrWaitTooLong if budget frees up after max wait
+// these three are wrapped in RetryError
+// ErrDenied if key can never spend n units
+// or ctx.Err() if ctx is done first
+//
+// hooks and shadow limiters are not run
+// resolution is one second
+func (l *Limiter[T]) WaitN(ctx context.Context, id T, n int) error {
+	if n < 1 {
+		n = 1
+	}
+	return l.wait(ctx, id, n, 0)
+}
+
+// like WaitMax() with one unit
+func (l *Limiter[T]) WaitMax(ctx context.Context, id T, max time.Duration) error {
+	return l.WaitNMax(ctx, id, 1, max)
+}
+
+// like WaitN() but returns ErrWaitTooLong in RetryError
+// right away if key budget frees up after max
+// so hopeless waits are not queued
+// and wait in queue is bounded by max too
+//
+// max <= 0 means only queue max wait
+func (l *Limiter[T]) WaitNMax(ctx context.Context, id T, n int, max time.Duration) error {
+	if n < 1 {
+		n = 1
+	}
+	d, ok := l.RetryAfter(id, n)
+	if !ok {
+		return ErrDenied
+	}
+	if max > 0 && d > max {
+		return &RetryError{Err: ErrWaitTooLong, After: d}
+	}
+	return l.wait(ctx, id, n, max)
+}
+
+// wait for n units of id no longer than
+// max and queue max wait, max <= 0 means
+// only queue max wait
+func (l *Limiter[T]) wait(ctx context.Context, id T, n int, max time.Duration) error {
+	w, maxWait, err := l.enqueue(id)
+	if err != nil {
+		return l.retryError(err, id, n)
+	}
+	defer l.dequeue(id, w)
+
+	if max > 0 && (maxWait <= 0 || max < maxWait) {
+		maxWait = max
+	}
+
+	var (
+		deadline time.Time
+		expired  <-chan time.Time
+	)
+	if maxWait > 0 {
+		deadline = l.now().Add(maxWait)
+		t := time.NewTimer(maxWait)
+		defer t.Stop()
+		expired = t.C
+	}
+
+	select {
+	case <-w.turn:
+	case <-w.shed:
+		return l.retryError(ErrShed, id, n)
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-expired:
+		return l.retryError(ErrWaitTooLong, id, n)
+	}
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	<-timer.C
+	for {
+		ok, at := l.tryReady(id, n)
+		if ok {
+			return nil
+		}
+		if at < 0 {
+			return ErrDenied
+		}
+		wake := time.Unix(at, 0)
+		if !deadline.IsZero() && wake.After(deadline) {
+			return &RetryError{
+				Err:   ErrWaitTooLong,
+				After: wake.Sub(l.now()),
+			}
+		}
+
+		timer.Reset(wake.Sub(l.now()))
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// take place in queue of id
+func (l *Limiter[T]) enqueue(id T) (*waiter, time.Duration, error) {
+	q := &l.queue
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	ws := q.waiting[id]
+	if ws == nil {
+		ws = &waiters{}
+		if q.waiting == nil {
+			q.waiting = make(map[T]*waiters)
+		}
+		q.waiting[id] = ws
+	}
+	now := l.now()
+	if q.depth > 0 && len(ws.list) >= q.depth && !q.shed(ws, now) {
+		return nil, 0, ErrQueueFull
+	}
+
+	w := &waiter{
+		turn:  make(chan struct{}),
+		shed:  make(chan struct{}),
+		since: now,
+	}
+	ws.list = append(ws.list, w)
+	if ws.active == nil {
+		q.activate(ws, now)
+	}
+	return w, q.maxWait, nil
+}
+
+// leave queue of id
+// and give turn to next waiter
+func (l *Limiter[T]) dequeue(id T, w *waiter) {
+	q := &l.queue
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	ws := q.waiting[id]
+	if ws == nil {
+		// shed waiter of queue that is gone
+		return
+	}
+	for i, v := range ws.list {
+		if v == w {
+			ws.list = append(ws.list[:i], ws.list[i+1:]...)
+			break
+		}
+	}
+	if len(ws.list) == 0 {
+		delete(q.waiting, id)
+		return
+	}
+	if ws.active == w {
+		q.activate(ws, l.now())
+	}
+	if len(ws.list) == 0 {
+		delete(q.waiting, id)
+	}
+}
+
+// true if queue is in lifo order now
+// q.mu must be held
+func (q *queue[T]) lifo(ws *waiters, now time.Time) bool {
+	switch q.order {
+	case QueueLIFO:
+		return true
+	case QueueAdaptive:
+		return q.maxWait > 0 && now.Sub(ws.list[0].since) >= q.maxWait/2
+	}
+	return false
+}
+
+// give turn to next waiter
+// q.mu must be held
+func (q *queue[T]) activate(ws *waiters, now time.Time) {
+	ws.active = nil
+	for len(ws.list) > 0 {
+		i := 0
+		if q.lifo(ws, now) {
+			i = len(ws.list) - 1
+		}
+		w := ws.list[i]
+		if !q.drop(ws, now.Sub(w.since), now) {
+			ws.active = w
+			close(w.turn)
+			return
+		}
+		ws.list = append(ws.list[:i], ws.list[i+1:]...)
+		close(w.shed)
+	}
+}
+
+// record delay of waiter that gets turn
+// and return true if it must be shed
+//
+// q.mu must be held
+func (q *queue[T]) drop(ws *waiters, delay time.Duration, now time.Time) bool {
+	c := q.codel
+	if c.target <= 0 {
+		return false
+	}
+
+	if !now.Before(ws.intervalEnd) {
+		ws.overloaded = !ws.intervalEnd.IsZero() && ws.minDelay > c.target
+		ws.minDelay = delay
+		ws.intervalEnd = now.Add(c.interval)
+	} else if delay < ws.minDelay {
+		ws.minDelay = delay
+	}
+	return ws.overloaded && delay > c.target
+}
+
+// remove oldest waiting waiter in lifo order
+// returns false if nothing was shed
+//
+// q.mu must be held
+func (q *queue[T]) shed(ws *waiters, now time.Time) bool {
+	if !q.lifo(ws, now) {
+		return false
+	}
+	for i, w := range ws.list {
+		if w == ws.active {
+			continue
+		}
+		ws.list = append(ws.list[:i], ws.list[i+1:]...)
+		close(w.shed)
+		return true
+	}
+	return false
+}
+
+// time until key can spend n units, like for Retry-After
+// 0 if it can do it now and false if it never can
+// n < 1 is counted as 1
+func (l *Limiter[T]) RetryAfter(id T, n int) (time.Duration, bool) {
+	if n < 1 {
+		n = 1
+	}
+	if l.exempted(id) {
+		return 0, true
+	}
+	if hk := l.hotOf(id); hk != nil {
+		hk.settle(id)
+		return hk.l.RetryAfter(id, n)
+	}
+	now := l.now()
+
+	var at int64
+	mu.ExecRWMutex(&l.mu, func() {
+		at = l.readyAt(id, n, now.Unix())
+	})
+	if at < 0 {
+		return 0, false
+	}
+	if d := time.Unix(at, 0).Sub(now); d > 0 {
+		return d, true
+	}
+	return 0, true
+}
+
+// try id only if it can spend n units now
+// otherwise nothing is recorded and
+// earliest time to try again is returned
+func (l *Limiter[T]) tryReady(id T, n int) (bool, int64) {
+	if ok, paused := l.pausedDecision(); paused {
+		return ok, l.now().Unix() + 1
+	}
+	if l.exempted(id) {
+		l.stats.allowed.Add(1)
+		return true, 0
+	}
+	if hk := l.hotOf(id); hk != nil {
+		hk.calls.Add(1)
+		hk.settle(id)
+		ok, at := hk.l.tryReady(id, n)
+		if ok {
+			l.stats.allowed.Add(1)
+		}
+		return ok, at
+	}
+	rp, resolved := l.resolve(id)
+	now := l.now()
+	timeNow := now.Unix()
+
+	var (
+		o     outcome[T]
+		pd    pending[T]
+		at    int64
+		ready bool
+	)
+	var moved bool
+	mu.ExecMutex(timedMutex[T]{l, l.sampled()}, func() {
+		// key was isolated after check above
+		if moved = l.hotOf(id) != nil; moved {
+			return
+		}
+		if resolved {
+			l.setResolved(id, rp)
+		}
+		at = l.readyAt(id, n, timeNow)
+		if at != timeNow {
+			return
+		}
+		ready = true
+		o = l.commit(id, l.try(id, n, noPriority, now), timeNow)
+		pd = l.pending(id, o, timeNow)
+	})
+	if moved {
+		return l.tryReady(id, n)
+	}
+	if !ready {
+		return false, at
+	}
+	if !l.finish(id, o, pd, timeNow) {
+		return false, timeNow + 1
+	}
+	return true, 0
+}
+
+// earliest time when id can spend n units
+// timeNow if it can do it now
+// and -1 if it never can
+//
+// l.mu must be held
+func (l *Limiter[T]) readyAt(id T, n int, timeNow int64) int64 {
+	if ok, listed := l.listed(id); listed {
+		if ok {
+			return timeNow
+		}
+		return -1
+	}
+
+	p := l.policyOf(id)
+	a, found := l.get(id)
+	if !found {
+		if l.maxMapLen > 0 && len(l.m) >= l.maxMapLen &&
+			l.fullPolicy == FullDeny {
+			return timeNow + 1
+		}
+		a, found = l.buried(id, timeNow)
+	}
+	if !found {
+		a = p.fresh(timeNow)
+	}
+
+	a = l.current(a, p, timeNow)
+	switch {
+	case a.banned(timeNow):
+		return a.bannedUntil
+	case a.cooldownUntil > timeNow:
+		return a.cooldownUntil
+	case l.pacing && a.pacedUntil > l.now().UnixNano():
+		// next second after paced time
+		return a.pacedUntil/int64(time.Second) + 1
+	}
+
+	at := l.healthScale(id, l.loadScale(l.warmup.scale(p, a, timeNow))).
+		readyAt(a, n, timeNow)
+	if at < 0 && p.readyAt(a, n, timeNow) >= 0 {
+		// key grows to full limit later
+		return timeNow + 1
+	}
+	return at
+}
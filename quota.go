@@ -0,0 +1,152 @@
+package limiter
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ssleert/mu"
+	"golang.org/x/exp/constraints"
+)
+
+var ErrOvercommit = errors.New("limiter: guarantees over budget")
+
+// global budget split into guaranteed shares
+// of keys like premium tenants and best effort pool
+// with the rest of budget contended by every key
+//
+// key spends its guaranteed share first and then
+// takes from pool, so under load keys with guarantee
+// still get their share whatever others do
+// buckets are refilled with budget window
+// resolution is one second
+type Quota[T constraints.Ordered] struct {
+	mu sync.Mutex
+
+	budget     Policy
+	guaranteed int
+	shares     map[T]policy
+	m          map[T]action
+
+	pool      policy
+	poolState action
+}
+
+// make new quota with budget policy
+// budget without burst gets MaxCount burst
+func NewQuota[T constraints.Ordered](budget Policy) *Quota[T] {
+	pp := bucketPolicy(budget)
+	return &Quota[T]{
+		budget:    pp.external(),
+		shares:    make(map[T]policy),
+		m:         make(map[T]action, defaultMapLen),
+		pool:      pp,
+		poolState: pp.fresh(time.Now().Unix()),
+	}
+}
+
+// guarantee n actions per budget window to key
+// and take them out of pool, n <= 0 gives key
+// share back to pool
+//
+// returns ErrOvercommit if guarantees of all
+// keys get over budget
+func (q *Quota[T]) SetGuarantee(id T, n int) error {
+	var err error
+	mu.ExecMutex(&q.mu, func() {
+		old := q.shares[id].maxCount
+		sum := q.guaranteed - old
+		if n > 0 {
+			sum += n
+		}
+		if sum > q.budget.MaxCount {
+			err = fmt.Errorf(
+				"%w: %d of %d", ErrOvercommit, sum, q.budget.MaxCount,
+			)
+			return
+		}
+		q.guaranteed = sum
+
+		if n > 0 {
+			q.shares[id] = bucketPolicy(Policy{
+				MaxCount: n,
+				Window:   q.budget.Window,
+			})
+		} else {
+			delete(q.shares, id)
+			delete(q.m, id)
+		}
+		q.resize(time.Now().Unix())
+	})
+	return err
+}
+
+// set pool to budget without guarantees
+// scaling burst the same way
+//
+// q.mu must be held
+func (q *Quota[T]) resize(timeNow int64) {
+	rest := q.budget.MaxCount - q.guaranteed
+	p := q.pool
+	tokens := p.tokens(q.poolState, timeNow)
+
+	p.maxCount = rest
+	p.burst = q.budget.Burst * rest / q.budget.MaxCount
+	if p.burst > 0 && tokens > float64(p.burst) {
+		tokens = float64(p.burst)
+	}
+	q.pool = p
+	q.poolState.tokens = tokens
+	q.poolState.refilled = timeNow
+}
+
+func (q *Quota[T]) Try(id T) bool {
+	return q.TryN(id, 1)
+}
+
+// true if key has n actions in its guaranteed
+// share or in pool, n < 1 is counted as 1
+func (q *Quota[T]) TryN(id T, n int) bool {
+	if n < 1 {
+		n = 1
+	}
+	timeNow := time.Now().Unix()
+
+	var ok bool
+	mu.ExecMutex(&q.mu, func() {
+		if p, found := q.shares[id]; found {
+			a, found := q.m[id]
+			if !found {
+				a = p.fresh(timeNow)
+			}
+			if p.admit(&a, n, timeNow) {
+				q.m[id] = a
+				ok = true
+				return
+			}
+		}
+		if q.pool.maxCount > 0 {
+			ok = q.pool.admit(&q.poolState, n, timeNow)
+		}
+	})
+	return ok
+}
+
+// tokens left in guaranteed share of key and in pool
+func (q *Quota[T]) Tokens(id T) (share, pool float64) {
+	timeNow := time.Now().Unix()
+	mu.ExecMutex(&q.mu, func() {
+		if p, found := q.shares[id]; found {
+			a, found := q.m[id]
+			if !found {
+				a = p.fresh(timeNow)
+			}
+			share = p.tokens(a, timeNow)
+		}
+		if q.pool.maxCount > 0 {
+			pool = q.pool.tokens(q.poolState, timeNow)
+		}
+	})
+	return share, pool
+}
@@ -0,0 +1,53 @@
+package limiter
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidRate = errors.New("limiter: invalid rate")
+
+// parse rate like "100/m", "10/s", "5000/h" or "1000/d"
+// into count of actions and window
+//
+// unit can have multiplier or be any
+// time.ParseDuration() string like "5/10m" or "3/1h30m"
+func ParseRate(s string) (count int, window time.Duration, err error) {
+	n, unit, ok := strings.Cut(strings.TrimSpace(s), "/")
+	if !ok {
+		return 0, 0, fmt.Errorf("%w: %q has no '/'", ErrInvalidRate, s)
+	}
+
+	count, err = strconv.Atoi(strings.TrimSpace(n))
+	if err != nil || count <= 0 {
+		return 0, 0, fmt.Errorf("%w: bad count in %q", ErrInvalidRate, s)
+	}
+
+	unit = strings.TrimSpace(unit)
+	switch unit {
+	case "s", "sec", "second":
+		window = time.Second
+	case "m", "min", "minute":
+		window = time.Minute
+	case "h", "hour":
+		window = time.Hour
+	case "d", "day":
+		window = 24 * time.Hour
+	default:
+		if strings.HasSuffix(unit, "d") {
+			days, err := strconv.Atoi(strings.TrimSuffix(unit, "d"))
+			if err == nil && days > 0 {
+				return count, time.Duration(days) * 24 * time.Hour, nil
+			}
+		}
+		window, err = time.ParseDuration(unit)
+		if err != nil || window < time.Second {
+			return 0, 0, fmt.Errorf("%w: bad window in %q", ErrInvalidRate, s)
+		}
+	}
+
+	return count, window, nil
+}
@@ -0,0 +1,114 @@
+package limiter
+
+import (
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// env var that disables all registered limiters
+// when it is true like "1" or "true"
+const DisableEnv = "LIMITER_DISABLED"
+
+// limiter that can be paused
+// every limiter.Limiter[T] implements it
+type Pausable interface {
+	Pause(mode PauseMode)
+	Resume()
+}
+
+var registry = struct {
+	mu       sync.Mutex
+	m        map[string]Pausable
+	disabled bool
+	checked  bool
+}{}
+
+// add l to package registry with name
+// so it can be disabled with others by DisableAll()
+// or by DisableEnv, same name replaces old limiter
+//
+// l is paused right away if limiters are disabled
+func Register(name string, l Pausable) {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+
+	checkEnv()
+	if registry.m == nil {
+		registry.m = make(map[string]Pausable)
+	}
+	registry.m[name] = l
+	if registry.disabled {
+		l.Pause(PauseAllow)
+	}
+}
+
+// remove limiter with name from registry
+// it is left as it is, even if paused
+func Unregister(name string) {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	delete(registry.m, name)
+}
+
+// sorted names of registered limiters
+func Registered() []string {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+
+	names := make([]string, 0, len(registry.m))
+	for name := range registry.m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// take all registered limiters out of path
+// they allow every action until EnableAll()
+// also limiters registered after it are paused
+func DisableAll() {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+
+	registry.checked = true
+	registry.disabled = true
+	for _, l := range registry.m {
+		l.Pause(PauseAllow)
+	}
+}
+
+// resume all registered limiters after DisableAll()
+// or DisableEnv, limiters paused by own Pause()
+// calls are resumed too
+func EnableAll() {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+
+	registry.checked = true
+	registry.disabled = false
+	for _, l := range registry.m {
+		l.Resume()
+	}
+}
+
+// true if registered limiters are disabled
+func Disabled() bool {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	checkEnv()
+	return registry.disabled
+}
+
+// read DisableEnv once, calls of
+// DisableAll() and EnableAll() override it
+//
+// registry.mu must be held
+func checkEnv() {
+	if registry.checked {
+		return
+	}
+	registry.checked = true
+	registry.disabled, _ = strconv.ParseBool(os.Getenv(DisableEnv))
+}
@@ -0,0 +1,91 @@
+package limiter
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+
+	"golang.org/x/exp/constraints"
+)
+
+// reloads limiter config from file
+// on SIGHUP or Reload() call
+type Reloader[T constraints.Ordered] struct {
+	l      *Limiter[T]
+	path   string
+	decode func(data []byte) (Config[T], error)
+
+	mu   sync.Mutex
+	last Config[T]
+}
+
+// make new reloader of l config from file at path
+// if decode is nil file is decoded with DecodeJSON()
+func NewReloader[T constraints.Ordered](
+	l *Limiter[T],
+	path string,
+	decode func(data []byte) (Config[T], error),
+) *Reloader[T] {
+	if decode == nil {
+		decode = DecodeJSON[T]
+	}
+	return &Reloader[T]{
+		l:      l,
+		path:   path,
+		decode: decode,
+	}
+}
+
+// read, decode and validate file and apply it
+// on any error limiter keeps its current config
+func (r *Reloader[T]) Reload() error {
+	data, err := os.ReadFile(r.path)
+	if err != nil {
+		return err
+	}
+	cfg, err := r.decode(data)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.l.Apply(cfg); err != nil {
+		return err
+	}
+	r.last = cfg
+	return nil
+}
+
+// last successfully applied config
+func (r *Reloader[T]) Last() Config[T] {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.last
+}
+
+// reload config on every SIGHUP until ctx is done
+// it blocks so run it in your own goroutine
+//
+// reload errors are passed to onErr
+// nil onErr ignores them
+//
+// returns ctx.Err()
+func (r *Reloader[T]) Run(ctx context.Context, onErr func(err error)) error {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGHUP)
+	defer signal.Stop(ch)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ch:
+			if err := r.Reload(); err != nil && onErr != nil {
+				onErr(err)
+			}
+		}
+	}
+}
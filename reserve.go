@@ -0,0 +1,209 @@
+package limiter
+
+import (
+	"errors"
+	"time"
+
+	"github.com/ssleert/mu"
+	"golang.org/x/exp/constraints"
+)
+
+var (
+	ErrWindowFull  = errors.New("limiter: window is fully reserved")
+	ErrCantReserve = errors.New("limiter: window can't be reserved")
+)
+
+// units of key booked for window with time at
+type booking struct {
+	at  int64
+	n   int
+	seq uint64
+}
+
+// units booked by ReserveAt()
+type Reservation[T constraints.Ordered] struct {
+	l   *Limiter[T]
+	id  T
+	seq uint64
+
+	// time units were booked for
+	At time.Time
+	N  int
+}
+
+// like ReserveNAt() with one unit
+func (l *Limiter[T]) ReserveAt(id T, t time.Time) (*Reservation[T], error) {
+	return l.ReserveNAt(id, 1, t)
+}
+
+// book n units of key in window that has t
+// so schedulers can plan work in upcoming windows
+// booked units count as spent in that window
+// so don't Try() for them again
+//
+// rolling windows of key are predicted to go
+// back to back from current one, if key is idle
+// and its new window starts later, units are
+// counted in window that has t at that time
+//
+// returns ErrWindowFull if window has no n units
+// ErrCantReserve if t is in past or key policy
+// has burst, tokens have no windows to book and
+// ErrMapFull if key is new and FullDeny keeps it out
+// n < 1 is counted as 1
+func (l *Limiter[T]) ReserveNAt(id T, n int, t time.Time) (*Reservation[T], error) {
+	if n < 1 {
+		n = 1
+	}
+	timeNow := l.now().Unix()
+	at := t.Unix()
+	if at < timeNow {
+		return nil, ErrCantReserve
+	}
+	// bookings are counted in shared map
+	l.moveBack(id)
+
+	var (
+		r   *Reservation[T]
+		err error
+	)
+	mu.ExecMutex(&l.mu, func() {
+		p := l.policyOf(id)
+		if p.burst > 0 {
+			err = ErrCantReserve
+			return
+		}
+		a, found := l.get(id)
+		if !found {
+			if _, booked := l.bookings[id]; !booked && !l.makeSpace(1, timeNow, id) {
+				err = ErrMapFull
+				return
+			}
+			a = p.fresh(timeNow)
+		}
+		a = l.current(a, p, timeNow)
+		l.dropBookings(id, a.deltaTime)
+
+		start, end := p.windowOf(a, at)
+		used, limit := l.booked(id, start, end), p.maxCount
+		if start == a.deltaTime {
+			used += a.count
+			limit += a.carry
+		}
+		if used+n > limit {
+			err = ErrWindowFull
+			return
+		}
+
+		if l.bookings == nil {
+			l.bookings = make(map[T][]booking)
+		}
+		l.bookSeq++
+		l.bookings[id] = append(l.bookings[id], booking{
+			at:  at,
+			n:   n,
+			seq: l.bookSeq,
+		})
+		r = &Reservation[T]{
+			l:   l,
+			id:  id,
+			seq: l.bookSeq,
+			At:  t,
+			N:   n,
+		}
+	})
+	return r, err
+}
+
+// give booked units back to key
+// returns false if reservation is already
+// canceled or dropped after its window
+func (r *Reservation[T]) Cancel() bool {
+	l := r.l
+	var ok bool
+	mu.ExecMutex(&l.mu, func() {
+		bs := l.bookings[r.id]
+		for i, b := range bs {
+			if b.seq != r.seq {
+				continue
+			}
+			bs = append(bs[:i], bs[i+1:]...)
+			if len(bs) == 0 {
+				delete(l.bookings, r.id)
+			} else {
+				l.bookings[r.id] = bs
+			}
+			ok = true
+			return
+		}
+	})
+	return ok
+}
+
+// start and end of window of key that has at
+// rolling windows are predicted back to back
+func (p policy) windowOf(a action, at int64) (int64, int64) {
+	if p.schedule != nil {
+		start, end := p.schedule.Window(time.Unix(at, 0))
+		return start.Unix(), end.Unix()
+	}
+	end := p.end(a)
+	if at < end {
+		return a.deltaTime, end
+	}
+	length := p.maxTime + a.jitter
+	// window of zero length from bad snapshot or policy
+	if length <= 0 {
+		length = 1
+	}
+	start := end + (at-end)/length*length
+	return start, start + length
+}
+
+// units of key booked in [start, end)
+//
+// l.mu must be held
+func (l *Limiter[T]) booked(id T, start, end int64) int {
+	var n int
+	for _, b := range l.bookings[id] {
+		if b.at >= start && b.at < end {
+			n += b.n
+		}
+	}
+	return n
+}
+
+// drop bookings of key before start
+// they can't be counted in any window
+//
+// l.mu must be held
+func (l *Limiter[T]) dropBookings(id T, start int64) {
+	bs, ok := l.bookings[id]
+	if !ok {
+		return
+	}
+	kept := bs[:0]
+	for _, b := range bs {
+		if b.at >= start {
+			kept = append(kept, b)
+		}
+	}
+	if len(kept) == 0 {
+		delete(l.bookings, id)
+		return
+	}
+	l.bookings[id] = kept
+}
+
+// drop dead bookings of all keys
+//
+// l.mu must be held
+func (l *Limiter[T]) cleanBookings(timeNow int64) {
+	for id := range l.bookings {
+		start := timeNow
+		if a, ok := l.get(id); ok {
+			start = l.current(a, l.policyOf(id), timeNow).deltaTime
+		}
+		l.dropBookings(id, start)
+	}
+}
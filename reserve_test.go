@@ -0,0 +1,151 @@
+package limiter_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ssleert/limiter"
+	"github.com/ssleert/limiter/limitertest"
+)
+
+func TestReserve(t *testing.T) {
+	type step struct {
+		// seconds from start of key window
+		at   int64
+		n    int
+		want error
+	}
+	tests := []struct {
+		name  string
+		steps []step
+		// actions of key allowed right after steps
+		allowed int
+	}{
+		{
+			name:    "current window",
+			steps:   []step{{10, 2, nil}, {20, 1, limiter.ErrWindowFull}},
+			allowed: 0,
+		},
+		{
+			name:    "next window",
+			steps:   []step{{70, 3, nil}, {100, 1, limiter.ErrWindowFull}, {130, 3, nil}},
+			allowed: 2,
+		},
+		{
+			name:    "past",
+			steps:   []step{{-1, 1, limiter.ErrCantReserve}},
+			allowed: 2,
+		},
+		{
+			name:    "too many",
+			steps:   []step{{10, 3, limiter.ErrWindowFull}},
+			allowed: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := limitertest.NewClock(time.Unix(1000, 0))
+			l := limiter.New[string](3, 60, 16, 1024, 16)
+			l.SetDeterministic(c.Now, 1)
+			limitertest.AssertAllowed[string](t, l, "a", 1)
+
+			for _, s := range tt.steps {
+				_, err := l.ReserveNAt("a", s.n, time.Unix(1000+s.at, 0))
+				if !errors.Is(err, s.want) {
+					t.Fatalf("ReserveNAt(%d, +%ds) err = %v, want %v", s.n, s.at, err, s.want)
+				}
+			}
+			limitertest.AssertAllowed[string](t, l, "a", tt.allowed)
+			limitertest.AssertDenied[string](t, l, "a")
+		})
+	}
+}
+
+func TestReserveRollover(t *testing.T) {
+	c := limitertest.NewClock(time.Unix(1000, 0))
+	l := limiter.New[string](3, 60, 16, 1024, 16)
+	l.SetDeterministic(c.Now, 1)
+	limitertest.AssertAllowed[string](t, l, "a", 1)
+
+	// booked for next window
+	if _, err := l.ReserveNAt("a", 2, time.Unix(1070, 0)); err != nil {
+		t.Fatalf("ReserveNAt() err = %v", err)
+	}
+	limitertest.AssertAllowed[string](t, l, "a", 2)
+	limitertest.AssertDenied[string](t, l, "a")
+
+	// booked units are spent in new window
+	c.Advance(65 * time.Second)
+	limitertest.AssertAllowed[string](t, l, "a", 1)
+	limitertest.AssertDenied[string](t, l, "a")
+
+	// bookings of ended windows are dropped
+	c.Advance(60 * time.Second)
+	limitertest.AssertAllowed[string](t, l, "a", 3)
+}
+
+func TestReservationCancel(t *testing.T) {
+	c := limitertest.NewClock(time.Unix(1000, 0))
+	l := limiter.New[string](3, 60, 16, 1024, 16)
+	l.SetDeterministic(c.Now, 1)
+
+	r, err := l.ReserveNAt("a", 3, time.Unix(1010, 0))
+	if err != nil {
+		t.Fatalf("ReserveNAt() err = %v", err)
+	}
+	if _, err := l.ReserveAt("a", time.Unix(1020, 0)); !errors.Is(err, limiter.ErrWindowFull) {
+		t.Fatalf("ReserveAt() of full window err = %v, want %v", err, limiter.ErrWindowFull)
+	}
+	if !r.Cancel() {
+		t.Fatal("Cancel() = false, want true")
+	}
+	if r.Cancel() {
+		t.Fatal("second Cancel() = true, want false")
+	}
+	limitertest.AssertAllowed[string](t, l, "a", 3)
+	limitertest.AssertDenied[string](t, l, "a")
+}
+
+func TestReserveZeroWindow(t *testing.T) {
+	c := limitertest.NewClock(time.Unix(1000, 0))
+	// window of zero seconds can come from New() or snapshots
+	l := limiter.New[string](3, 0, 16, 1024, 16)
+	l.SetDeterministic(c.Now, 1)
+	limitertest.AssertAllowed[string](t, l, "a", 1)
+
+	if _, err := l.ReserveAt("a", time.Unix(1010, 0)); err != nil {
+		t.Fatalf("ReserveAt() err = %v", err)
+	}
+}
+
+func TestReserveFullMap(t *testing.T) {
+	tests := []struct {
+		name    string
+		full    limiter.FullPolicy
+		id      string
+		want    error
+		evicted uint64
+	}{
+		{"deny new", limiter.FullDeny, "b", limiter.ErrMapFull, 0},
+		{"deny known", limiter.FullDeny, "a", nil, 0},
+		{"allow new", limiter.FullAllow, "b", nil, 0},
+		{"evict new", limiter.FullEvict, "b", nil, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := limitertest.NewClock(time.Unix(1000, 0))
+			l := limiter.New[string](3, 60, 1, 1, 16)
+			l.SetDeterministic(c.Now, 1)
+			l.SetFullPolicy(tt.full)
+			limitertest.AssertAllowed[string](t, l, "a", 1)
+
+			if _, err := l.ReserveAt(tt.id, time.Unix(1010, 0)); !errors.Is(err, tt.want) {
+				t.Fatalf("ReserveAt(%s) err = %v, want %v", tt.id, err, tt.want)
+			}
+			if got := l.Stats().Evicted; got != tt.evicted {
+				t.Fatalf("Evicted = %d, want %d", got, tt.evicted)
+			}
+		})
+	}
+}
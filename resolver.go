@@ -0,0 +1,125 @@
+package limiter
+
+import (
+	"github.com/ssleert/mu"
+)
+
+// gives policy for key by its attributes
+// like geoip country, asn or customer segment
+// zero Policy means key gets default policy
+type PolicyResolver[T any] func(id T) Policy
+
+// call f on first Try() of key without own policy
+// result is used for key until its entry is removed
+// f is called without limiter lock held
+//
+// nil f removes resolver
+func (l *Limiter[T]) SetPolicyResolver(f PolicyResolver[T]) {
+	defer l.moveBack()
+	if f == nil {
+		l.resolver.Store(nil)
+	} else {
+		l.resolver.Store(&f)
+	}
+	mu.ExecMutex(&l.mu, func() {
+		l.resolved = nil
+		l.distrust()
+	})
+}
+
+// set policy of id like resolver does
+// it is used until key entry is removed
+// and own policy of SetKeyPolicy() wins over it
+// zero p means key gets default policy
+//
+// for policies looked up on every request like
+// plan of customer, call with same policy
+// takes only read lock and changes nothing
+func (l *Limiter[T]) ResolvePolicy(id T, p Policy) {
+	if hk := l.hotOf(id); hk != nil {
+		hk.l.ResolvePolicy(id, p)
+		return
+	}
+	ip := resolvedPolicy(p)
+	var same bool
+	mu.ExecRWMutex(&l.mu, func() {
+		cur, ok := l.resolved[id]
+		same = ok && cur.same(ip)
+	})
+	if same {
+		return
+	}
+	mu.ExecMutex(&l.mu, func() {
+		l.setResolved(id, ip)
+		l.distrust(id)
+	})
+	l.denyCache.forget(id)
+}
+
+// remove resolved policies of keys without entry
+// like ones set by ResolvePolicy() and never tried
+//
+// l.mu must be held
+func (l *Limiter[T]) cleanResolved() {
+	for id := range l.resolved {
+		if _, ok := l.m[id]; !ok {
+			delete(l.resolved, id)
+		}
+	}
+}
+
+// resolve policy of id if it is not known yet
+func (l *Limiter[T]) resolve(id T) (policy, bool) {
+	f := l.resolver.Load()
+	if f == nil {
+		return policy{}, false
+	}
+
+	var known bool
+	mu.ExecRWMutex(&l.mu, func() {
+		_, known = l.keyPolicies[id]
+		if !known {
+			_, known = l.resolved[id]
+		}
+	})
+	if known {
+		return policy{}, false
+	}
+	return resolvedPolicy((*f)(id)), true
+}
+
+// resolved policy in limiter units
+// zero p stays zero so default policy is used
+func resolvedPolicy(p Policy) policy {
+	if p == (Policy{}) {
+		return policy{}
+	}
+	return p.internal()
+}
+
+// resolved policy of id if it is not default one
+//
+// l.mu must be held
+func (l *Limiter[T]) resolvedOf(id T) (policy, bool) {
+	p, ok := l.resolved[id]
+	return p, ok && p.maxCount > 0
+}
+
+// remember resolved policy of id
+//
+// l.mu must be held
+func (l *Limiter[T]) setResolved(id T, p policy) {
+	if l.resolved == nil {
+		l.resolved = make(map[T]policy)
+	}
+	l.resolved[id] = p
+}
+
+// remove entry of id with its resolved policy
+//
+// l.mu must be held
+func (l *Limiter[T]) remove(id T) {
+	delete(l.m, id)
+	delete(l.resolved, id)
+	delete(l.meta, id)
+}
@@ -0,0 +1,152 @@
+package limiter_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ssleert/limiter"
+	"github.com/ssleert/limiter/limitertest"
+)
+
+func TestResolvePolicy(t *testing.T) {
+	five := limiter.Policy{MaxCount: 5, Window: time.Minute}
+	tests := []struct {
+		name string
+		run  func(t *testing.T, l *limiter.Limiter[string])
+		// actions of a allowed after run
+		allowed int
+		// true if a has own or resolved policy after run
+		known bool
+	}{
+		{
+			name:    "limits key",
+			run:     func(t *testing.T, l *limiter.Limiter[string]) { l.ResolvePolicy("a", five) },
+			allowed: 5,
+			known:   true,
+		},
+		{
+			name: "own policy wins",
+			run: func(t *testing.T, l *limiter.Limiter[string]) {
+				l.SetKeyPolicy("a", limiter.Policy{MaxCount: 3, Window: time.Minute})
+				l.ResolvePolicy("a", five)
+			},
+			allowed: 3,
+			known:   true,
+		},
+		{
+			name: "changed",
+			run: func(t *testing.T, l *limiter.Limiter[string]) {
+				l.ResolvePolicy("a", five)
+				limitertest.AssertAllowed[string](t, l, "a", 2)
+				l.ResolvePolicy("a", limiter.Policy{MaxCount: 3, Window: time.Minute})
+			},
+			allowed: 1,
+			known:   true,
+		},
+		{
+			name:    "zero is default",
+			run:     func(t *testing.T, l *limiter.Limiter[string]) { l.ResolvePolicy("a", limiter.Policy{}) },
+			allowed: 10,
+		},
+		{
+			name: "removed with entry",
+			run: func(t *testing.T, l *limiter.Limiter[string]) {
+				l.ResolvePolicy("a", five)
+				limitertest.AssertAllowed[string](t, l, "a", 1)
+				l.Reset("a")
+			},
+			allowed: 10,
+		},
+		{
+			name: "without entry",
+			run: func(t *testing.T, l *limiter.Limiter[string]) {
+				l.ResolvePolicy("a", five)
+				l.Clean()
+			},
+			allowed: 10,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := limitertest.NewClock(time.Unix(1000, 0))
+			l := limiter.New[string](10, 60, 16, 1024, 16)
+			l.SetDeterministic(c.Now, 1)
+
+			tt.run(t, l)
+			if _, ok := l.KeyPolicy("a"); ok != tt.known {
+				t.Fatalf("KeyPolicy() known = %v, want %v", ok, tt.known)
+			}
+			limitertest.AssertAllowed[string](t, l, "a", tt.allowed)
+			limitertest.AssertDenied[string](t, l, "a")
+		})
+	}
+}
+
+func TestResolvePolicySame(t *testing.T) {
+	minute := limiter.ScheduleFunc(func(t time.Time) (time.Time, time.Time) {
+		start := t.Truncate(time.Minute)
+		return start, start.Add(time.Minute)
+	})
+	tests := []struct {
+		name    string
+		p, next limiter.Policy
+		same    bool
+	}{
+		{
+			name: "window",
+			p:    limiter.Policy{MaxCount: 100, Window: time.Minute},
+			next: limiter.Policy{MaxCount: 100, Window: time.Minute},
+			same: true,
+		},
+		{
+			name: "aligned",
+			p:    limiter.Policy{MaxCount: 100, Schedule: limiter.Aligned(time.Minute)},
+			next: limiter.Policy{MaxCount: 100, Schedule: limiter.Aligned(time.Minute)},
+			same: true,
+		},
+		{
+			name: "daily",
+			p:    limiter.Policy{MaxCount: 100, Schedule: limiter.Daily(time.UTC)},
+			next: limiter.Policy{MaxCount: 100, Schedule: limiter.Daily(time.UTC)},
+			same: true,
+		},
+		{
+			name: "other count",
+			p:    limiter.Policy{MaxCount: 100, Window: time.Minute},
+			next: limiter.Policy{MaxCount: 50, Window: time.Minute},
+		},
+		{
+			name: "other schedule",
+			p:    limiter.Policy{MaxCount: 100, Schedule: limiter.Daily(time.UTC)},
+			next: limiter.Policy{MaxCount: 100, Schedule: limiter.Monthly(time.UTC)},
+		},
+		{
+			name: "schedule func",
+			p:    limiter.Policy{MaxCount: 100, Schedule: minute},
+			next: limiter.Policy{MaxCount: 100, Schedule: minute},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := limitertest.NewClock(time.Unix(1000, 0))
+			l := limiter.New[string](100, 60, 16, 1024, 16)
+			l.SetDeterministic(c.Now, 1)
+			l.SetTrust(3, 0.5, time.Second)
+
+			l.ResolvePolicy("a", tt.p)
+			limitertest.AssertAllowed[string](t, l, "a", 4)
+			c.Advance(2 * time.Second)
+			// ends trust period
+			limitertest.AssertAllowed[string](t, l, "a", 1)
+			if got := l.Trusted(); len(got) != 1 {
+				t.Fatalf("Trusted() = %v, want [a]", got)
+			}
+
+			// trust is dropped only when policy changes
+			l.ResolvePolicy("a", tt.next)
+			if got := len(l.Trusted()) == 1; got != tt.same {
+				t.Fatalf("trusted after ResolvePolicy() = %v, want %v", got, tt.same)
+			}
+		})
+	}
+}
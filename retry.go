@@ -0,0 +1,51 @@
+package limiter
+
+import (
+	"errors"
+	"time"
+)
+
+// error with hint when action can be tried again
+// errors of Wait(), WaitN() and DoOnce() that can
+// pass with time are wrapped in it, so errors.Is()
+// still matches ErrQueueFull and others
+type RetryError struct {
+	Err   error
+	After time.Duration
+}
+
+func (e *RetryError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *RetryError) Unwrap() error {
+	return e.Err
+}
+
+// time to wait before next try
+func (e *RetryError) RetryAfter() time.Duration {
+	return e.After
+}
+
+// get retry hint of err from any error in its chain
+// with RetryAfter() method, like RetryError
+// returns false if err has no hint
+func RetryHint(err error) (time.Duration, bool) {
+	var r interface {
+		RetryAfter() time.Duration
+	}
+	if errors.As(err, &r) {
+		return r.RetryAfter(), true
+	}
+	return 0, false
+}
+
+// wrap err with time until key can spend n units
+// err is returned as is if key never can
+func (l *Limiter[T]) retryError(err error, id T, n int) error {
+	d, ok := l.RetryAfter(id, n)
+	if !ok {
+		return err
+	}
+	return &RetryError{Err: err, After: d}
+}
@@ -0,0 +1,105 @@
+package limiter
+
+import (
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// sampled denied action
+type DeniedSample[T any] struct {
+	Key  T
+	Time time.Time
+	// given to TryMeta(), nil for other calls
+	Meta any
+}
+
+// uniform sample of denied actions
+type reservoir[T any] struct {
+	// size > 0, read without lock
+	// so denials skip it when disabled
+	on      atomic.Bool
+	mu      sync.Mutex
+	size    int
+	seen    uint64
+	samples []DeniedSample[T]
+	// seeded rand of deterministic mode
+	rng *rand.Rand
+}
+
+// keep uniform sample of up to n denied actions
+// so blocked traffic can be inspected
+// without logging every denial
+//
+// changing n drops current sample
+// n <= 0 disables sampling
+func (l *Limiter[T]) SampleDenials(n int) {
+	r := &l.samples
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.size = n
+	r.on.Store(n > 0)
+	r.seen = 0
+	r.samples = nil
+}
+
+// get copy of sampled denied actions
+// and count of denials they were sampled from
+func (l *Limiter[T]) DeniedSamples() ([]DeniedSample[T], uint64) {
+	r := &l.samples
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	res := make([]DeniedSample[T], len(r.samples))
+	copy(res, r.samples)
+	return res, r.seen
+}
+
+// like Try() but meta is kept with action
+// if it is denied and sampled, e.g. path of request
+func (l *Limiter[T]) TryMeta(id T, meta any) bool {
+	now := l.now()
+	ok := l.run(id, 1, noPriority, now)
+	if !ok {
+		l.sampleDenial(id, meta, now)
+	}
+	if s := l.shadow.Load(); s != nil {
+		l.compareShadow(ok, s.Try(id))
+	}
+	return ok
+}
+
+// add action denied at now to sample
+func (l *Limiter[T]) sampleDenial(id T, meta any, now time.Time) {
+	r := &l.samples
+	if !r.on.Load() {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.size <= 0 {
+		return
+	}
+	r.seen++
+	s := DeniedSample[T]{
+		Key:  id,
+		Time: now,
+		Meta: meta,
+	}
+	if len(r.samples) < r.size {
+		r.samples = append(r.samples, s)
+		return
+	}
+	var i int64
+	if r.rng != nil {
+		i = r.rng.Int63n(int64(r.seen))
+	} else {
+		i = rand.Int63n(int64(r.seen))
+	}
+	if i < int64(r.size) {
+		r.samples[i] = s
+	}
+}
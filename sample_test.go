@@ -0,0 +1,45 @@
+package limiter_test
+
+import (
+	"testing"
+
+	"github.com/ssleert/limiter"
+)
+
+func TestSampleDenials(t *testing.T) {
+	tests := []struct {
+		name    string
+		size    int
+		denials int
+		samples int
+		seen    uint64
+	}{
+		{"disabled", 0, 5, 0, 0},
+		{"negative", -1, 5, 0, 0},
+		{"not full", 10, 3, 3, 3},
+		{"full", 2, 5, 2, 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := limiter.New[string](1, 60, 16, 1024, 16)
+			l.SampleDenials(tt.size)
+			l.TryMeta("a", nil)
+			for i := 0; i < tt.denials; i++ {
+				if l.TryMeta("a", i) {
+					t.Fatalf("action %d allowed, want denied", i+2)
+				}
+			}
+
+			samples, seen := l.DeniedSamples()
+			if len(samples) != tt.samples || seen != tt.seen {
+				t.Fatalf("DeniedSamples() = %d samples of %d, want %d of %d",
+					len(samples), seen, tt.samples, tt.seen)
+			}
+			for _, s := range samples {
+				if s.Key != "a" || s.Meta == nil {
+					t.Fatalf("sample %+v, want key a with meta", s)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,217 @@
+package limiter
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+var ErrInvalidSchedule = errors.New("limiter: invalid schedule")
+
+// window boundaries that don't depend on
+// key first action like calendar days
+type Schedule interface {
+	// start of window containing t
+	// and start of next window
+	Window(t time.Time) (start, end time.Time)
+}
+
+// ScheduleFunc is func that implements Schedule
+type ScheduleFunc func(t time.Time) (start, end time.Time)
+
+func (f ScheduleFunc) Window(t time.Time) (start, end time.Time) {
+	return f(t)
+}
+
+// windows of length d aligned to unix epoch
+// so all keys reset together at every :00
+// of second, minute or hour depending on d
+//
+// without schedule window starts at key first action
+// d less than second is rounded up to second
+func Aligned(d time.Duration) Schedule {
+	n := int64(d / time.Second)
+	if n <= 0 {
+		n = 1
+	}
+	return aligned(n)
+}
+
+// window length in seconds
+type aligned int64
+
+func (n aligned) String() string {
+	return "aligned " + (time.Duration(n) * time.Second).String()
+}
+
+func (n aligned) Window(t time.Time) (time.Time, time.Time) {
+	u, s := t.Unix(), int64(n)
+	start := u - u%s
+	return time.Unix(start, 0), time.Unix(start+s, 0)
+}
+
+// windows from midnight to midnight in loc
+// e.g. 1000 actions per calendar day in Europe/Berlin
+//
+// if loc is nil time.Local is used
+func Daily(loc *time.Location) Schedule {
+	if loc == nil {
+		loc = time.Local
+	}
+	return daily{loc}
+}
+
+type daily struct {
+	loc *time.Location
+}
+
+func (d daily) String() string {
+	return "daily " + d.loc.String()
+}
+
+func (d daily) Window(t time.Time) (time.Time, time.Time) {
+	t = t.In(d.loc)
+	y, m, day := t.Date()
+	start := time.Date(y, m, day, 0, 0, 0, 0, d.loc)
+	return start, start.AddDate(0, 0, 1)
+}
+
+// true if window of entry ended at timeNow
+func (p policy) ended(a action, timeNow int64) bool {
+	if p.schedule == nil {
+		return timeNow-a.deltaTime >= p.maxTime+a.jitter
+	}
+	return timeNow >= p.end(a)
+}
+
+// true if window at timeNow goes
+// right after window of entry
+func (p policy) next(a action, timeNow int64) bool {
+	if p.schedule == nil {
+		return timeNow-a.deltaTime < 2*(p.maxTime+a.jitter)
+	}
+	return p.start(timeNow) == p.end(a)
+}
+
+// start of new window at timeNow
+func (p policy) start(timeNow int64) int64 {
+	if p.schedule == nil {
+		return timeNow
+	}
+	start, _ := p.schedule.Window(time.Unix(timeNow, 0))
+	return start.Unix()
+}
+
+// end of entry window
+func (p policy) end(a action) int64 {
+	if p.schedule == nil {
+		return a.deltaTime + p.maxTime + a.jitter
+	}
+	_, end := p.schedule.Window(time.Unix(a.deltaTime, 0))
+	return end.Unix()
+}
+
+// windows from first day of month to first day
+// of next month in loc, so month lengths are exact
+//
+// use Save() and Load() to keep quotas
+// across restarts in the middle of month
+//
+// if loc is nil time.Local is used
+func Monthly(loc *time.Location) Schedule {
+	if loc == nil {
+		loc = time.Local
+	}
+	return monthly{loc}
+}
+
+type monthly struct {
+	loc *time.Location
+}
+
+func (m monthly) String() string {
+	return "monthly " + m.loc.String()
+}
+
+func (m monthly) Window(t time.Time) (time.Time, time.Time) {
+	t = t.In(m.loc)
+	y, mon, _ := t.Date()
+	start := time.Date(y, mon, 1, 0, 0, 0, 0, m.loc)
+	return start, start.AddDate(0, 1, 0)
+}
+
+// parse schedule from text form
+// that schedules of this package print
+//
+//	"aligned 1m"
+//	"daily Europe/Berlin"
+//	"monthly UTC"
+//	"cron 0 9 * * 1 Local"
+//
+// location is optional and time.Local by default
+func ParseSchedule(s string) (Schedule, error) {
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("%w: empty", ErrInvalidSchedule)
+	}
+
+	kind, args := fields[0], fields[1:]
+	// location is last argument
+	loc := func(n int) (*time.Location, error) {
+		if len(args) == n {
+			return time.Local, nil
+		}
+		if len(args) != n+1 {
+			return nil, fmt.Errorf("%w: %q has %d arguments", ErrInvalidSchedule, s, len(args))
+		}
+		l, err := time.LoadLocation(args[n])
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q: %v", ErrInvalidSchedule, s, err)
+		}
+		return l, nil
+	}
+
+	switch kind {
+	case "aligned":
+		if len(args) != 1 {
+			return nil, fmt.Errorf("%w: %q needs window", ErrInvalidSchedule, s)
+		}
+		d, err := time.ParseDuration(args[0])
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("%w: bad window in %q", ErrInvalidSchedule, s)
+		}
+		return Aligned(d), nil
+	case "daily", "monthly":
+		l, err := loc(0)
+		if err != nil {
+			return nil, err
+		}
+		if kind == "daily" {
+			return Daily(l), nil
+		}
+		return Monthly(l), nil
+	case "cron":
+		if len(args) < 5 {
+			return nil, fmt.Errorf("%w: %q needs 5 cron fields", ErrInvalidSchedule, s)
+		}
+		l, err := loc(5)
+		if err != nil {
+			return nil, err
+		}
+		return Cron(strings.Join(args[:5], " "), l)
+	}
+	return nil, fmt.Errorf("%w: unknown kind %q", ErrInvalidSchedule, kind)
+}
+
+// true if a and b are same schedule
+// schedules of not comparable types like
+// ScheduleFunc are never same
+func sameSchedule(a, b Schedule) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	ta, tb := reflect.TypeOf(a), reflect.TypeOf(b)
+	return ta == tb && ta.Comparable() && a == b
+}
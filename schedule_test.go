@@ -0,0 +1,124 @@
+package limiter_test
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ssleert/limiter"
+)
+
+func TestParseSchedule(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+		err  error
+	}{
+		{in: "aligned 1m", want: "aligned 1m0s"},
+		{in: "aligned 1m0s", want: "aligned 1m0s"},
+		{in: "daily UTC", want: "daily UTC"},
+		{in: "daily", want: "daily Local"},
+		{in: "monthly Europe/Berlin", want: "monthly Europe/Berlin"},
+		{in: "cron */15 * * * *", want: "cron */15 * * * * Local"},
+		{in: "cron 0   9 * * 1 UTC", want: "cron 0 9 * * 1 UTC"},
+		{in: "", err: limiter.ErrInvalidSchedule},
+		{in: "weekly", err: limiter.ErrInvalidSchedule},
+		{in: "aligned", err: limiter.ErrInvalidSchedule},
+		{in: "aligned -1m", err: limiter.ErrInvalidSchedule},
+		{in: "daily Nowhere/City", err: limiter.ErrInvalidSchedule},
+		{in: "daily UTC UTC", err: limiter.ErrInvalidSchedule},
+		{in: "cron * * *", err: limiter.ErrInvalidSchedule},
+		{in: "cron 0 0 31 2 *", err: limiter.ErrInvalidCron},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			s, err := limiter.ParseSchedule(tt.in)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("ParseSchedule() err = %v, want %v", err, tt.err)
+			}
+			if err != nil {
+				return
+			}
+			got := s.(interface{ String() string }).String()
+			if got != tt.want {
+				t.Fatalf("String() = %q, want %q", got, tt.want)
+			}
+			if _, err := limiter.ParseSchedule(got); err != nil {
+				t.Fatalf("ParseSchedule(%q) err = %v", got, err)
+			}
+		})
+	}
+}
+
+func TestPolicyJSON(t *testing.T) {
+	daily := limiter.Daily(time.UTC)
+	cur := limiter.Policy{MaxCount: 10, Window: time.Minute, Burst: 20, Schedule: daily}
+	tests := []struct {
+		name string
+		data string
+		want limiter.Policy
+		err  bool
+	}{
+		{
+			name: "empty keeps all",
+			data: `{}`,
+			want: cur,
+		},
+		{
+			name: "rate keeps burst and schedule",
+			data: `{"max_count":5,"window":"1h"}`,
+			want: limiter.Policy{MaxCount: 5, Window: time.Hour, Burst: 20, Schedule: daily},
+		},
+		{
+			name: "schedule",
+			data: `{"schedule":"aligned 1h"}`,
+			want: limiter.Policy{MaxCount: 10, Window: time.Minute, Burst: 20, Schedule: limiter.Aligned(time.Hour)},
+		},
+		{
+			name: "no schedule",
+			data: `{"schedule":""}`,
+			want: limiter.Policy{MaxCount: 10, Window: time.Minute, Burst: 20},
+		},
+		{
+			name: "bad schedule",
+			data: `{"max_count":5,"schedule":"weekly"}`,
+			want: cur,
+			err:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := cur
+			err := json.Unmarshal([]byte(tt.data), &p)
+			if (err != nil) != tt.err {
+				t.Fatalf("Unmarshal() err = %v, want error %v", err, tt.err)
+			}
+			if p != tt.want {
+				t.Fatalf("Unmarshal() = %+v, want %+v", p, tt.want)
+			}
+
+			// schedule survives round trip
+			b, err := json.Marshal(p)
+			if err != nil {
+				t.Fatalf("Marshal() err = %v", err)
+			}
+			var back limiter.Policy
+			if err := json.Unmarshal(b, &back); err != nil {
+				t.Fatalf("Unmarshal(%s) err = %v", b, err)
+			}
+			if back != p {
+				t.Fatalf("round trip of %s = %+v, want %+v", b, back, p)
+			}
+		})
+	}
+}
+
+func TestPolicyJSONScheduleFunc(t *testing.T) {
+	p := limiter.Policy{Schedule: limiter.ScheduleFunc(func(t time.Time) (time.Time, time.Time) {
+		return t, t.Add(time.Minute)
+	})}
+	if _, err := json.Marshal(p); !errors.Is(err, limiter.ErrScheduleText) {
+		t.Fatalf("Marshal() err = %v, want %v", err, limiter.ErrScheduleText)
+	}
+}
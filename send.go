@@ -0,0 +1,88 @@
+package limiter
+
+import (
+	"time"
+)
+
+// key of global rate in SendQuota
+const sendGlobalKey = ""
+
+// quotas of message sender like email, sms or
+// webhooks, every recipient and every sender
+// has daily quota and all sends share global rate
+//
+// send counts only if every quota allows it
+// so denied send spends none of them
+type SendQuota struct {
+	recipients *Limiter[string]
+	senders    *Limiter[string]
+	global     *Limiter[string]
+}
+
+// make new send quota with daily quotas per recipient
+// and per sender and global rate of sends per window
+// days start at midnight in loc, nil loc is UTC
+func NewSendQuota(
+	perRecipient,
+	perSender int,
+	loc *time.Location,
+	rate int,
+	window time.Duration,
+) *SendQuota {
+	if loc == nil {
+		loc = time.UTC
+	}
+	daily := func(n int) *Limiter[string] {
+		l := New[string](n, Default, Default, Default, Default)
+		l.SetPolicy(Policy{MaxCount: n, Schedule: Daily(loc)})
+		return l
+	}
+
+	global := New[string](rate, Default, 1, 0, Default)
+	global.SetPolicy(Policy{MaxCount: rate, Window: window})
+
+	return &SendQuota{
+		recipients: daily(perRecipient),
+		senders:    daily(perSender),
+		global:     global,
+	}
+}
+
+// limiters of quotas for own settings
+// like SetKeyPolicy() for big sender
+func (q *SendQuota) Recipients() *Limiter[string] {
+	return q.recipients
+}
+
+func (q *SendQuota) Senders() *Limiter[string] {
+	return q.senders
+}
+
+func (q *SendQuota) Global() *Limiter[string] {
+	return q.global
+}
+
+// true if sender can send message to recipient now
+func (q *SendQuota) Try(sender, recipient string) bool {
+	return TryAll(
+		q.recipients.Check(recipient, 1),
+		q.senders.Check(sender, 1),
+		q.global.Check(sendGlobalKey, 1),
+	)
+}
+
+// true if sender can send message to all recipients
+// now, e.g. for one sms to many phones, message is
+// sent to all of them or none
+func (q *SendQuota) TryMany(sender string, recipients ...string) bool {
+	cs := make([]Check, 0, len(recipients)+2)
+	for _, r := range recipients {
+		cs = append(cs, q.recipients.Check(r, 1))
+	}
+	n := len(recipients)
+	cs = append(cs,
+		q.senders.Check(sender, n),
+		q.global.Check(sendGlobalKey, n),
+	)
+	return TryAll(cs...)
+}
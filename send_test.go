@@ -0,0 +1,52 @@
+package limiter_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ssleert/limiter"
+	"github.com/ssleert/limiter/limitertest"
+)
+
+func TestSendQuotaDays(t *testing.T) {
+	// local zone far from utc so
+	// local and utc midnights differ
+	local := time.Local
+	time.Local = time.FixedZone("UTC+5", 5*3600)
+	t.Cleanup(func() { time.Local = local })
+
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("no tzdata:", err)
+	}
+	tests := []struct {
+		name string
+		loc  *time.Location
+		// last minute of day in loc
+		end time.Time
+	}{
+		{"nil is utc", nil, time.Date(2024, 3, 10, 23, 59, 0, 0, time.UTC)},
+		{"utc", time.UTC, time.Date(2024, 3, 10, 23, 59, 0, 0, time.UTC)},
+		{"berlin", berlin, time.Date(2024, 3, 10, 23, 59, 0, 0, berlin)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := limiter.NewSendQuota(1, 10, tt.loc, 100, time.Second)
+			c := limitertest.NewClock(tt.end.Add(-time.Hour))
+			c.Attach(q.Recipients(), q.Senders(), q.Global())
+
+			if !q.Try("s", "r") {
+				t.Fatal("first send denied")
+			}
+			c.Set(tt.end)
+			if q.Try("s", "r") {
+				t.Fatal("second send of same day allowed")
+			}
+			// new day in loc
+			c.Advance(2 * time.Minute)
+			if !q.Try("s", "r") {
+				t.Fatal("send of next day denied")
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package limiter
+
+import (
+	"sync/atomic"
+)
+
+// comparison of primary and shadow decisions
+type ShadowStats struct {
+	// actions decided by both limiters
+	Compared uint64
+	// actions where both made same decision
+	Agreed uint64
+	// actions allowed by primary but denied by shadow
+	WouldDeny uint64
+	// actions denied by primary but allowed by shadow
+	WouldAllow uint64
+}
+
+// part of Compared where limiters agreed
+func (s ShadowStats) AgreementRate() float64 {
+	if s.Compared == 0 {
+		return 1
+	}
+	return float64(s.Agreed) / float64(s.Compared)
+}
+
+type shadowCounters struct {
+	compared   atomic.Uint64
+	agreed     atomic.Uint64
+	wouldDeny  atomic.Uint64
+	wouldAllow atomic.Uint64
+}
+
+// attach shadow limiter with candidate config
+// every Try() is repeated on shadow and decisions
+// are compared, shadow never changes Try() result
+//
+// shadow must not be in dry run mode
+// nil s detaches shadow
+func (l *Limiter[T]) SetShadow(s *Limiter[T]) {
+	l.shadow.Store(s)
+}
+
+// get comparison of primary and shadow decisions
+func (l *Limiter[T]) ShadowStats() ShadowStats {
+	return ShadowStats{
+		Compared:   l.shadowStats.compared.Load(),
+		Agreed:     l.shadowStats.agreed.Load(),
+		WouldDeny:  l.shadowStats.wouldDeny.Load(),
+		WouldAllow: l.shadowStats.wouldAllow.Load(),
+	}
+}
+
+// record decision of shadow for primary decision ok
+func (l *Limiter[T]) compareShadow(ok, shadow bool) {
+	l.shadowStats.compared.Add(1)
+	switch {
+	case ok == shadow:
+		l.shadowStats.agreed.Add(1)
+	case ok:
+		l.shadowStats.wouldDeny.Add(1)
+	default:
+		l.shadowStats.wouldAllow.Add(1)
+	}
+}
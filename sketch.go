@@ -0,0 +1,202 @@
+package limiter
+
+import (
+	"context"
+	"encoding/binary"
+	"hash/maphash"
+	"math"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/ssleert/mu"
+	"golang.org/x/exp/constraints"
+)
+
+// approximate limiter backed by count-min sketch
+// it uses same memory for any count of keys
+//
+// sketch never counts less than key real count
+// so it never allows more than limit, but it can
+// deny key early, with width w and depth d count
+// of key is over by at most e/w * N with
+// probability 1 - (1/e)^d where N is count of
+// all actions in window and e is 2.718...
+//
+// e.g. width 2^16 and depth 4 with 10M actions
+// per window overcount by at most 415
+// in 98% of cases
+type Sketch[T constraints.Ordered] struct {
+	mu sync.Mutex
+
+	counts []uint32
+	width  uint64
+	depth  uint64
+	seed   maphash.Seed
+
+	limit  uint32
+	window int64
+	// start of current window
+	start int64
+}
+
+// make new sketch limiter with limit of actions per
+// window for every key, windows are aligned to
+// unix epoch and resolution is one second
+//
+// width and depth <= 0 set to 2^16 and 4
+func NewSketch[T constraints.Ordered](
+	limit int,
+	window time.Duration,
+	width,
+	depth int,
+) *Sketch[T] {
+	if width <= 0 {
+		width = 1 << 16
+	}
+	if depth <= 0 {
+		depth = 4
+	}
+	w := int64(window / time.Second)
+	if w < 1 {
+		w = 1
+	}
+	if limit > math.MaxUint32 {
+		limit = math.MaxUint32
+	}
+	return &Sketch[T]{
+		counts: make([]uint32, width*depth),
+		width:  uint64(width),
+		depth:  uint64(depth),
+		seed:   maphash.MakeSeed(),
+		limit:  uint32(limit),
+		window: w,
+	}
+}
+
+// true if estimated count of key is under limit
+func (s *Sketch[T]) Try(id T) bool {
+	return s.TryN(id, 1)
+}
+
+// true if key can take n actions by estimated count
+// n < 1 is counted as 1
+func (s *Sketch[T]) TryN(id T, n int) bool {
+	if n < 1 {
+		n = 1
+	}
+	h1, h2 := s.hash(id)
+	timeNow := time.Now().Unix()
+
+	var ok bool
+	mu.ExecMutex(&s.mu, func() {
+		s.roll(timeNow)
+
+		est := s.estimate(h1, h2)
+		if uint64(est)+uint64(n) > uint64(s.limit) {
+			return
+		}
+		// conservative update raises only
+		// smallest counters to keep overcount low
+		for i := uint64(0); i < s.depth; i++ {
+			c := &s.counts[s.index(i, h1, h2)]
+			if *c < est+uint32(n) {
+				*c = est + uint32(n)
+			}
+		}
+		ok = true
+	})
+	return ok
+}
+
+// wait until key can take action or ctx is done
+// it tries again at start of every window
+//
+// returns ErrDenied if limit is 0
+func (s *Sketch[T]) Wait(ctx context.Context, id T) error {
+	if s.limit == 0 {
+		return ErrDenied
+	}
+	return waitWindows(ctx, s.window, time.Now, func() bool {
+		return s.Try(id)
+	})
+}
+
+// always false because counters of
+// sketch are shared by many keys
+// key is reset only by window end
+func (s *Sketch[T]) Reset(id T) bool {
+	return false
+}
+
+// estimated count of key in current window
+func (s *Sketch[T]) Count(id T) int {
+	h1, h2 := s.hash(id)
+	timeNow := time.Now().Unix()
+
+	var est uint32
+	mu.ExecMutex(&s.mu, func() {
+		s.roll(timeNow)
+		est = s.estimate(h1, h2)
+	})
+	return int(est)
+}
+
+// s.mu must be held
+func (s *Sketch[T]) estimate(h1, h2 uint64) uint32 {
+	est := uint32(math.MaxUint32)
+	for i := uint64(0); i < s.depth; i++ {
+		if c := s.counts[s.index(i, h1, h2)]; c < est {
+			est = c
+		}
+	}
+	return est
+}
+
+// reset counts when window ends
+//
+// s.mu must be held
+func (s *Sketch[T]) roll(timeNow int64) {
+	start := timeNow - timeNow%s.window
+	if start == s.start {
+		return
+	}
+	s.start = start
+	for i := range s.counts {
+		s.counts[i] = 0
+	}
+}
+
+// counter of key in row i
+func (s *Sketch[T]) index(i, h1, h2 uint64) uint64 {
+	return i*s.width + (h1+i*h2)%s.width
+}
+
+// two hashes of key for double hashing
+func (s *Sketch[T]) hash(id T) (uint64, uint64) {
+	var h maphash.Hash
+	h.SetSeed(s.seed)
+	writeKey(&h, id)
+	sum := h.Sum64()
+	// odd second hash goes over all columns
+	// of power of two width
+	return sum, (sum>>32 | sum<<32) | 1
+}
+
+// write key bytes to h by its kind
+func writeKey[T constraints.Ordered](h *maphash.Hash, id T) {
+	var b [8]byte
+	v := reflect.ValueOf(id)
+	switch {
+	case v.Kind() == reflect.String:
+		h.WriteString(v.String())
+		return
+	case v.CanInt():
+		binary.LittleEndian.PutUint64(b[:], uint64(v.Int()))
+	case v.CanUint():
+		binary.LittleEndian.PutUint64(b[:], v.Uint())
+	case v.CanFloat():
+		binary.LittleEndian.PutUint64(b[:], math.Float64bits(v.Float()))
+	}
+	h.Write(b[:])
+}
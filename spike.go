@@ -0,0 +1,90 @@
+package limiter
+
+import (
+	"math"
+	"time"
+
+	"github.com/ssleert/mu"
+)
+
+// weight of last interval in key average
+const spikeAlpha = 0.2
+
+type spikeDetector[T any] struct {
+	// seconds in one interval
+	// 0 disables detector
+	interval int64
+	factor   float64
+	f        func(id T, rate, avg float64)
+}
+
+// call f when Try() calls of key in interval go over
+// factor times key average calls per interval
+// even if key is under its limit, e.g. for early
+// warnings about abuse
+//
+// average is ewma over past intervals and f is called
+// at most once per interval outside of limiter lock
+// rate and avg are in calls per second
+//
+// nil f disables it
+// resolution is one second
+func (l *Limiter[T]) OnSpike(
+	interval time.Duration,
+	factor float64,
+	f func(id T, rate, avg float64),
+) {
+	defer l.moveBack()
+	secs := int64(interval / time.Second)
+	if secs < 1 {
+		secs = 1
+	}
+	mu.ExecMutex(&l.mu, func() {
+		l.spike = spikeDetector[T]{}
+		if f != nil {
+			l.spike = spikeDetector[T]{
+				interval: secs,
+				factor:   factor,
+				f:        f,
+			}
+		}
+	})
+}
+
+// count call of key and check it for spike
+//
+// l.mu must be held
+func (l *Limiter[T]) detectSpike(a *action, o *outcome[T], timeNow int64) {
+	d := l.spike
+	if d.interval <= 0 {
+		return
+	}
+
+	start := timeNow - timeNow%d.interval
+	if start != a.spikeStart {
+		if a.spikeStart != 0 {
+			if a.spikeAvg == 0 {
+				a.spikeAvg = float64(a.spikeCount)
+			} else {
+				a.spikeAvg = spikeAlpha*float64(a.spikeCount) +
+					(1-spikeAlpha)*a.spikeAvg
+			}
+			// intervals without calls lower average too
+			empty := (start-a.spikeStart)/d.interval - 1
+			a.spikeAvg *= math.Pow(1-spikeAlpha, float64(empty))
+		}
+		a.spikeStart = start
+		a.spikeCount = 0
+		a.spiked = false
+	}
+	a.spikeCount++
+
+	if a.spiked || a.spikeAvg <= 0 ||
+		float64(a.spikeCount) <= d.factor*a.spikeAvg {
+		return
+	}
+	a.spiked = true
+	o.spike = true
+	o.spikeRate = float64(a.spikeCount) / float64(d.interval)
+	o.spikeAvg = a.spikeAvg / float64(d.interval)
+}
@@ -0,0 +1,238 @@
+package limiter
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/ssleert/mu"
+)
+
+// aggregate limiter counters
+// since limiter creation
+type Stats struct {
+	// Try() calls that returned true
+	Allowed uint64
+	// Try() calls that returned false
+	Denied uint64
+	// new keys added to map
+	Inserted uint64
+	// keys removed to make space for new keys
+	Evicted uint64
+	// expired keys removed by clean up
+	Cleaned uint64
+	// events dropped because stream buffer was full
+	DroppedEvents uint64
+
+	// sampled Try() calls with measured lock wait
+	// see SetTimingSampling()
+	LockSamples uint64
+	// total lock wait of sampled calls
+	LockWait time.Duration
+	// sampled map lookups and their total time
+	LookupSamples uint64
+	LookupTime    time.Duration
+	// sampled Try() calls and their total time
+	// from call to result
+	TrySamples uint64
+	TryTime    time.Duration
+
+	// approximate count of unique keys seen
+	// since limiter creation, even removed ones
+	// use it to size max keys
+	UniqueKeys uint64
+
+	// limit usage of ended windows
+	Usage Usage
+
+	// keys in map right now
+	Keys int
+	// max keys before clean up
+	// 0 means unlimited
+	MaxKeys int
+}
+
+type counters struct {
+	allowed  atomic.Uint64
+	denied   atomic.Uint64
+	inserted atomic.Uint64
+	evicted  atomic.Uint64
+	cleaned  atomic.Uint64
+	dropped  atomic.Uint64
+
+	lockSamples   atomic.Uint64
+	lockWait      atomic.Int64
+	lookupSamples atomic.Uint64
+	lookupTime    atomic.Int64
+	trySamples    atomic.Uint64
+	tryTime       atomic.Int64
+}
+
+// get current limiter counters
+func (l *Limiter[T]) Stats() Stats {
+	var (
+		keys, maxKeys int
+		unique        uint64
+		usage         Usage
+	)
+	mu.ExecRWMutex(&l.mu, func() {
+		keys = len(l.m) + len(l.hotKeys())
+		usage = l.usage.external()
+		maxKeys = l.maxMapLen
+		unique = l.unique.count()
+	})
+
+	return Stats{
+		Allowed:       l.stats.allowed.Load(),
+		Denied:        l.stats.denied.Load(),
+		Inserted:      l.stats.inserted.Load(),
+		Evicted:       l.stats.evicted.Load(),
+		Cleaned:       l.stats.cleaned.Load(),
+		DroppedEvents: l.stats.dropped.Load(),
+		LockSamples:   l.stats.lockSamples.Load(),
+		LockWait:      time.Duration(l.stats.lockWait.Load()),
+		LookupSamples: l.stats.lookupSamples.Load(),
+		LookupTime:    time.Duration(l.stats.lookupTime.Load()),
+		TrySamples:    l.stats.trySamples.Load(),
+		TryTime:       time.Duration(l.stats.tryTime.Load()),
+		UniqueKeys:    unique,
+		Usage:         usage,
+		Keys:          keys,
+		MaxKeys:       maxKeys,
+	}
+}
+
+// count of keys in map right now
+// cheaper than Stats().Keys
+func (l *Limiter[T]) Len() int {
+	var n int
+	mu.ExecRWMutex(&l.mu, func() {
+		n = len(l.m) + len(l.hotKeys())
+	})
+	return n
+}
+
+// state of single key
+type KeyState struct {
+	// allowed actions in current window
+	Count int
+	// actions left in current window
+	Remaining int
+	// denied actions in current window
+	Denies int
+	// denied actions in a row since last allowed one
+	// big streak is sustained abuse and small one
+	// is occasional overflow
+	DenyStreak int
+	// overdraft to be repaid, see SetOverdraft()
+	Debt int
+
+	// zero if key is not banned
+	BannedUntil time.Time
+	// count of recent bans, see SetBanBackoff()
+	BanLevel int
+	// zero if key is not in cooldown
+	CooldownUntil time.Time
+
+	WindowStart time.Time
+	// when current window ends
+	// and Remaining resets
+	ResetAt   time.Time
+	FirstSeen time.Time
+	LastSeen  time.Time
+	// zero if key was never denied
+	LastDenied time.Time
+
+	// value of key, see SetMeta()
+	Meta any
+}
+
+// time between first and last Try() of key
+// small age with big count is new key hammering
+// and big one is long time client
+func (s KeyState) Age() time.Duration {
+	return s.LastSeen.Sub(s.FirstSeen)
+}
+
+// time since last Try() of key at now
+func (s KeyState) Idle(now time.Time) time.Duration {
+	return now.Sub(s.LastSeen)
+}
+
+// get state of key
+// returns false if key is not tracked
+func (l *Limiter[T]) KeyStats(id T) (KeyState, bool) {
+	return l.keyStats(id, l.now().Unix())
+}
+
+func (l *Limiter[T]) keyStats(id T, timeNow int64) (KeyState, bool) {
+	if hk := l.hotOf(id); hk != nil {
+		hk.settle(id)
+		return hk.l.keyStats(id, timeNow)
+	}
+	var (
+		st KeyState
+		ok bool
+	)
+	mu.ExecRWMutex(&l.mu, func() {
+		var a action
+		a, ok = l.get(id)
+		if ok {
+			st = l.keyState(a, l.policyOf(id), timeNow)
+			st.Meta = l.meta[id]
+		}
+	})
+	return st, ok
+}
+
+// make KeyState from entry
+// as it looks at timeNow
+//
+// l.mu must be held
+func (l *Limiter[T]) keyState(a action, p policy, timeNow int64) KeyState {
+	a = l.current(a, p, timeNow)
+
+	remaining := p.maxCount + a.carry - a.count
+	debt := a.debt
+	if p.burst > 0 {
+		tokens := p.tokens(a, timeNow)
+		remaining = int(tokens)
+		debt = 0
+		if tokens < 0 {
+			debt = int(-tokens)
+		}
+	}
+	if remaining < 0 || a.banned(timeNow) || a.cooldownUntil > timeNow {
+		remaining = 0
+	}
+
+	var bannedUntil time.Time
+	if a.banned(timeNow) {
+		bannedUntil = time.Unix(a.bannedUntil, 0)
+	}
+
+	var lastDenied time.Time
+	if a.lastDenied != 0 {
+		lastDenied = time.Unix(a.lastDenied, 0)
+	}
+
+	var cooldownUntil time.Time
+	if a.cooldownUntil > timeNow {
+		cooldownUntil = time.Unix(a.cooldownUntil, 0)
+	}
+
+	return KeyState{
+		CooldownUntil: cooldownUntil,
+		BannedUntil:   bannedUntil,
+		BanLevel:      l.banLevel(a, timeNow),
+		Count:         a.count,
+		Remaining:     remaining,
+		Denies:        a.denies,
+		DenyStreak:    a.streak,
+		Debt:          debt,
+		WindowStart:   time.Unix(a.deltaTime, 0),
+		ResetAt:       time.Unix(p.end(a), 0),
+		FirstSeen:     time.Unix(a.firstTime, 0),
+		LastSeen:      time.Unix(a.lastTime, 0),
+		LastDenied:    lastDenied,
+	}
+}
@@ -0,0 +1,186 @@
+package limiter
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ssleert/mu"
+	"golang.org/x/exp/constraints"
+)
+
+// position in streamed handoff
+// sender and receiver keep it so broken
+// stream resumes where it stopped
+type HandoffCursor[T any] struct {
+	// last sent key of first pass
+	// nil before first batch
+	After *T `json:"after,omitempty"`
+	// sender clock in unix seconds when handoff
+	// started, 0 for new handoff
+	Start int64 `json:"start,omitempty"`
+	// first pass is sent
+	Done bool `json:"done,omitempty"`
+}
+
+// part of streamed handoff, e.g. one grpc stream message
+type HandoffBatch[T any] struct {
+	Version int `json:"version"`
+	// sender clock in unix seconds
+	Time    int64      `json:"time"`
+	Entries []Entry[T] `json:"entries,omitempty"`
+	// cursor after this batch
+	Cursor HandoffCursor[T] `json:"cursor"`
+	// last batch of handoff
+	End bool `json:"end,omitempty"`
+}
+
+// stream entries of l in batches of up to batch keys
+// for HandoffReceiver on new instance, send is
+// like Send() of grpc stream and its blocking
+// is flow control of stream
+//
+// first pass sends all keys in order and second one
+// resends entries changed since start, then l is
+// drained, so stop traffic to l before second pass
+//
+// on error it returns cursor of last sent batch
+// and call with it resumes handoff, use cursor
+// from receiver if it got more than sender knows
+func (l *Limiter[T]) StreamHandoff(
+	ctx context.Context,
+	cur HandoffCursor[T],
+	batch int,
+	send func(b HandoffBatch[T]) error,
+) (HandoffCursor[T], error) {
+	if batch <= 0 {
+		batch = snapshotChunk
+	}
+	if cur.Start == 0 {
+		cur.Start = l.now().Unix()
+	}
+	out := func(entries []Entry[T], next HandoffCursor[T], end bool) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		return send(HandoffBatch[T]{
+			Version: handoffVersion,
+			Time:    l.now().Unix(),
+			Entries: entries,
+			Cursor:  next,
+			End:     end,
+		})
+	}
+
+	// first pass pages over one sorted copy of keys
+	l.moveBack()
+	sorted := l.sortedKeys()
+	for !cur.Done {
+		keys, more := l.page(sorted, cur.After, batch)
+		next := cur
+		next.After = nil
+		if more {
+			next.After = &keys[len(keys)-1]
+		}
+		next.Done = !more
+
+		var entries []Entry[T]
+		mu.ExecRWMutex(&l.mu, func() {
+			entries = make([]Entry[T], 0, len(keys))
+			for _, id := range keys {
+				if a, ok := l.get(id); ok {
+					entries = append(entries, l.export(id, a))
+				}
+			}
+		})
+		if err := out(entries, next, false); err != nil {
+			return cur, err
+		}
+		cur = next
+	}
+
+	l.moveBack()
+	var changed []Entry[T]
+	mu.ExecRWMutex(&l.mu, func() {
+		for id, p := range l.m {
+			if a := p.unpack(); a.lastTime >= cur.Start {
+				changed = append(changed, l.export(id, a))
+			}
+		}
+	})
+	for len(changed) > batch {
+		if err := out(changed[:batch], cur, false); err != nil {
+			return cur, err
+		}
+		changed = changed[batch:]
+	}
+	if err := out(changed, cur, true); err != nil {
+		return cur, err
+	}
+
+	mu.ExecMutex(&l.mu, func() {
+		for id := range l.m {
+			l.remove(id)
+		}
+	})
+	return cur, nil
+}
+
+// receiving side of StreamHandoff()
+// entries are kept until last batch and then
+// merged with ones in limiter, resent entries
+// of second pass replace first pass ones
+type HandoffReceiver[T constraints.Ordered] struct {
+	l      *Limiter[T]
+	got    map[T]Entry[T]
+	cursor HandoffCursor[T]
+}
+
+// make new receiver of handoff into l
+// keep it between broken streams to resume handoff
+func NewHandoffReceiver[T constraints.Ordered](l *Limiter[T]) *HandoffReceiver[T] {
+	return &HandoffReceiver[T]{
+		l:   l,
+		got: make(map[T]Entry[T]),
+	}
+}
+
+// cursor of last received batch
+// to resume sender from it
+func (r *HandoffReceiver[T]) Cursor() HandoffCursor[T] {
+	return r.cursor
+}
+
+// take batch of stream, entry times are
+// moved by difference of clocks
+// returns true and count of applied entries
+// after last batch
+func (r *HandoffReceiver[T]) Receive(b HandoffBatch[T]) (int, bool, error) {
+	if b.Version != handoffVersion {
+		return 0, false, fmt.Errorf("%w: version %d", ErrHandoff, b.Version)
+	}
+	l := r.l
+	skew := l.now().Unix() - b.Time
+	for _, e := range b.Entries {
+		r.got[e.Key] = e.shift(skew)
+	}
+	r.cursor = b.Cursor
+	if !b.End {
+		return 0, false, nil
+	}
+
+	ids := make([]T, 0, len(r.got))
+	for id := range r.got {
+		ids = append(ids, id)
+	}
+	l.moveBackOf(ids)
+	timeNow := l.now().Unix()
+	mu.ExecMutex(&l.mu, func() {
+		for id, e := range r.got {
+			l.accept(id, e, timeNow)
+		}
+	})
+	n := len(r.got)
+	r.got = make(map[T]Entry[T])
+	r.cursor = HandoffCursor[T]{}
+	return n, true, nil
+}
@@ -0,0 +1,78 @@
+package limiter_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/ssleert/limiter"
+	"github.com/ssleert/limiter/limitertest"
+)
+
+var errBroken = errors.New("broken stream")
+
+func TestStreamHandoff(t *testing.T) {
+	tests := []struct {
+		name  string
+		keys  int
+		batch int
+		// batch that fails once, 0 for none
+		fail int
+	}{
+		{"empty", 0, 4, 0},
+		{"one batch", 3, 4, 0},
+		{"many batches", 10, 3, 0},
+		{"resumed", 10, 3, 2},
+		{"resumed at second pass", 6, 3, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			from := limiter.New[string](10, 60, 16, 1024, 16)
+			to := limiter.New[string](10, 60, 16, 1024, 16)
+			limitertest.NewClock(time.Unix(1000, 0)).Attach(from, to)
+			for i := 0; i < tt.keys; i++ {
+				id := fmt.Sprintf("k%02d", i)
+				limitertest.AssertAllowed[string](t, from, id, i%5+1)
+			}
+
+			r := limiter.NewHandoffReceiver(to)
+			var batches, applied int
+			send := func(b limiter.HandoffBatch[string]) error {
+				batches++
+				if batches == tt.fail {
+					return errBroken
+				}
+				n, _, err := r.Receive(b)
+				applied += n
+				return err
+			}
+
+			cur, err := from.StreamHandoff(context.Background(), limiter.HandoffCursor[string]{}, tt.batch, send)
+			if tt.fail > 0 {
+				if !errors.Is(err, errBroken) {
+					t.Fatalf("StreamHandoff() = %v, want broken stream", err)
+				}
+				_, err = from.StreamHandoff(context.Background(), cur, tt.batch, send)
+			}
+			if err != nil {
+				t.Fatalf("StreamHandoff() = %v", err)
+			}
+
+			if applied != tt.keys {
+				t.Fatalf("receiver applied %d entries, want %d", applied, tt.keys)
+			}
+			if n := from.Len(); n != 0 {
+				t.Fatalf("sender has %d keys after handoff, want 0", n)
+			}
+			for i := 0; i < tt.keys; i++ {
+				id := fmt.Sprintf("k%02d", i)
+				s, ok := to.KeyStats(id)
+				if !ok || s.Count != i%5+1 {
+					t.Fatalf("KeyStats(%q) = %+v, %v, want count %d", id, s, ok, i%5+1)
+				}
+			}
+		})
+	}
+}
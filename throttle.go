@@ -0,0 +1,80 @@
+package limiter
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ssleert/mu"
+	"golang.org/x/exp/constraints"
+)
+
+// allows at most one action per key every interval
+// e.g. one email per hour for every user
+type Throttle[T constraints.Ordered] struct {
+	// unix nanoseconds of last allowed action
+	m        map[T]int64
+	mu       sync.Mutex
+	interval time.Duration
+}
+
+// make new throttle with one action per interval
+func NewThrottle[T constraints.Ordered](interval time.Duration) *Throttle[T] {
+	return &Throttle[T]{
+		m:        make(map[T]int64, defaultMapLen),
+		interval: interval,
+	}
+}
+
+// true if key can take action now
+func (t *Throttle[T]) Try(id T) bool {
+	ok, _ := t.TryOrNext(id)
+	return ok
+}
+
+// true if key can take action now
+// otherwise false and time when it can take next one
+func (t *Throttle[T]) TryOrNext(id T) (bool, time.Time) {
+	now := time.Now().UnixNano()
+
+	var (
+		ok   bool
+		next int64
+	)
+	mu.ExecMutex(&t.mu, func() {
+		last, found := t.m[id]
+		next = last + int64(t.interval)
+		if !found || now >= next {
+			t.m[id] = now
+			ok = true
+		}
+	})
+	if ok {
+		return true, time.Time{}
+	}
+	return false, time.Unix(0, next)
+}
+
+// forget last action of key
+// so it can take next one now
+func (t *Throttle[T]) Reset(id T) {
+	mu.ExecMutex(&t.mu, func() {
+		delete(t.m, id)
+	})
+}
+
+// remove keys whose interval has ended
+// returns count of removed keys
+func (t *Throttle[T]) Clean() int {
+	now := time.Now().UnixNano()
+
+	var removed int
+	mu.ExecMutex(&t.mu, func() {
+		for id, last := range t.m {
+			if now-last >= int64(t.interval) {
+				delete(t.m, id)
+				removed++
+			}
+		}
+	})
+	return removed
+}
@@ -0,0 +1,91 @@
+package limiter
+
+import (
+	"time"
+
+	"github.com/ssleert/mu"
+)
+
+// recently evicted keys
+type tombstones[T comparable] struct {
+	ttl int64
+	m   map[T]tomb
+}
+
+// state of evicted key and unix time
+// until it is remembered
+type tomb struct {
+	until int64
+	p     packed
+}
+
+// remember state of keys evicted to make room for ttl
+// and give it back to key if it returns in that time,
+// so flood of evicted keys doesn't churn fresh entries
+// and key can't get new window by being evicted
+//
+// keys are never denied for being remembered and
+// Reset() forgets key without remembering it
+//
+// at most max map len keys are remembered
+// ttl <= 0 disables it, resolution is one second
+func (l *Limiter[T]) SetRemovedTTL(ttl time.Duration) {
+	mu.ExecMutex(&l.mu, func() {
+		l.tomb.ttl = int64(ttl / time.Second)
+		if l.tomb.ttl <= 0 {
+			l.tomb.m = nil
+		}
+	})
+}
+
+// remember state of evicted key
+//
+// l.mu must be held
+func (l *Limiter[T]) bury(id T, p packed, timeNow int64) {
+	if l.tomb.ttl <= 0 {
+		return
+	}
+	if l.maxMapLen > 0 && len(l.tomb.m) >= l.maxMapLen {
+		return
+	}
+	if l.tomb.m == nil {
+		l.tomb.m = make(map[T]tomb)
+	}
+	l.tomb.m[id] = tomb{
+		until: timeNow + l.tomb.ttl,
+		p:     p,
+	}
+}
+
+// remembered state of key
+//
+// l.mu must be held
+func (l *Limiter[T]) buried(id T, timeNow int64) (action, bool) {
+	t, ok := l.tomb.m[id]
+	if !ok || t.until <= timeNow {
+		return action{}, false
+	}
+	return t.p.unpack(), true
+}
+
+// forget remembered state of key
+// that is back in map
+//
+// l.mu must be held
+func (l *Limiter[T]) unbury(id T) {
+	if len(l.tomb.m) > 0 {
+		delete(l.tomb.m, id)
+	}
+}
+
+// forget ended tombstones
+// on every full scan of clean up
+//
+// l.mu must be held
+func (l *Limiter[T]) cleanTombstones(timeNow int64) {
+	for id, t := range l.tomb.m {
+		if t.until <= timeNow {
+			delete(l.tomb.m, id)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package limiter_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ssleert/limiter"
+	"github.com/ssleert/limiter/limitertest"
+)
+
+func TestRemovedTTL(t *testing.T) {
+	tests := []struct {
+		name  string
+		ttl   time.Duration
+		after func(l *limiter.Limiter[string], c *limitertest.Clock)
+		allow bool
+	}{
+		{
+			name:  "returns with state",
+			ttl:   time.Minute,
+			allow: false,
+		},
+		{
+			name:  "disabled",
+			ttl:   0,
+			allow: true,
+		},
+		{
+			name: "ttl ended",
+			ttl:  time.Second,
+			after: func(l *limiter.Limiter[string], c *limitertest.Clock) {
+				c.Advance(2 * time.Second)
+			},
+			allow: true,
+		},
+		{
+			name: "reset",
+			ttl:  time.Minute,
+			after: func(l *limiter.Limiter[string], c *limitertest.Clock) {
+				l.Reset("a")
+			},
+			allow: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := limiter.New[string](2, 60, 1, 1, 1)
+			l.SetAutoClean(false)
+			l.SetFullPolicy(limiter.FullEvict)
+			l.SetRemovedTTL(tt.ttl)
+			c := limitertest.Use(l)
+
+			limitertest.AssertAllowed[string](t, l, "a", 2)
+			c.Advance(time.Second)
+			// evicts a
+			limitertest.AssertAllowed[string](t, l, "b", 1)
+			if tt.after != nil {
+				tt.after(l, c)
+			}
+
+			if got := l.Try("a"); got != tt.allow {
+				t.Fatalf("Try(a) after eviction = %v, want %v", got, tt.allow)
+			}
+			// remembered keys are never denied for it
+			limitertest.AssertAllowed[string](t, l, "c", 1)
+		})
+	}
+}
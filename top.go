@@ -0,0 +1,75 @@
+package limiter
+
+import (
+	"time"
+
+	"github.com/ssleert/mu"
+	"golang.org/x/exp/slices"
+)
+
+// key with its counter value
+type KeyCount[T any] struct {
+	Key   T
+	Count int
+}
+
+// n keys with most denied actions in current window
+// sorted from biggest to smallest
+func (l *Limiter[T]) TopDenied(n int) []KeyCount[T] {
+	return l.top(n, func(a action) int {
+		return a.denies
+	})
+}
+
+// n keys with most allowed actions in current window
+// sorted from biggest to smallest
+func (l *Limiter[T]) TopConsumers(n int) []KeyCount[T] {
+	return l.top(n, func(a action) int {
+		return a.count
+	})
+}
+
+// n keys first seen within maxAge with most
+// Try() calls in current window, e.g. new keys
+// hammering limiter, sorted from biggest to smallest
+func (l *Limiter[T]) TopNew(n int, maxAge time.Duration) []KeyCount[T] {
+	since := l.now().Add(-maxAge).Unix()
+	return l.top(n, func(a action) int {
+		if a.firstTime < since {
+			return 0
+		}
+		return a.count + a.denies
+	})
+}
+
+// n keys with biggest non zero value of f
+func (l *Limiter[T]) top(n int, f func(a action) int) []KeyCount[T] {
+	if n <= 0 {
+		return nil
+	}
+	timeNow := l.now().Unix()
+
+	var res []KeyCount[T]
+	mu.ExecRWMutex(&l.mu, func() {
+		for key, val := range l.m {
+			c := f(l.current(val.unpack(), l.policyOf(key), timeNow))
+			if c > 0 {
+				res = append(res, KeyCount[T]{key, c})
+			}
+		}
+		l.eachHot(func(id T, hl *Limiter[T], a action) {
+			c := f(hl.current(a, hl.policyOf(id), timeNow))
+			if c > 0 {
+				res = append(res, KeyCount[T]{id, c})
+			}
+		})
+	})
+
+	slices.SortFunc(res, func(a, b KeyCount[T]) int {
+		return b.Count - a.Count
+	})
+	if len(res) > n {
+		res = res[:n]
+	}
+	return res
+}
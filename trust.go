@@ -0,0 +1,189 @@
+package limiter
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/ssleert/mu"
+	"golang.org/x/exp/constraints"
+)
+
+type trust[T constraints.Ordered] struct {
+	// min calls of trusted key in period
+	// 0 disables trust
+	calls int
+	// max part of key limit trusted key uses
+	usage  float64
+	period int64
+	// start of current period
+	start int64
+
+	on   atomic.Bool
+	busy atomic.Bool
+
+	// trusted keys and their calls in period
+	// copy on write
+	keys atomic.Pointer[map[T]*atomic.Uint64]
+}
+
+// trust keys that make at least calls Try() calls
+// and use at most usage part of their limit without
+// any denial, e.g. internal services, trusted keys are
+// allowed by atomic counter without limiter lock
+//
+// keys are checked at start of every period, key is
+// trusted if its current window passes and stays trusted
+// while its calls in period pass, scaled to period
+// trusted key entry isn't updated while it's trusted
+//
+// keys lose trust when they are denied by DenyKeys()
+// or their policy changes, e.g. by SetPolicy(),
+// SetKeyPolicy() or SetKeyScale()
+//
+// calls <= 0 drops all trusted keys and disables it
+// resolution is one second
+func (l *Limiter[T]) SetTrust(calls int, usage float64, period time.Duration) {
+	secs := int64(period / time.Second)
+	if secs < 1 {
+		secs = 1
+	}
+	mu.ExecMutex(&l.mu, func() {
+		l.trust.calls = calls
+		l.trust.usage = usage
+		l.trust.period = secs
+		l.trust.start = 0
+	})
+	l.trust.on.Store(calls > 0)
+	if calls <= 0 {
+		l.trust.keys.Store(nil)
+	}
+}
+
+// keys trusted right now
+func (l *Limiter[T]) Trusted() []T {
+	var ids []T
+	if p := l.trust.keys.Load(); p != nil {
+		for id := range *p {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// calls counter of key if it is trusted
+func (l *Limiter[T]) trustedOf(id T) *atomic.Uint64 {
+	if p := l.trust.keys.Load(); p != nil {
+		return (*p)[id]
+	}
+	return nil
+}
+
+// drop trust of keys or all keys if ids are empty
+// key is checked again at end of period
+//
+// l.mu must be held for writing
+// so retrust() doesn't bring keys back
+func (l *Limiter[T]) distrust(ids ...T) {
+	p := l.trust.keys.Load()
+	if p == nil {
+		return
+	}
+	if len(ids) == 0 {
+		l.trust.keys.Store(nil)
+		return
+	}
+	keys := make(map[T]*atomic.Uint64, len(*p))
+	for id, c := range *p {
+		keys[id] = c
+	}
+	for _, id := range ids {
+		delete(keys, id)
+	}
+	l.trust.keys.Store(&keys)
+}
+
+// true if period ended and keys must be checked
+//
+// l.mu must be held
+func (l *Limiter[T]) trustDue(timeNow int64) bool {
+	t := &l.trust
+	if !t.on.Load() {
+		return false
+	}
+	if t.start == 0 {
+		t.start = timeNow
+	}
+	if timeNow-t.start < t.period {
+		return false
+	}
+	t.start = timeNow
+	return true
+}
+
+// check trusted keys and find new ones
+func (l *Limiter[T]) retrust(timeNow int64) {
+	if !l.trust.busy.CompareAndSwap(false, true) {
+		return
+	}
+	defer l.trust.busy.Store(false)
+
+	keys := make(map[T]*atomic.Uint64)
+
+	// keys are stored under lock so keys
+	// distrusted meanwhile don't come back
+	mu.ExecRWMutex(&l.mu, func() {
+		t := &l.trust
+		if t.calls <= 0 {
+			return
+		}
+		old := map[T]*atomic.Uint64{}
+		if p := t.keys.Load(); p != nil {
+			old = *p
+		}
+		defer t.keys.Store(&keys)
+
+		for id, c := range old {
+			if _, listed := l.listed(id); listed {
+				continue
+			}
+			p := l.policyOf(id)
+			limit := t.usage * float64(p.maxCount) *
+				float64(t.period) / float64(p.maxTime)
+			calls := c.Load()
+			if calls >= uint64(t.calls) && float64(calls) <= limit {
+				keys[id] = &atomic.Uint64{}
+			}
+		}
+		for id, pa := range l.m {
+			if _, ok := keys[id]; ok {
+				continue
+			}
+			if _, ok := old[id]; ok {
+				continue
+			}
+			if _, listed := l.listed(id); listed {
+				continue
+			}
+			p := l.policyOf(id)
+			a := l.current(pa.unpack(), p, timeNow)
+			if a.count >= t.calls && a.denies == 0 && a.streak == 0 &&
+				!a.banned(timeNow) &&
+				float64(a.count) <= t.usage*float64(p.maxCount) {
+				keys[id] = &atomic.Uint64{}
+			}
+		}
+	})
+}
+
+// check trust of keys in background
+// or right away in deterministic mode
+func (l *Limiter[T]) checkTrust(timeNow int64) {
+	if l.deterministic.Load() {
+		l.retrust(timeNow)
+		return
+	}
+	go l.do(context.Background(), "trust", func(context.Context) {
+		l.retrust(timeNow)
+	})
+}
@@ -0,0 +1,66 @@
+package limiter_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ssleert/limiter"
+	"github.com/ssleert/limiter/limitertest"
+)
+
+func TestTrustDropped(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(l *limiter.Limiter[string])
+		// actions of a allowed after change
+		allowed int
+	}{
+		{
+			name:    "deny keys",
+			change:  func(l *limiter.Limiter[string]) { l.DenyKeys("a") },
+			allowed: 0,
+		},
+		{
+			name: "key policy",
+			change: func(l *limiter.Limiter[string]) {
+				l.SetKeyPolicy("a", limiter.Policy{MaxCount: 6, Window: time.Minute})
+			},
+			allowed: 1,
+		},
+		{
+			name:    "key scale",
+			change:  func(l *limiter.Limiter[string]) { l.SetKeyScale("a", 0.06) },
+			allowed: 1,
+		},
+		{
+			name: "policy",
+			change: func(l *limiter.Limiter[string]) {
+				l.SetPolicy(limiter.Policy{MaxCount: 6, Window: time.Minute})
+			},
+			allowed: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := limitertest.NewClock(time.Unix(1000, 0))
+			l := limiter.New[string](100, 60, 16, 1024, 16)
+			l.SetDeterministic(c.Now, 1)
+			l.SetTrust(3, 0.5, time.Second)
+
+			limitertest.AssertAllowed[string](t, l, "a", 4)
+			c.Advance(2 * time.Second)
+			// ends trust period
+			limitertest.AssertAllowed[string](t, l, "a", 1)
+			if got := l.Trusted(); len(got) != 1 || got[0] != "a" {
+				t.Fatalf("Trusted() = %v, want [a]", got)
+			}
+
+			tt.change(l)
+			if got := l.Trusted(); len(got) != 0 {
+				t.Fatalf("Trusted() after change = %v, want none", got)
+			}
+			limitertest.AssertAllowed[string](t, l, "a", tt.allowed)
+			limitertest.AssertDenied[string](t, l, "a")
+		})
+	}
+}
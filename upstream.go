@@ -0,0 +1,123 @@
+package limiter
+
+import (
+	"context"
+	"math/rand"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// defaults of NewUpstream()
+const (
+	// calls per second to one host
+	DefaultUpstreamRate = 10
+	// calls one host can get at once
+	DefaultUpstreamBurst = 20
+	// max random delay after every wait
+	DefaultUpstreamJitter = 50 * time.Millisecond
+)
+
+// throttle of outbound calls per destination host
+// like dns lookups or webhook deliveries
+// hosts get bucket with burst and every wait
+// gets random delay, so many workers don't
+// hit one host at the same moment
+//
+// hosts are tracked in limiter that evicts
+// least recently used ones when it is full
+type Upstream struct {
+	l      *Limiter[string]
+	jitter time.Duration
+}
+
+// make new upstream throttle with rate calls per second
+// and burst for every host, values <= 0 mean defaults
+func NewUpstream(rate, burst int) *Upstream {
+	if rate <= 0 {
+		rate = DefaultUpstreamRate
+	}
+	if burst <= 0 {
+		burst = DefaultUpstreamBurst
+	}
+	l := New[string](rate, 1, Default, Default, Default)
+	l.SetPolicy(Policy{
+		MaxCount: rate,
+		Window:   time.Second,
+		Burst:    burst,
+	})
+	l.SetFullPolicy(FullEvict)
+	return &Upstream{
+		l:      l,
+		jitter: DefaultUpstreamJitter,
+	}
+}
+
+// limiter of hosts for own settings
+// like SetKeyPolicy() for slow partner
+func (u *Upstream) Limiter() *Limiter[string] {
+	return u.l
+}
+
+// set max random delay after every wait
+// max <= 0 disables it
+func (u *Upstream) SetJitter(max time.Duration) {
+	u.jitter = max
+}
+
+// true if host of dest can take call now
+func (u *Upstream) Try(dest string) bool {
+	return u.l.Try(Host(dest))
+}
+
+// wait until host of dest can take call
+// and then for random jitter or until ctx is done
+func (u *Upstream) Wait(ctx context.Context, dest string) error {
+	if err := u.l.Wait(ctx, Host(dest)); err != nil {
+		return err
+	}
+	if u.jitter <= 0 {
+		return nil
+	}
+
+	t := time.NewTimer(time.Duration(rand.Int63n(int64(u.jitter))))
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// wrap dial func like net.Dialer.DialContext so every
+// dial waits for its host, e.g. for net.Resolver.Dial
+// or http.Transport.DialContext
+func (u *Upstream) Dial(
+	dial func(ctx context.Context, network, addr string) (net.Conn, error),
+) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		if err := u.Wait(ctx, addr); err != nil {
+			return nil, err
+		}
+		return dial(ctx, network, addr)
+	}
+}
+
+// host of url, host:port or host
+// in lower case without trailing dot
+// so all forms of one host share limit
+func Host(dest string) string {
+	if strings.Contains(dest, "://") {
+		if u, err := url.Parse(dest); err == nil {
+			dest = u.Host
+		}
+	}
+	if host, _, err := net.SplitHostPort(dest); err == nil {
+		dest = host
+	}
+	dest = strings.TrimPrefix(dest, "[")
+	dest = strings.TrimSuffix(dest, "]")
+	return strings.TrimSuffix(strings.ToLower(dest), ".")
+}
@@ -0,0 +1,104 @@
+package limiter
+
+import (
+	"github.com/ssleert/mu"
+)
+
+// usage histogram has bucket for every percent
+// of limit and last one for fully used limit
+const usageBuckets = 101
+
+// usage of limit by ended windows
+type usage struct {
+	buckets [usageBuckets]uint64
+	windows uint64
+	sum     float64
+}
+
+// distribution of limit usage by key windows
+// usage is count of window divided by its limit
+// so 0.5 is half used window and 1 is fully used one
+//
+// percentiles are rounded down to percent, when P99 is
+// far under 1 limit doesn't constrain anyone and can be
+// made tighter
+type Usage struct {
+	// ended windows since limiter creation
+	Windows uint64
+	// usage of all of them
+	Sum float64
+
+	P50 float64
+	P95 float64
+	P99 float64
+}
+
+// record usage of ended window of key
+// token bucket keys have no windows and are skipped
+//
+// l.mu must be held
+func (l *Limiter[T]) endWindow(a action, p policy) {
+	limit := p.maxCount + a.carry
+	if p.burst > 0 || limit <= 0 {
+		return
+	}
+	u := float64(a.count) / float64(limit)
+	i := int(u * 100)
+	if i >= usageBuckets {
+		i = usageBuckets - 1
+	}
+	if i < 0 {
+		i = 0
+	}
+	l.usage.buckets[i]++
+	l.usage.windows++
+	l.usage.sum += u
+}
+
+// roll window of entry like current()
+// and record usage of window that ended
+//
+// l.mu must be held
+func (l *Limiter[T]) roll(a action, p policy, timeNow int64) action {
+	if p.ended(a, timeNow) {
+		l.endWindow(a, p)
+	}
+	return l.current(a, p, timeNow)
+}
+
+// l.mu must be held
+func (u *usage) external() Usage {
+	return Usage{
+		Windows: u.windows,
+		Sum:     u.sum,
+		P50:     u.quantile(0.5),
+		P95:     u.quantile(0.95),
+		P99:     u.quantile(0.99),
+	}
+}
+
+// usage under which q of windows are
+func (u *usage) quantile(q float64) float64 {
+	if u.windows == 0 {
+		return 0
+	}
+	rank := uint64(q * float64(u.windows))
+	var seen uint64
+	for i, n := range u.buckets {
+		seen += n
+		if seen > rank {
+			return float64(i) / 100
+		}
+	}
+	return 1
+}
+
+// get count of ended windows for every percent of limit
+// they used, last element is for fully used windows
+func (l *Limiter[T]) UsageHistogram() []uint64 {
+	res := make([]uint64, usageBuckets)
+	mu.ExecRWMutex(&l.mu, func() {
+		copy(res, l.usage.buckets[:])
+	})
+	return res
+}
@@ -0,0 +1,63 @@
+package limiter
+
+import (
+	"time"
+
+	"github.com/ssleert/mu"
+)
+
+type warmup struct {
+	// part of limit for brand new key
+	start float64
+	// seconds until key gets full limit
+	period int64
+}
+
+// make new keys start with start part of their limit
+// growing linearly to full limit over period since
+// key was first seen, e.g. SetWarmup(0.1, time.Hour)
+//
+// key whose entry was cleaned up is new again
+// so use SetIdleTTL() to remember keys longer
+//
+// period <= 0 disables warm up
+// resolution is one second
+func (l *Limiter[T]) SetWarmup(start float64, period time.Duration) {
+	defer l.moveBack()
+	if start < 0 {
+		start = 0
+	}
+	if start > 1 {
+		start = 1
+	}
+	mu.ExecMutex(&l.mu, func() {
+		l.warmup = warmup{
+			start:  start,
+			period: int64(period / time.Second),
+		}
+	})
+}
+
+// policy p scaled for key age at timeNow
+func (w warmup) scale(p policy, a action, timeNow int64) policy {
+	age := timeNow - a.firstTime
+	if w.period <= 0 || age >= w.period {
+		return p
+	}
+
+	k := w.start + (1-w.start)*float64(age)/float64(w.period)
+	p.maxCount = scaled(p.maxCount, k)
+	if p.burst > 0 {
+		p.burst = scaled(p.burst, k)
+	}
+	return p
+}
+
+// n multiplied by k but not less than 1
+func scaled(n int, k float64) int {
+	v := int(float64(n) * k)
+	if v < 1 {
+		return 1
+	}
+	return v
+}